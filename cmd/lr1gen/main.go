@@ -0,0 +1,46 @@
+// lr1gen builds the LR(1) parse table for parser.GrammarProductions
+// and writes it in the format embedded by the parser package
+//
+// usage: lr1gen [-o lr1_table.txt]
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"mettlach.codes/frizzy/lr1"
+	"mettlach.codes/frizzy/parser"
+)
+
+func main() {
+	outputPath := flag.String("o", "lr1_table.txt", "path to write the generated table to")
+	flag.Parse()
+
+	grammar, err := lr1.NewGrammar(parser.GrammarProductions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lr1gen: invalid grammar: %s\n", err)
+		os.Exit(1)
+	}
+
+	table, err := lr1.BuildTable(grammar)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lr1gen: %s\n", err)
+		os.Exit(1)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := table.Write(buf); err != nil {
+		fmt.Fprintf(os.Stderr, "lr1gen: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(*outputPath, buf.Bytes(), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "lr1gen: could not write %s: %s\n", *outputPath, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("lr1gen: wrote %d states for %d productions to %s\n",
+		len(table.Rows), len(grammar.Productions), *outputPath)
+}
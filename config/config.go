@@ -3,6 +3,7 @@ package config
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -12,6 +13,20 @@ const (
 	DefaultContentDir  string = "content"
 	DefaultPagesDir    string = "pages"
 	DefaultTemplateDir string = "templates"
+	DefaultCacheDir    string = ".frizzy-cache"
+)
+
+// UndefinedPolicy is what happens when a template reads a variable
+// that isn't set
+type UndefinedPolicy string
+
+const (
+	// UndefinedSilent renders undefined variables as empty strings
+	UndefinedSilent UndefinedPolicy = "silent"
+	// UndefinedWarn renders them as empty strings and reports a warning
+	UndefinedWarn UndefinedPolicy = "warn"
+	// UndefinedError fails the file with an error
+	UndefinedError UndefinedPolicy = "error"
 )
 
 // Config holds the configuration options for the
@@ -22,6 +37,12 @@ type Config struct {
 	PagesDir    string
 	OutputPath  string
 	TemplateDir string
+	CacheDir    string
+	// TrimBlockLines removes lines that only hold tags like {{if}} and
+	// {{end}} from the output instead of leaving them blank
+	TrimBlockLines bool
+	// UndefinedVariables is one of "silent", "warn" or "error"
+	UndefinedVariables UndefinedPolicy
 }
 
 var loadedConfig *Config
@@ -65,6 +86,10 @@ func (receiver *Config) GetTemplatePath() string {
 	return filepath.Join(receiver.RootPath, receiver.TemplateDir)
 }
 
+func (receiver *Config) GetCachePath() string {
+	return filepath.Join(receiver.RootPath, receiver.CacheDir)
+}
+
 func loadConfigObject(configStream io.Reader) (*Config, error) {
 	dec := json.NewDecoder(configStream)
 
@@ -87,5 +112,18 @@ func loadConfigObject(configStream io.Reader) (*Config, error) {
 		c.TemplateDir = DefaultTemplateDir
 	}
 
+	if c.CacheDir == "" {
+		c.CacheDir = DefaultCacheDir
+	}
+
+	switch c.UndefinedVariables {
+	case "":
+		c.UndefinedVariables = UndefinedWarn
+	case UndefinedSilent, UndefinedWarn, UndefinedError:
+	default:
+		return nil, fmt.Errorf("UndefinedVariables must be %q, %q or %q, got %q",
+			UndefinedSilent, UndefinedWarn, UndefinedError, c.UndefinedVariables)
+	}
+
 	return c, nil
 }
@@ -62,3 +62,58 @@ func TestConfigSetsCorrectPagesDir(t *testing.T) {
 		t.Errorf(`expected content path to be %q got %q`, expected, config.GetContentPath())
 	}
 }
+
+func TestConfigSetsDefaultCacheDir(t *testing.T) {
+	expectedCache := filepath.Join(expectedRoot, ".frizzy-cache")
+
+	configJSON := fmt.Sprintf(`{"RootPath": %q}`, expectedRoot)
+	if config, err := loadConfigObject(strings.NewReader(configJSON)); err != nil {
+		t.Errorf("expected no error, got %q", err)
+	} else if config.GetCachePath() != expectedCache {
+		t.Errorf(`expected cache path to be %q got %q`, expectedCache, config.GetCachePath())
+	}
+}
+
+func TestConfigSetsTrimBlockLines(t *testing.T) {
+	var tests = []struct {
+		configJSON string
+		expected   bool
+	}{
+		{`{"RootPath": "/root"}`, false},
+		{`{"RootPath": "/root", "TrimBlockLines": true}`, true},
+	}
+
+	for i, test := range tests {
+		if config, err := loadConfigObject(strings.NewReader(test.configJSON)); err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if config.TrimBlockLines != test.expected {
+			t.Errorf("%d: expected TrimBlockLines to be %t, got %t", i, test.expected, config.TrimBlockLines)
+		}
+	}
+}
+
+func TestConfigSetsUndefinedVariables(t *testing.T) {
+	var tests = []struct {
+		configJSON string
+		expected   UndefinedPolicy
+	}{
+		{`{"RootPath": "/root"}`, UndefinedWarn},
+		{`{"RootPath": "/root", "UndefinedVariables": "silent"}`, UndefinedSilent},
+		{`{"RootPath": "/root", "UndefinedVariables": "error"}`, UndefinedError},
+	}
+
+	for i, test := range tests {
+		if config, err := loadConfigObject(strings.NewReader(test.configJSON)); err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if config.UndefinedVariables != test.expected {
+			t.Errorf("%d: expected UndefinedVariables to be %q, got %q", i, test.expected, config.UndefinedVariables)
+		}
+	}
+}
+
+func TestConfigRejectsUnknownUndefinedVariables(t *testing.T) {
+	configJSON := `{"RootPath": "/root", "UndefinedVariables": "strict"}`
+	if _, err := loadConfigObject(strings.NewReader(configJSON)); err == nil {
+		t.Errorf("expected an error for an unknown UndefinedVariables policy")
+	}
+}
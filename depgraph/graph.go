@@ -0,0 +1,305 @@
+// Package depgraph records which inputs each output of a build was
+// rendered from so later builds only re-render outputs whose inputs
+// have changed
+package depgraph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// GraphFile is the name of the file the graph is saved to in the
+// cache directory
+const GraphFile = "dependencies.json"
+
+// Kind is the type of input a Dependency refers to
+type Kind string
+
+const (
+	// FileDependency changes when the contents of the file change
+	FileDependency Kind = "file"
+	// DirDependency changes when files are added to or removed from
+	// the directory or any directory below it
+	DirDependency Kind = "dir"
+)
+
+// Dependency is an input read while rendering an output
+type Dependency struct {
+	Kind Kind
+	Path string
+}
+
+// HashedDependency is a Dependency along with its hash at the time the
+// output was rendered
+type HashedDependency struct {
+	Dependency
+	Hash string
+}
+
+// Output is an output file along with the source it was rendered from
+// and everything read while rendering it
+type Output struct {
+	Source       string
+	Dependencies []HashedDependency
+}
+
+// Graph maps output paths to the inputs they were rendered from
+type Graph struct {
+	Outputs map[string]*Output
+	// BuildHash is the hash of what outputs are rendered with besides
+	// their dependencies, like the config and the binary
+	BuildHash string
+
+	mut sync.Mutex
+	// stale is true when BuildHash changed since the graph was saved
+	stale bool
+	// hashes of the dependencies as they are in this build
+	hashes map[Dependency]string
+	// recorders for outputs being rendered, keyed by output path
+	pending map[string]*Recorder
+}
+
+// NewGraph creates an empty Graph, every source needs rendering
+func NewGraph() *Graph {
+	return &Graph{
+		Outputs: map[string]*Output{},
+		hashes:  map[Dependency]string{},
+		pending: map[string]*Recorder{},
+	}
+}
+
+// Load reads the graph saved in cacheDir
+// If no graph has been saved yet an empty Graph is returned
+func Load(cacheDir string) (*Graph, error) {
+	graph := NewGraph()
+
+	bytes, err := os.ReadFile(filepath.Join(cacheDir, GraphFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return graph, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(bytes, graph); err != nil {
+		return nil, err
+	}
+
+	if graph.Outputs == nil {
+		graph.Outputs = map[string]*Output{}
+	}
+
+	return graph, nil
+}
+
+// Save writes the graph to cacheDir, creating it if needed
+func (receiver *Graph) Save(cacheDir string) error {
+	receiver.mut.Lock()
+	bytes, err := json.MarshalIndent(receiver, "", "  ")
+	receiver.mut.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(cacheDir, GraphFile), bytes, 0644)
+}
+
+// UseBuildHash sets the hash of what this build renders outputs with
+// besides their dependencies, every source needs rendering if it isn't
+// the hash the graph was saved with
+func (receiver *Graph) UseBuildHash(hash string) {
+	receiver.mut.Lock()
+	defer receiver.mut.Unlock()
+
+	receiver.stale = receiver.BuildHash != hash
+	receiver.BuildHash = hash
+}
+
+// NeedsRender reports whether source has to be rendered again
+// It does unless the build hash is unchanged, every output previously
+// rendered from source still exists and none of their dependencies
+// have changed
+func (receiver *Graph) NeedsRender(source string) bool {
+	receiver.mut.Lock()
+	defer receiver.mut.Unlock()
+
+	if receiver.stale {
+		return true
+	}
+
+	found := false
+	for outputPath, output := range receiver.Outputs {
+		if output.Source != source {
+			continue
+		}
+		found = true
+
+		if _, err := os.Stat(outputPath); err != nil {
+			return true
+		}
+
+		for _, dependency := range output.Dependencies {
+			if receiver.hash(dependency.Dependency) != dependency.Hash {
+				return true
+			}
+		}
+	}
+
+	return !found
+}
+
+// Track holds on to recorder until its source is committed or discarded
+func (receiver *Graph) Track(outputPath string, recorder *Recorder) {
+	receiver.mut.Lock()
+	defer receiver.mut.Unlock()
+	receiver.pending[outputPath] = recorder
+}
+
+// Commit replaces the outputs recorded for source with the ones
+// tracked since source was last committed
+// It should be called once every output of source rendered successfully
+func (receiver *Graph) Commit(source string) {
+	receiver.mut.Lock()
+	defer receiver.mut.Unlock()
+
+	receiver.removeSource(source)
+	for outputPath, recorder := range receiver.pending {
+		if recorder.Source == source {
+			receiver.Outputs[outputPath] = receiver.newOutput(recorder)
+			delete(receiver.pending, outputPath)
+		}
+	}
+}
+
+// Discard forgets everything recorded for source so it is rendered
+// again by the next build
+func (receiver *Graph) Discard(source string) {
+	receiver.mut.Lock()
+	defer receiver.mut.Unlock()
+
+	receiver.removeSource(source)
+	for outputPath, recorder := range receiver.pending {
+		if recorder.Source == source {
+			delete(receiver.pending, outputPath)
+		}
+	}
+}
+
+// Prune removes the outputs of sources that are not in sources and
+// deletes their files
+// Every output is removed from the graph even if deleting one fails,
+// the first error is returned
+func (receiver *Graph) Prune(sources []string) error {
+	receiver.mut.Lock()
+	defer receiver.mut.Unlock()
+
+	exists := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		exists[source] = true
+	}
+
+	var firstErr error
+	for outputPath, output := range receiver.Outputs {
+		if exists[output.Source] {
+			continue
+		}
+
+		delete(receiver.Outputs, outputPath)
+		if err := os.Remove(outputPath); err != nil && !errors.Is(err, fs.ErrNotExist) && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// removeSource deletes the outputs of source, the caller must hold mut
+func (receiver *Graph) removeSource(source string) {
+	for outputPath, output := range receiver.Outputs {
+		if output.Source == source {
+			delete(receiver.Outputs, outputPath)
+		}
+	}
+}
+
+// newOutput hashes the dependencies collected by recorder, the caller
+// must hold mut
+func (receiver *Graph) newOutput(recorder *Recorder) *Output {
+	dependencies := recorder.Dependencies()
+	output := &Output{
+		Source:       recorder.Source,
+		Dependencies: make([]HashedDependency, len(dependencies)),
+	}
+
+	for i, dependency := range dependencies {
+		output.Dependencies[i] = HashedDependency{
+			Dependency: dependency,
+			Hash:       receiver.hash(dependency),
+		}
+	}
+
+	return output
+}
+
+// hash returns the hash of dependency as it is now
+// Hashes are computed once per build, the caller must hold mut
+func (receiver *Graph) hash(dependency Dependency) string {
+	if hash, ok := receiver.hashes[dependency]; ok {
+		return hash
+	}
+
+	var hash string
+	switch dependency.Kind {
+	case FileDependency:
+		hash = hashFile(dependency.Path)
+	case DirDependency:
+		hash = hashDir(dependency.Path)
+	}
+
+	receiver.hashes[dependency] = hash
+	return hash
+}
+
+// hashFile returns the hex encoded SHA-256 of the file at path or an
+// empty string if it can't be read
+func hashFile(path string) string {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashDir returns the hex encoded SHA-256 of the paths of the files
+// below path or an empty string if it doesn't exist
+func hashDir(path string) string {
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+
+	hasher := sha256.New()
+	filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			hasher.Write([]byte(filePath + "\n"))
+		}
+		return nil
+	})
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
@@ -0,0 +1,209 @@
+package depgraph
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0640); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// renderedGraph returns a graph with output rendered from source and
+// depending on dependencies
+func renderedGraph(t *testing.T, output, source string, dependencies ...Dependency) *Graph {
+	writeFile(t, output, "rendered")
+
+	recorder := NewRecorder(source)
+	for _, dependency := range dependencies {
+		recorder.add(dependency)
+	}
+
+	graph := NewGraph()
+	graph.Track(output, recorder)
+	graph.Commit(source)
+
+	return graph
+}
+
+func TestNeedsRender(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "pages", "index.html")
+	output := filepath.Join(root, "output", "index.html")
+	template := filepath.Join(root, "templates", "base.html")
+	posts := filepath.Join(root, "content", "posts")
+
+	var tests = []struct {
+		description string
+		change      func()
+		expected    bool
+	}{
+		{"nothing changed", func() {}, false},
+		{"source changed", func() { writeFile(t, source, "changed") }, true},
+		{"template changed", func() { writeFile(t, template, "changed") }, true},
+		{"template removed", func() { os.Remove(template) }, true},
+		{"post edited", func() { writeFile(t, filepath.Join(posts, "a.md"), "changed") }, false},
+		{"post added", func() { writeFile(t, filepath.Join(posts, "c.md"), "new") }, true},
+		{"output removed", func() { os.Remove(output) }, true},
+	}
+
+	for i, test := range tests {
+		writeFile(t, source, "source")
+		writeFile(t, template, "template")
+		writeFile(t, filepath.Join(posts, "a.md"), "a")
+		writeFile(t, filepath.Join(posts, "b.md"), "b")
+		os.Remove(filepath.Join(posts, "c.md"))
+
+		graph := renderedGraph(t, output, source,
+			Dependency{Kind: FileDependency, Path: template},
+			Dependency{Kind: DirDependency, Path: posts},
+		)
+
+		test.change()
+		// hashes are computed once per build so load a fresh graph
+		graph = &Graph{Outputs: graph.Outputs, hashes: map[Dependency]string{}, pending: map[string]*Recorder{}}
+
+		if actual := graph.NeedsRender(source); actual != test.expected {
+			t.Errorf("%d: expected NeedsRender to be %t when %s, got %t", i, test.expected, test.description, actual)
+		}
+	}
+}
+
+func TestNeedsRenderUnknownSource(t *testing.T) {
+	if !NewGraph().NeedsRender("/never/rendered.html") {
+		t.Errorf("expected a source without outputs to need rendering")
+	}
+}
+
+func TestDiscardForgetsSource(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "pages", "index.html")
+	output := filepath.Join(root, "output", "index.html")
+	writeFile(t, source, "source")
+
+	graph := renderedGraph(t, output, source)
+	graph.Track(output, NewRecorder(source))
+	graph.Discard(source)
+
+	if !graph.NeedsRender(source) {
+		t.Errorf("expected a discarded source to need rendering")
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	root := t.TempDir()
+	cacheDir := filepath.Join(root, ".frizzy-cache")
+	source := filepath.Join(root, "pages", "index.html")
+	output := filepath.Join(root, "output", "index.html")
+	writeFile(t, source, "source")
+
+	graph := renderedGraph(t, output, source)
+	if err := graph.Save(cacheDir); err != nil {
+		t.Fatalf("expected no error saving, got %q", err)
+	}
+
+	loaded, err := Load(cacheDir)
+	if err != nil {
+		t.Fatalf("expected no error loading, got %q", err)
+	}
+
+	if loaded.NeedsRender(source) {
+		t.Errorf("expected loaded graph to know %s is up to date", source)
+	}
+
+	if actual := loaded.Outputs[output].Dependencies; len(actual) != 1 || actual[0].Path != source {
+		t.Errorf("expected %s to depend only on %s, got %v", output, source, actual)
+	}
+}
+
+func TestLoadMissingGraph(t *testing.T) {
+	graph, err := Load(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	if len(graph.Outputs) != 0 {
+		t.Errorf("expected an empty graph, got %d outputs", len(graph.Outputs))
+	}
+}
+
+func TestPrune(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "pages", "index.html")
+	output := filepath.Join(root, "output", "index.html")
+	writeFile(t, source, "source")
+
+	graph := renderedGraph(t, output, source)
+	if err := graph.Prune([]string{filepath.Join(root, "pages", "other.html")}); err != nil {
+		t.Fatalf("expected no error pruning, got %q", err)
+	}
+
+	if _, ok := graph.Outputs[output]; ok {
+		t.Errorf("expected outputs of removed sources to be pruned")
+	}
+
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("expected the output of a removed source to be deleted, got %v", err)
+	}
+}
+
+func TestPruneKeepsOutputsOfExistingSources(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "pages", "index.html")
+	output := filepath.Join(root, "output", "index.html")
+	writeFile(t, source, "source")
+
+	graph := renderedGraph(t, output, source)
+	if err := graph.Prune([]string{source}); err != nil {
+		t.Fatalf("expected no error pruning, got %q", err)
+	}
+
+	if _, ok := graph.Outputs[output]; !ok {
+		t.Errorf("expected outputs of existing sources to be kept")
+	}
+
+	if _, err := os.Stat(output); err != nil {
+		t.Errorf("expected the output of an existing source to be kept, got %v", err)
+	}
+}
+
+func TestChangedBuildHashRendersEverything(t *testing.T) {
+	root := t.TempDir()
+	cacheDir := filepath.Join(root, ".frizzy-cache")
+	source := filepath.Join(root, "pages", "index.html")
+	output := filepath.Join(root, "output", "index.html")
+	writeFile(t, source, "source")
+
+	graph := renderedGraph(t, output, source)
+	graph.UseBuildHash("config-a")
+	if err := graph.Save(cacheDir); err != nil {
+		t.Fatalf("expected no error saving, got %q", err)
+	}
+
+	var tests = []struct {
+		hash     string
+		expected bool
+	}{
+		{"config-a", false},
+		{"config-b", true},
+		{"", true},
+	}
+
+	for i, test := range tests {
+		loaded, err := Load(cacheDir)
+		if err != nil {
+			t.Fatalf("expected no error loading, got %q", err)
+		}
+
+		loaded.UseBuildHash(test.hash)
+		if actual := loaded.NeedsRender(source); actual != test.expected {
+			t.Errorf("%d: expected NeedsRender with build hash %q to be %t, got %t", i, test.hash, test.expected, actual)
+		}
+	}
+}
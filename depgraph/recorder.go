@@ -0,0 +1,73 @@
+package depgraph
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"mettlach.codes/frizzy/config"
+)
+
+// Recorder collects the dependencies of a single output while it is
+// rendered
+// It satisfies processor.DependencyRecorder
+type Recorder struct {
+	Source string
+
+	mut          sync.Mutex
+	dependencies map[Dependency]bool
+}
+
+// NewRecorder creates a Recorder for an output rendered from source
+// The source file is always a dependency of its outputs
+func NewRecorder(source string) *Recorder {
+	recorder := &Recorder{Source: source, dependencies: map[Dependency]bool{}}
+	recorder.add(Dependency{Kind: FileDependency, Path: source})
+	return recorder
+}
+
+// RecordTemplate adds the file at templatePath in the template directory
+func (receiver *Recorder) RecordTemplate(templatePath string) {
+	config := config.GetLoadedConfig()
+	fullPath := filepath.Join(config.GetTemplatePath(), templatePath)
+	receiver.add(Dependency{Kind: FileDependency, Path: fullPath})
+}
+
+// RecordContentPath adds the listing of subpath in the content directory
+func (receiver *Recorder) RecordContentPath(subpath string) {
+	config := config.GetLoadedConfig()
+	fullPath := filepath.Join(config.GetContentPath(), subpath)
+	receiver.add(Dependency{Kind: DirDependency, Path: fullPath})
+}
+
+// RecordExport adds the file whose exports were read
+// A file's exports only change when the file does
+func (receiver *Recorder) RecordExport(filePath string) {
+	receiver.add(Dependency{Kind: FileDependency, Path: filePath})
+}
+
+// Dependencies returns everything recorded so far sorted by path
+func (receiver *Recorder) Dependencies() []Dependency {
+	receiver.mut.Lock()
+	defer receiver.mut.Unlock()
+
+	dependencies := make([]Dependency, 0, len(receiver.dependencies))
+	for dependency := range receiver.dependencies {
+		dependencies = append(dependencies, dependency)
+	}
+
+	sort.Slice(dependencies, func(a, b int) bool {
+		if dependencies[a].Path != dependencies[b].Path {
+			return dependencies[a].Path < dependencies[b].Path
+		}
+		return dependencies[a].Kind < dependencies[b].Kind
+	})
+
+	return dependencies
+}
+
+func (receiver *Recorder) add(dependency Dependency) {
+	receiver.mut.Lock()
+	defer receiver.mut.Unlock()
+	receiver.dependencies[dependency] = true
+}
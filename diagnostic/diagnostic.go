@@ -0,0 +1,222 @@
+// Package diagnostic describes problems found while building a site
+// along with where in the source they happened
+package diagnostic
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Severity is how serious a Diagnostic is
+type Severity int
+
+const (
+	// Error diagnostics fail the build
+	Error Severity = iota
+	// Warning diagnostics are reported but don't fail the build
+	Warning
+)
+
+func (receiver Severity) String() string {
+	if receiver == Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic is a problem in a source file
+// Line and Column start at 1, zero means the position isn't known
+// EndColumn is the column after the last one the problem covers
+type Diagnostic struct {
+	Filename  string
+	Line      int
+	Column    int
+	EndColumn int
+	Severity  Severity
+	Message   string
+
+	// cause is the error the diagnostic was created from, if any
+	cause error
+}
+
+// Errorf creates an error Diagnostic at line and col
+func Errorf(line, col int, msg string, msgFmt ...interface{}) *Diagnostic {
+	return &Diagnostic{Line: line, Column: col, Severity: Error, Message: fmt.Sprintf(msg, msgFmt...)}
+}
+
+// Wrap creates an error Diagnostic at line and col from err, the
+// original error can still be found with errors.As
+func Wrap(line, col int, err error) *Diagnostic {
+	return &Diagnostic{Line: line, Column: col, Severity: Error, Message: err.Error(), cause: err}
+}
+
+// Warn creates a warning Diagnostic at line and col from err
+func Warn(line, col int, err error) *Diagnostic {
+	return &Diagnostic{Line: line, Column: col, Severity: Warning, Message: err.Error(), cause: err}
+}
+
+// FromError returns err as a Diagnostic for filename
+// If err wraps a Diagnostic its position and severity are kept,
+// otherwise the Diagnostic is an error with no position
+func FromError(filename string, err error) *Diagnostic {
+	var diagnostic *Diagnostic
+	if errors.As(err, &diagnostic) {
+		copied := *diagnostic
+		if copied.Filename == "" {
+			copied.Filename = filename
+		}
+		return &copied
+	}
+
+	return &Diagnostic{Filename: filename, Severity: Error, Message: err.Error(), cause: err}
+}
+
+// IsError reports whether err is anything other than a warning
+func IsError(err error) bool {
+	var diagnostic *Diagnostic
+	return !errors.As(err, &diagnostic) || diagnostic.Severity == Error
+}
+
+func (receiver *Diagnostic) Error() string {
+	location := []string{}
+	if receiver.Filename != "" {
+		location = append(location, receiver.Filename)
+	}
+	if receiver.Line > 0 {
+		location = append(location, fmt.Sprint(receiver.Line))
+		if receiver.Column > 0 {
+			location = append(location, fmt.Sprint(receiver.Column))
+		}
+	}
+
+	message := fmt.Sprintf("%s: %s", receiver.Severity, receiver.Message)
+	if len(location) == 0 {
+		return message
+	}
+	return strings.Join(location, ":") + ": " + message
+}
+
+// Unwrap returns the error the diagnostic was created from
+func (receiver *Diagnostic) Unwrap() error {
+	return receiver.cause
+}
+
+// Snippet returns the source line of the diagnostic with the columns it
+// covers underlined by carets, or an empty string if the line can't be read
+func (receiver *Diagnostic) Snippet() string {
+	if receiver.Filename == "" || receiver.Line < 1 {
+		return ""
+	}
+
+	source, err := os.ReadFile(receiver.Filename)
+	if err != nil {
+		return ""
+	}
+
+	lines := strings.Split(string(source), "\n")
+	if receiver.Line > len(lines) {
+		return ""
+	}
+
+	line := strings.TrimRight(lines[receiver.Line-1], "\r")
+	gutter := fmt.Sprintf("%5d | ", receiver.Line)
+	snippet := gutter + line + "\n"
+
+	if receiver.Column > 0 {
+		runes := []rune(line)
+		start := minInt(receiver.Column-1, len(runes))
+		end := maxInt(start+1, minInt(receiver.EndColumn-1, len(runes)))
+
+		// keep tabs so the carets line up with the source
+		padding := strings.Map(func(r rune) rune {
+			if r == '\t' {
+				return r
+			}
+			return ' '
+		}, string(runes[:start]))
+
+		snippet += strings.Repeat(" ", len(gutter)-2) + "| " + padding + strings.Repeat("^", end-start) + "\n"
+	}
+
+	return snippet
+}
+
+// Diagnostics is every problem found during a build
+type Diagnostics []*Diagnostic
+
+func (receiver Diagnostics) Error() string {
+	numErrors := receiver.NumErrors()
+	return fmt.Sprintf("%d error(s) and %d warning(s)", numErrors, len(receiver)-numErrors)
+}
+
+// Add appends err to the diagnostics
+// err can be Diagnostics, a single Diagnostic or any other error
+func (receiver *Diagnostics) Add(err error) {
+	var diagnostics Diagnostics
+	if errors.As(err, &diagnostics) {
+		*receiver = append(*receiver, diagnostics...)
+	} else if err != nil {
+		*receiver = append(*receiver, FromError("", err))
+	}
+}
+
+// NumErrors returns how many of the diagnostics are errors
+func (receiver Diagnostics) NumErrors() int {
+	numErrors := 0
+	for _, diagnostic := range receiver {
+		if diagnostic.Severity == Error {
+			numErrors++
+		}
+	}
+	return numErrors
+}
+
+// HasErrors reports whether any of the diagnostics are errors
+func (receiver Diagnostics) HasErrors() bool {
+	return receiver.NumErrors() > 0
+}
+
+// Sort orders the diagnostics by file and position
+func (receiver Diagnostics) Sort() {
+	sort.SliceStable(receiver, func(a, b int) bool {
+		left, right := receiver[a], receiver[b]
+		if left.Filename != right.Filename {
+			return left.Filename < right.Filename
+		}
+		if left.Line != right.Line {
+			return left.Line < right.Line
+		}
+		return left.Column < right.Column
+	})
+}
+
+// Print writes every diagnostic followed by its snippet to writer in
+// file and position order
+func (receiver Diagnostics) Print(writer io.Writer) {
+	receiver.Sort()
+
+	for _, diagnostic := range receiver {
+		fmt.Fprintln(writer, diagnostic.Error())
+		io.WriteString(writer, diagnostic.Snippet())
+	}
+
+	fmt.Fprintln(writer, receiver.Error())
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,143 @@
+package diagnostic
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiagnosticError(t *testing.T) {
+	var tests = []struct {
+		diagnostic Diagnostic
+		expected   string
+	}{
+		{Diagnostic{Filename: "a.html", Line: 3, Column: 5, Message: "bad"}, "a.html:3:5: error: bad"},
+		{Diagnostic{Filename: "a.html", Line: 3, Message: "bad"}, "a.html:3: error: bad"},
+		{Diagnostic{Filename: "a.html", Severity: Warning, Message: "odd"}, "a.html: warning: odd"},
+		{Diagnostic{Message: "bad"}, "error: bad"},
+		{Diagnostic{Line: 2, Column: 7, Message: "bad"}, "2:7: error: bad"},
+	}
+
+	for i, test := range tests {
+		if actual := test.diagnostic.Error(); actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestFromError(t *testing.T) {
+	positioned := fmt.Errorf("wrapped: %w", Errorf(2, 4, "bad %s", "token"))
+
+	var tests = []struct {
+		err      error
+		expected string
+	}{
+		{positioned, "a.html:2:4: error: bad token"},
+		{fmt.Errorf("plain"), "a.html: error: plain"},
+		{&Diagnostic{Filename: "b.html", Line: 1, Message: "kept"}, "b.html:1: error: kept"},
+	}
+
+	for i, test := range tests {
+		if actual := FromError("a.html", test.err).Error(); actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+type causeError struct{}
+
+func (receiver causeError) Error() string {
+	return "cause"
+}
+
+func TestWrapKeepsCause(t *testing.T) {
+	wrapped := FromError("a.html", Wrap(3, 2, causeError{}))
+
+	if actual := wrapped.Error(); actual != "a.html:3:2: error: cause" {
+		t.Errorf("expected %q, got %q", "a.html:3:2: error: cause", actual)
+	}
+
+	var cause causeError
+	if !errors.As(wrapped, &cause) {
+		t.Errorf("expected the cause to be found through the diagnostic")
+	}
+}
+
+func TestWarnIsNotAnError(t *testing.T) {
+	warning := FromError("a.html", Warn(3, 2, causeError{}))
+
+	if actual := warning.Error(); actual != "a.html:3:2: warning: cause" {
+		t.Errorf("expected %q, got %q", "a.html:3:2: warning: cause", actual)
+	}
+
+	if IsError(warning) {
+		t.Errorf("expected a warning not to count as an error")
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "page.html")
+	source := "first line\n\t{{: 1 + }}\nlast line\n"
+	if err := os.WriteFile(path, []byte(source), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		diagnostic Diagnostic
+		expected   string
+	}{
+		{
+			Diagnostic{Filename: path, Line: 2, Column: 6, EndColumn: 7},
+			"    2 | \t{{: 1 + }}\n      | \t    ^\n",
+		},
+		{
+			Diagnostic{Filename: path, Line: 2, Column: 2, EndColumn: 11},
+			"    2 | \t{{: 1 + }}\n      | \t^^^^^^^^^\n",
+		},
+		{
+			Diagnostic{Filename: path, Line: 1},
+			"    1 | first line\n",
+		},
+		{Diagnostic{Filename: path, Line: 10}, ""},
+		{Diagnostic{Filename: path}, ""},
+	}
+
+	for i, test := range tests {
+		if actual := test.diagnostic.Snippet(); actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestDiagnosticsPrint(t *testing.T) {
+	diagnostics := Diagnostics{}
+	diagnostics.Add(&Diagnostic{Filename: "b.html", Line: 1, Message: "second"})
+	diagnostics.Add(Diagnostics{
+		{Filename: "a.html", Line: 9, Message: "first"},
+		{Filename: "b.html", Line: 4, Severity: Warning, Message: "third"},
+	})
+	diagnostics.Add(nil)
+
+	output := &bytes.Buffer{}
+	diagnostics.Print(output)
+
+	expected := strings.Join([]string{
+		"a.html:9: error: first",
+		"b.html:1: error: second",
+		"b.html:4: warning: third",
+		"2 error(s) and 1 warning(s)",
+		"",
+	}, "\n")
+
+	if output.String() != expected {
+		t.Errorf("expected %q, got %q", expected, output.String())
+	}
+
+	if !diagnostics.HasErrors() {
+		t.Errorf("expected diagnostics to have errors")
+	}
+}
@@ -1,27 +1,50 @@
 package file
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"path"
+	"sync"
 	"time"
 )
 
+// LiveReloadPath is the Server-Sent Events endpoint pages connect to
+// when live reload is on
+const LiveReloadPath = "/_frizzy/livereload"
+
+// liveReloadScript reloads the page whenever the server sends an event
+var liveReloadScript = []byte(
+	`<script>new EventSource("` + LiveReloadPath + `").onmessage = function () { location.reload() }</script>`,
+)
+
 type DevServer struct {
 	ServerRoot string
 	Port       int
+	// LiveReload injects a script into HTML pages that reloads them
+	// each time Reload is called
+	LiveReload bool
+
+	once     sync.Once
+	reloader *reloader
 }
 
 func (receiver *DevServer) ListenAndServe() error {
 	rootFS := os.DirFS(receiver.ServerRoot)
 	address := fmt.Sprintf(":%d", receiver.Port)
+	handler := &devServerHandler{rootFS: rootFS}
+	if receiver.LiveReload {
+		handler.reloader = receiver.getReloader()
+	}
+
 	server := http.Server{
 		Addr:        address,
 		ReadTimeout: time.Second * 5,
-		Handler:     &devServerHandler{rootFS},
+		Handler:     handler,
 	}
 
 	log.Printf("serving files from %s\n", receiver.ServerRoot)
@@ -30,21 +53,119 @@ func (receiver *DevServer) ListenAndServe() error {
 	return server.ListenAndServe()
 }
 
+// Reload tells every connected page to reload
+func (receiver *DevServer) Reload() {
+	receiver.getReloader().broadcast()
+}
+
+func (receiver *DevServer) getReloader() *reloader {
+	receiver.once.Do(func() {
+		receiver.reloader = newReloader()
+	})
+	return receiver.reloader
+}
+
 type devServerHandler struct {
 	rootFS fs.FS
+	// reloader is nil unless live reload is on
+	reloader *reloader
 }
 
 func (receiver *devServerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	requestPath := request.URL.EscapedPath()
+	if receiver.reloader != nil && requestPath == LiveReloadPath {
+		receiver.reloader.ServeHTTP(writer, request)
+		return
+	}
+
 	if f, err := receiver.rootFS.Open(requestPath[1:]); err != nil {
 		writer.WriteHeader(404)
 		io.WriteString(writer, fmt.Sprintf("File %s not found: %s", requestPath, err))
 	} else {
+		defer f.Close()
+
 		if bytes, err := io.ReadAll(f); err != nil {
 			writer.WriteHeader(500)
 			io.WriteString(writer, fmt.Sprintf("File %s could not be read", requestPath))
 		} else {
+			if receiver.reloader != nil && path.Ext(requestPath) == ".html" {
+				bytes = injectLiveReload(bytes)
+			}
 			writer.Write(bytes)
 		}
 	}
 }
+
+// injectLiveReload adds the live reload script before the closing body
+// tag of page or at the end if it doesn't have one
+func injectLiveReload(page []byte) []byte {
+	end := bytes.LastIndex(bytes.ToLower(page), []byte("</body>"))
+	if end == -1 {
+		return append(page, liveReloadScript...)
+	}
+
+	injected := make([]byte, 0, len(page)+len(liveReloadScript))
+	injected = append(injected, page[:end]...)
+	injected = append(injected, liveReloadScript...)
+	return append(injected, page[end:]...)
+}
+
+// reloader sends a reload event to every connected page
+type reloader struct {
+	mut     sync.Mutex
+	clients map[chan struct{}]bool
+}
+
+func newReloader() *reloader {
+	return &reloader{clients: map[chan struct{}]bool{}}
+}
+
+func (receiver *reloader) broadcast() {
+	receiver.mut.Lock()
+	defer receiver.mut.Unlock()
+
+	for client := range receiver.clients {
+		// a client that hasn't handled the last reload doesn't need another
+		select {
+		case client <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ServeHTTP holds the request open as an event stream until the
+// client disconnects
+func (receiver *reloader) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		writer.WriteHeader(500)
+		io.WriteString(writer, "streaming is not supported")
+		return
+	}
+
+	client := make(chan struct{}, 1)
+	receiver.mut.Lock()
+	receiver.clients[client] = true
+	receiver.mut.Unlock()
+
+	defer func() {
+		receiver.mut.Lock()
+		delete(receiver.clients, client)
+		receiver.mut.Unlock()
+	}()
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.WriteHeader(200)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-client:
+			io.WriteString(writer, "data: reload\n\n")
+			flusher.Flush()
+		case <-request.Context().Done():
+			return
+		}
+	}
+}
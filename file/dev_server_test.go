@@ -0,0 +1,97 @@
+package file
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestInjectLiveReload(t *testing.T) {
+	script := string(liveReloadScript)
+
+	var tests = []struct {
+		page     string
+		expected string
+	}{
+		{"<html><body><p>hi</p></body></html>", "<html><body><p>hi</p>" + script + "</body></html>"},
+		{"<BODY>shouting</BODY>", "<BODY>shouting" + script + "</BODY>"},
+		{"<p>fragment</p>", "<p>fragment</p>" + script},
+	}
+
+	for i, test := range tests {
+		if actual := string(injectLiveReload([]byte(test.page))); actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestDevServerHandlerInjectsOnlyIntoHTML(t *testing.T) {
+	rootFS := fstest.MapFS{
+		"index.html": {Data: []byte("<body></body>")},
+		"style.css":  {Data: []byte("body {}")},
+	}
+
+	var tests = []struct {
+		liveReload bool
+		path       string
+		injected   bool
+	}{
+		{true, "/index.html", true},
+		{true, "/style.css", false},
+		{false, "/index.html", false},
+	}
+
+	for i, test := range tests {
+		handler := &devServerHandler{rootFS: rootFS}
+		if test.liveReload {
+			handler.reloader = newReloader()
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", test.path, nil))
+
+		if injected := strings.Contains(recorder.Body.String(), LiveReloadPath); injected != test.injected {
+			t.Errorf("%d: expected script injected into %s to be %t, got %t", i, test.path, test.injected, injected)
+		}
+	}
+}
+
+func TestReloadNotifiesConnectedPages(t *testing.T) {
+	devServer := &DevServer{LiveReload: true}
+	handler := &devServerHandler{rootFS: fstest.MapFS{}, reloader: devServer.getReloader()}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + LiveReloadPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("expected an event stream, got %q", contentType)
+	}
+
+	// the client is registered before the headers are flushed
+	devServer.Reload()
+
+	lines := make(chan string)
+	go func() {
+		line, _ := bufio.NewReader(response.Body).ReadString('\n')
+		lines <- line
+	}()
+
+	select {
+	case line := <-lines:
+		if line != "data: reload\n" {
+			t.Errorf("expected a reload event, got %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected a reload event, got nothing")
+	}
+
+}
@@ -0,0 +1,95 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FrontMatter holds the metadata decoded from the start of a file
+type FrontMatter map[string]interface{}
+
+var (
+	yamlDelimiter = "---"
+	tomlDelimiter = "+++"
+)
+
+// SplitFrontMatter separates a leading front matter block from input
+// The block can be YAML between --- lines, TOML between +++ lines or
+// a JSON object starting with {
+// It returns the decoded front matter, the remaining body and the number
+// of lines the front matter took up so later line numbers can be offset
+// If input has no front matter, the returned FrontMatter is nil
+func SplitFrontMatter(input []byte) (FrontMatter, []byte, int, error) {
+	firstLine, _ := splitLine(input)
+
+	switch {
+	case firstLine == yamlDelimiter:
+		return splitDelimitedFrontMatter(input, yamlDelimiter, yaml.Unmarshal)
+	case firstLine == tomlDelimiter:
+		return splitDelimitedFrontMatter(input, tomlDelimiter, toml.Unmarshal)
+	case len(input) > 0 && input[0] == '{' && !bytes.HasPrefix(input, []byte("{{")):
+		return splitJSONFrontMatter(input)
+	default:
+		return nil, input, 0, nil
+	}
+}
+
+// splitDelimitedFrontMatter decodes the lines between the opening
+// delimiter and the next line that matches it
+func splitDelimitedFrontMatter(input []byte, delimiter string, unmarshal func([]byte, interface{}) error) (FrontMatter, []byte, int, error) {
+	_, remaining := splitLine(input)
+	start := len(input) - len(remaining)
+	numLines := 1
+
+	for len(remaining) > 0 {
+		line, rest := splitLine(remaining)
+		end := len(input) - len(remaining)
+		numLines++
+
+		if line == delimiter {
+			frontMatter := FrontMatter{}
+			if err := unmarshal(input[start:end], &frontMatter); err != nil {
+				return nil, input, 0, fmt.Errorf("invalid front matter: %s", err)
+			}
+
+			return frontMatter, rest, numLines, nil
+		}
+
+		remaining = rest
+	}
+
+	return nil, input, 0, fmt.Errorf("front matter is missing its closing %s", delimiter)
+}
+
+// splitJSONFrontMatter decodes the JSON object at the start of input
+// Anything after the object on its closing line is dropped
+func splitJSONFrontMatter(input []byte) (FrontMatter, []byte, int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+
+	frontMatter := FrontMatter{}
+	if err := decoder.Decode(&frontMatter); err != nil {
+		return nil, input, 0, fmt.Errorf("invalid front matter: %s", err)
+	}
+
+	end := int(decoder.InputOffset())
+	numLines := bytes.Count(input[:end], []byte("\n")) + 1
+	_, body := splitLine(input[end:])
+
+	return frontMatter, body, numLines, nil
+}
+
+// splitLine returns the first line of input without its line ending
+// and the input after that line
+func splitLine(input []byte) (string, []byte) {
+	line, rest := input, []byte{}
+	if i := bytes.IndexByte(input, '\n'); i != -1 {
+		line, rest = input[:i], input[i+1:]
+	}
+
+	return string(bytes.TrimRight(line, " \t\r")), rest
+}
@@ -0,0 +1,78 @@
+package file
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSplitFrontMatterDecodesEachFormat(t *testing.T) {
+	var tests = []struct {
+		input    string
+		body     string
+		numLines int
+	}{
+		{"---\ntitle: Hello\ntags:\n  - a\n  - b\n---\n<p>body</p>\n", "<p>body</p>\n", 6},
+		{"+++\ntitle = \"Hello\"\ntags = [\"a\", \"b\"]\n+++\n<p>body</p>\n", "<p>body</p>\n", 4},
+		{"{\n  \"title\": \"Hello\",\n  \"tags\": [\"a\", \"b\"]\n}\n<p>body</p>\n", "<p>body</p>\n", 4},
+		{"---\r\ntitle: Hello\r\ntags: [a, b]\r\n---\r\n<p>body</p>", "<p>body</p>", 4},
+	}
+
+	for i, test := range tests {
+		frontMatter, body, numLines, err := SplitFrontMatter([]byte(test.input))
+
+		if err != nil {
+			t.Errorf("%d: expected no errors, got %q", i, err)
+			continue
+		}
+
+		if frontMatter["title"] != "Hello" {
+			t.Errorf("%d: expected title to be Hello, got %v", i, frontMatter["title"])
+		}
+
+		if tags := fmt.Sprint(frontMatter["tags"]); tags != "[a b]" {
+			t.Errorf("%d: expected tags to be [a b], got %s", i, tags)
+		}
+
+		if string(body) != test.body {
+			t.Errorf("%d: expected body %q, got %q", i, test.body, body)
+		}
+
+		if numLines != test.numLines {
+			t.Errorf("%d: expected front matter to take %d lines, got %d", i, test.numLines, numLines)
+		}
+	}
+}
+
+func TestSplitFrontMatterIgnoresFilesWithoutFrontMatter(t *testing.T) {
+	var tests = []string{
+		"<p>body</p>",
+		"{{title = `foo`}}\n",
+		"",
+		"--- not front matter\n",
+	}
+
+	for i, test := range tests {
+		frontMatter, body, numLines, err := SplitFrontMatter([]byte(test))
+
+		if err != nil || frontMatter != nil || numLines != 0 {
+			t.Errorf("%d: expected no front matter, got %v, %d lines, error %v", i, frontMatter, numLines, err)
+		} else if string(body) != test {
+			t.Errorf("%d: expected body %q, got %q", i, test, body)
+		}
+	}
+}
+
+func TestSplitFrontMatterReturnsErrors(t *testing.T) {
+	var tests = []string{
+		"---\ntitle: Hello\n",
+		"+++\ntitle = \n+++\n",
+		"{\"title\": }\n",
+		"---\n: [\n---\n",
+	}
+
+	for i, test := range tests {
+		if _, _, _, err := SplitFrontMatter([]byte(test)); err == nil {
+			t.Errorf("%d: expected an error for %q", i, test)
+		}
+	}
+}
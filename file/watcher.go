@@ -0,0 +1,106 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultWatchInterval is how often a Watcher polls when Interval isn't set
+const DefaultWatchInterval = 500 * time.Millisecond
+
+// Watcher polls directories for files that are added, modified or removed
+type Watcher struct {
+	Paths    []string
+	Interval time.Duration
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func (receiver fileState) equal(other fileState) bool {
+	return receiver.modTime.Equal(other.modTime) && receiver.size == other.size
+}
+
+// Watch sends the sorted paths of the files that changed between polls
+// until ctx is done
+// Changes made within the same interval are sent together
+func (receiver *Watcher) Watch(ctx context.Context) <-chan []string {
+	changedChan := make(chan []string)
+	interval := receiver.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	go func() {
+		defer close(changedChan)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		previous := receiver.snapshot()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current := receiver.snapshot()
+			if changed := diffSnapshots(previous, current); len(changed) > 0 {
+				select {
+				case changedChan <- changed:
+				case <-ctx.Done():
+					return
+				}
+			}
+			previous = current
+		}
+	}()
+
+	return changedChan
+}
+
+// snapshot returns the state of every file below the watched paths
+func (receiver *Watcher) snapshot() map[string]fileState {
+	states := map[string]fileState{}
+
+	for _, watchPath := range receiver.Paths {
+		filepath.Walk(watchPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				// the path may have been removed since it was listed
+				return nil
+			}
+
+			if !info.IsDir() {
+				states[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+			}
+			return nil
+		})
+	}
+
+	return states
+}
+
+func diffSnapshots(previous, current map[string]fileState) []string {
+	changed := []string{}
+
+	for path, state := range current {
+		if previousState, ok := previous[path]; !ok || !previousState.equal(state) {
+			changed = append(changed, path)
+		}
+	}
+
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+
+	sort.Strings(changed)
+	return changed
+}
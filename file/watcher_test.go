@@ -0,0 +1,72 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatcherSendsChangedPaths(t *testing.T) {
+	root := t.TempDir()
+	modified := filepath.Join(root, "modified.md")
+	removed := filepath.Join(root, "removed.md")
+	added := filepath.Join(root, "nested", "added.md")
+
+	for _, path := range []string{modified, removed} {
+		if err := os.WriteFile(path, []byte("before"), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	watcher := Watcher{Paths: []string{root}, Interval: 20 * time.Millisecond}
+	changedChan := watcher.Watch(ctx)
+	// let the first snapshot be taken
+	time.Sleep(50 * time.Millisecond)
+
+	os.WriteFile(modified, []byte("after, and longer"), 0640)
+	os.Remove(removed)
+	os.MkdirAll(filepath.Dir(added), 0750)
+	os.WriteFile(added, []byte("new"), 0640)
+
+	seen := map[string]bool{}
+	for len(seen) < 3 {
+		select {
+		case changed := <-changedChan:
+			for _, path := range changed {
+				seen[path] = true
+			}
+		case <-ctx.Done():
+			t.Fatalf("expected 3 changed paths, got %v", seen)
+		}
+	}
+
+	expected := map[string]bool{modified: true, removed: true, added: true}
+	if !reflect.DeepEqual(seen, expected) {
+		t.Errorf("expected changed paths %v, got %v", expected, seen)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	now := time.Now()
+	previous := map[string]fileState{
+		"same":    {modTime: now, size: 1},
+		"touched": {modTime: now, size: 1},
+		"gone":    {modTime: now, size: 1},
+	}
+	current := map[string]fileState{
+		"same":    {modTime: now, size: 1},
+		"touched": {modTime: now.Add(time.Second), size: 1},
+		"new":     {modTime: now, size: 1},
+	}
+
+	expected := []string{"gone", "new", "touched"}
+	if actual := diffSnapshots(previous, current); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
@@ -2,4 +2,8 @@ module mettlach.codes/frizzy
 
 go 1.16
 
-require github.com/gomarkdown/markdown v0.0.0-20210208175418-bda154fe17d8
+require (
+	github.com/BurntSushi/toml v1.2.1
+	github.com/gomarkdown/markdown v0.0.0-20210208175418-bda154fe17d8
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -3,9 +3,15 @@ package lexer
 import (
 	"bufio"
 	"context"
+	"fmt"
 	"io"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"mettlach.codes/frizzy/diagnostic"
 )
 
 var (
@@ -17,23 +23,41 @@ var (
 	assignOp             = regexp.MustCompile(`^=`)
 	unaryOp              = regexp.MustCompile(`^!`)
 	identExp             = regexp.MustCompile(`^_?[a-zA-Z]+[a-zA-Z0-9_]*`)
-	strExp               = regexp.MustCompile(`^"[^"]*"`)
+	strExp               = regexp.MustCompile(`^["']`)
+	floatExp             = regexp.MustCompile(`^[0-9]+\.[0-9]+`)
 	numExp               = regexp.MustCompile(`^[0-9]+`)
-	ifExp                = regexp.MustCompile(`^{{if`)
-	elseIfExp            = regexp.MustCompile(`^{{else_if`)
-	elseExp              = regexp.MustCompile(`^{{else}}`)
-	forExp               = regexp.MustCompile(`^{{for`)
-	inExp                = regexp.MustCompile(`^in`)
-	endExp               = regexp.MustCompile(`^{{end}}`)
-	boolExp              = regexp.MustCompile(`^(true|false)`)
-	symbolExp            = regexp.MustCompile(`^[(),\.]`)
+	ifExp                = regexp.MustCompile(`^{{(-\s*)?if`)
+	elseIfExp            = regexp.MustCompile(`^{{(-\s*)?else_if`)
+	elseExp              = regexp.MustCompile(`^{{(-\s*)?else\s*-?}}`)
+	forExp               = regexp.MustCompile(`^{{(-\s*)?for`)
+	inExp                = regexp.MustCompile(`^in\b`)
+	endExp               = regexp.MustCompile(`^{{(-\s*)?end\s*-?}}`)
+	extendsExp           = regexp.MustCompile(`^({{extends)\s+["'` + "`]")
+	namedBlockExp        = regexp.MustCompile(`^({{block)\s+["'` + "`]")
+	boolExp              = regexp.MustCompile(`^(true|false)\b`)
+	symbolExp            = regexp.MustCompile(`^(\?\?|[(),\.\[\]:|?])`)
+	openBraceExp         = regexp.MustCompile(`^{([^{]|$)`)
+	closeBraceExp        = regexp.MustCompile(`^}`)
+	trimRightBlockExp    = regexp.MustCompile(`^-}}`)
 	noWhitespaceBlockExp = regexp.MustCompile(`^-}`)
+	trimLeftBlockExp     = regexp.MustCompile(`^{{-(:|\s)`)
+	printBlockExp        = regexp.MustCompile(`^{{-?:`)
 	blockExp             = regexp.MustCompile(`^({{:|{{|}})`)
 	openRawStringExp     = regexp.MustCompile("^`")
 	closeRawStringExp    = regexp.MustCompile("`")
 	whitespaceExp        = regexp.MustCompile(`^\s+`)
 )
 
+// Comment and raw block markers
+// Text between them is never tokenized so they are matched as plain
+// strings rather than with the block expressions above
+const (
+	openComment  = "{{#"
+	closeComment = "#}}"
+	openRaw      = "{{raw}}"
+	closeRaw     = "{{endraw}}"
+)
+
 // Lexer states
 type LexerState int
 
@@ -42,17 +66,72 @@ const (
 	passthroughNoWhitespace
 	inBlock
 	inStr
+	inStrBody
+	inComment
+	inRaw
 )
 
+// InputLine is the part of a source line that hasn't been lexed yet
+// byteCol and runeCol are how far into the source line it starts
 type InputLine struct {
 	line    string
 	lineNum int
+	byteCol int
+	runeCol int
+}
+
+// advance drops the first n bytes of the line
+func (receiver InputLine) advance(n int) InputLine {
+	receiver.runeCol += utf8.RuneCountInString(receiver.line[:n])
+	receiver.byteCol += n
+	receiver.line = receiver.line[n:]
+	return receiver
+}
+
+// tokenData returns the position of a token that takes up the first
+// n bytes of the line
+func (receiver InputLine) tokenData(n int) TokenData {
+	return TokenData{
+		LineNum: receiver.lineNum,
+		LineCol: receiver.runeCol + 1,
+		ByteCol: receiver.byteCol + 1,
+		EndCol:  receiver.runeCol + utf8.RuneCountInString(receiver.line[:n]) + 1,
+	}
 }
 
 // Lexer turns a stream of text lines into a stream of tokens
 type Lexer struct {
-	lineChan <-chan InputLine
-	state    LexerState
+	// LineOffset is added to the line number of every token
+	// e.g. when the input comes after stripped front matter
+	LineOffset int
+	// TrimBlockLines drops the whitespace around tags that are alone on
+	// their line, including the newline, so they leave no blank lines
+	TrimBlockLines bool
+	lineChan       <-chan InputLine
+	state          LexerState
+	// braceDepth is how many map literals are open so their closing
+	// braces aren't mistaken for the end of the block
+	braceDepth int
+	// openData is where the comment, raw block or raw string being
+	// lexed was opened
+	openData TokenData
+	// raw collects the text of a raw block or raw string until it is
+	// closed
+	raw strings.Builder
+	// err is the first error found in the input
+	err error
+	// trimPrevious is set when a {{- trims every token before it on the
+	// current line so the lines before it are trimmed as well
+	trimPrevious bool
+	// trimmedWhitespace is set when a -}} has trimmed whitespace that
+	// hasn't been followed by any text yet
+	trimmedWhitespace bool
+	// printing is whether the block being lexed outputs text
+	printing bool
+	// lineTags counts the tags on the current line and lineOutputs is
+	// whether any of them output text
+	lineTags    int
+	lineOutputs bool
 }
 
 func (receiver *Lexer) Lex(inputReader io.Reader, ctx context.Context) (<-chan []Token, <-chan error) {
@@ -66,14 +145,28 @@ func (receiver *Lexer) Lex(inputReader io.Reader, ctx context.Context) (<-chan [
 		defer close(tokChan)
 		defer close(errChan)
 
-		lineNum := 0
-		i := 1
+		// pending holds the whitespace at the end of the lines lexed so
+		// far until it's known whether a later {{- trims it
+		pending := []Token{}
+
+		lineNum := receiver.LineOffset
+		i := receiver.LineOffset + 1
 		for inputBuffer.Scan() {
 			line := InputLine{line: inputBuffer.Text(), lineNum: i}
 			i++
 
+			tokens := receiver.processLine(line)
+			if receiver.trimPrevious {
+				trimTrailingWhitespace(pending)
+				receiver.trimPrevious = false
+			}
+
+			tokens = append(pending, tokens...)
+			held := getTrailingWhitespaceIndex(tokens)
+			pending = append([]Token{}, tokens[held:]...)
+
 			select {
-			case tokChan <- receiver.processLine(line):
+			case tokChan <- tokens[:held]:
 				lineNum++
 			case <-ctx.Done():
 				return
@@ -85,10 +178,15 @@ func (receiver *Lexer) Lex(inputReader io.Reader, ctx context.Context) (<-chan [
 			return
 		}
 
+		if err := receiver.getLexError(); err != nil {
+			errChan <- err
+			return
+		}
+
 		// send final EOL token
 		EOLTok := EOLToken{}
 		EOLTok.LineNum = lineNum
-		tokChan <- []Token{EOLTok}
+		tokChan <- append(pending, EOLTok)
 	}()
 
 	return tokChan, errChan
@@ -107,6 +205,13 @@ func (receiver *Lexer) processLine(line InputLine) []Token {
 	tokens := []Token{}
 	remainingLine := line
 
+	// a line that starts inside a tag is part of that tag
+	receiver.lineTags, receiver.lineOutputs = 0, false
+	if receiver.state != passthrough && receiver.state != passthroughNoWhitespace {
+		receiver.lineTags = 1
+		receiver.lineOutputs = receiver.printing || receiver.state == inRaw
+	}
+
 	for len(remainingLine.line) > 0 {
 		if receiver.state == passthrough || receiver.state == passthroughNoWhitespace {
 			tok, unprocessed := receiver.processPassthroughTokens(remainingLine)
@@ -114,50 +219,237 @@ func (receiver *Lexer) processLine(line InputLine) []Token {
 				tokens = append(tokens, tok)
 			}
 
+			if receiver.state == inBlock && isTrimLeftBlock(unprocessed.line) && trimTrailingWhitespace(tokens) {
+				receiver.trimPrevious = true
+			}
+
 			remainingLine = unprocessed
 		} else if receiver.state == inBlock {
 			toks, unprocessed := receiver.processTokensInBlock(remainingLine)
 			tokens = append(tokens, toks...)
 
 			remainingLine = unprocessed
-		} else if receiver.state == inStr {
+		} else if receiver.state == inStr || receiver.state == inStrBody {
 			tok, unprocessed := receiver.getRawStringToken(remainingLine)
 			if tok != nil {
 				tokens = append(tokens, tok)
 			}
 
+			remainingLine = unprocessed
+		} else if receiver.state == inComment {
+			remainingLine = receiver.skipComment(remainingLine)
+		} else if receiver.state == inRaw {
+			tok, unprocessed := receiver.getRawBlockToken(remainingLine)
+			if tok != nil {
+				tokens = append(tokens, tok)
+			}
+
 			remainingLine = unprocessed
 		}
 	}
 
+	if receiver.TrimBlockLines && receiver.lineTags > 0 && !receiver.lineOutputs {
+		clearWhitespaceLine(tokens)
+	}
+
 	return tokens
 }
 
-func (receiver *Lexer) processPassthroughTokens(inputLine InputLine) (Token, InputLine) {
-	passthroughText := inputLine.line
-	remainder := ""
+// isTrimLeftBlock reports whether line starts with a tag that trims the
+// whitespace before it
+func isTrimLeftBlock(line string) bool {
+	if !strings.HasPrefix(line, "{{-") {
+		return false
+	}
 
-	if receiver.state == passthroughNoWhitespace {
-		passthroughText = whitespaceExp.ReplaceAllString(passthroughText, "")
+	for _, exp := range []*regexp.Regexp{ifExp, elseIfExp, elseExp, forExp, endExp, trimLeftBlockExp} {
+		if exp.MatchString(line) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trimTrailingWhitespace trims the whitespace from the passthrough
+// tokens at the end of tokens
+// Emptied tokens are kept so a body that was only whitespace still
+// parses. Returns true if every token was emptied
+func trimTrailingWhitespace(tokens []Token) bool {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		passthroughTok, ok := tokens[i].(PassthroughToken)
+		if !ok {
+			return false
+		}
+
+		passthroughTok.Value = strings.TrimRightFunc(passthroughTok.Value, unicode.IsSpace)
+		tokens[i] = passthroughTok
+
+		if passthroughTok.Value != "" {
+			return false
+		}
 	}
 
-	if loc := strings.Index(passthroughText, "{{"); loc != -1 {
-		receiver.state = inBlock
+	return true
+}
 
-		remainder = passthroughText[loc:]
-		passthroughText = passthroughText[:loc]
+// getTrailingWhitespaceIndex returns the index of the first of the
+// passthrough tokens at the end of tokens that could still be trimmed
+// by a {{- i.e. trailing whitespace and the text it follows
+func getTrailingWhitespaceIndex(tokens []Token) int {
+	i := len(tokens)
+	for i > 0 {
+		passthroughTok, ok := tokens[i-1].(PassthroughToken)
+		if !ok {
+			break
+		}
+
+		i--
+		if strings.TrimSpace(passthroughTok.Value) != "" {
+			break
+		}
+	}
+
+	return i
+}
+
+// clearWhitespaceLine empties the passthrough tokens of a line if they
+// are all whitespace
+func clearWhitespaceLine(tokens []Token) {
+	for _, tok := range tokens {
+		if passthroughTok, ok := tok.(PassthroughToken); ok && strings.TrimSpace(passthroughTok.Value) != "" {
+			return
+		}
+	}
+
+	for i, tok := range tokens {
+		if passthroughTok, ok := tok.(PassthroughToken); ok {
+			passthroughTok.Value = ""
+			tokens[i] = passthroughTok
+		}
+	}
+}
+
+func (receiver *Lexer) processPassthroughTokens(inputLine InputLine) (Token, InputLine) {
+	if receiver.state == passthroughNoWhitespace {
+		if loc := whitespaceExp.FindStringIndex(inputLine.line); loc != nil {
+			receiver.trimmedWhitespace = true
+			inputLine = inputLine.advance(loc[1])
+		}
+
+		// keep trimming until the next text or tag
+		if len(inputLine.line) == 0 {
+			return nil, inputLine
+		}
+	}
+
+	// skip is the length of a comment or raw block opener which is
+	// dropped rather than lexed as a block
+	end, skip := len(inputLine.line), 0
+	if loc := strings.Index(inputLine.line, "{{"); loc != -1 {
+		receiver.state, skip = receiver.getOpenState(inputLine.advance(loc))
+		end = loc
 	} else {
 		receiver.state = passthrough
 	}
 
-	inputLine.line = remainder
-	if passthroughText == "" {
-		return nil, inputLine
+	trimmedWhitespace := receiver.trimmedWhitespace
+	receiver.trimmedWhitespace = false
+
+	if end == 0 {
+		if trimmedWhitespace {
+			// leave an empty token so a body that was only whitespace
+			// still parses
+			return PassthroughToken{TokenData: inputLine.tokenData(0)}, inputLine.advance(skip)
+		}
+		return nil, inputLine.advance(skip)
 	}
 
-	tok := PassthroughToken{Value: passthroughText}
-	tok.LineNum = inputLine.lineNum
-	return tok, inputLine
+	passthroughText, remaining := extractToken([]int{0, end}, inputLine)
+	tok := PassthroughToken{Value: passthroughText, TokenData: inputLine.tokenData(end)}
+	return tok, remaining.advance(skip)
+}
+
+// getOpenState returns the state to lex a line starting with {{ in and
+// the length of the opener if it starts a comment or raw block
+func (receiver *Lexer) getOpenState(inputLine InputLine) (LexerState, int) {
+	receiver.lineTags++
+	receiver.printing = printBlockExp.MatchString(inputLine.line)
+	receiver.lineOutputs = receiver.lineOutputs || receiver.printing
+
+	if strings.HasPrefix(inputLine.line, openComment) {
+		receiver.openData = inputLine.tokenData(len(openComment))
+		return inComment, len(openComment)
+	} else if strings.HasPrefix(inputLine.line, openRaw) {
+		receiver.lineOutputs = true
+		receiver.openData = inputLine.tokenData(len(openRaw))
+		return inRaw, len(openRaw)
+	}
+
+	return inBlock, 0
+}
+
+// skipComment drops everything up to and including the end of the
+// current comment
+func (receiver *Lexer) skipComment(inputLine InputLine) InputLine {
+	if loc := strings.Index(inputLine.line, closeComment); loc != -1 {
+		receiver.state = passthrough
+		return inputLine.advance(loc + len(closeComment))
+	}
+
+	return inputLine.advance(len(inputLine.line))
+}
+
+// getRawBlockToken collects the text of a raw block without lexing it
+// and returns it as a single passthrough token once the block is closed
+func (receiver *Lexer) getRawBlockToken(inputLine InputLine) (Token, InputLine) {
+	loc := strings.Index(inputLine.line, closeRaw)
+	if loc == -1 {
+		receiver.raw.WriteString(inputLine.line)
+		return nil, inputLine.advance(len(inputLine.line))
+	}
+
+	receiver.raw.WriteString(inputLine.line[:loc])
+	receiver.state = passthrough
+
+	value := receiver.raw.String()
+	receiver.raw.Reset()
+
+	if value == "" {
+		return nil, inputLine.advance(loc + len(closeRaw))
+	}
+
+	// the token starts just after {{raw}}
+	tokData := TokenData{
+		LineNum: receiver.openData.LineNum,
+		LineCol: receiver.openData.EndCol,
+		ByteCol: receiver.openData.ByteCol + len(openRaw),
+		EndCol:  inputLine.advance(loc).runeCol + 1,
+	}
+
+	return PassthroughToken{Value: value, TokenData: tokData}, inputLine.advance(loc + len(closeRaw))
+}
+
+// getLexError returns the first error found in the input or an error if
+// the input ended inside a comment, raw block or raw string
+func (receiver *Lexer) getLexError() error {
+	if receiver.err != nil {
+		return receiver.err
+	}
+
+	switch receiver.state {
+	case inComment:
+		return diagnostic.Errorf(receiver.openData.LineNum, receiver.openData.LineCol,
+			"comment is never closed, expected %q", closeComment)
+	case inRaw:
+		return diagnostic.Errorf(receiver.openData.LineNum, receiver.openData.LineCol,
+			"raw block is never closed, expected %q", closeRaw)
+	case inStrBody:
+		return diagnostic.Errorf(receiver.openData.LineNum, receiver.openData.LineCol,
+			"raw string is never closed, expected %q", "`")
+	default:
+		return nil
+	}
 }
 
 func (receiver *Lexer) processTokensInBlock(inputLine InputLine) ([]Token, InputLine) {
@@ -166,7 +458,10 @@ func (receiver *Lexer) processTokensInBlock(inputLine InputLine) ([]Token, Input
 	remainingLine := inputLine
 
 	for len(remainingLine.line) > 0 && receiver.state == inBlock {
-		remainingLine.line = whitespaceExp.ReplaceAllString(remainingLine.line, "")
+		if loc := whitespaceExp.FindStringIndex(remainingLine.line); loc != nil {
+			remainingLine = remainingLine.advance(loc[1])
+		}
+
 		if openRawStringExp.MatchString(remainingLine.line) {
 			receiver.state = inStr
 		} else {
@@ -183,147 +478,261 @@ func (receiver *Lexer) processTokensInBlock(inputLine InputLine) ([]Token, Input
 	return toks, remainingLine
 }
 
+// getRawStringToken collects the text of a raw string, which can span
+// lines, and returns it as a single token once the string is closed
+// The token is positioned at the opening quote
 func (receiver *Lexer) getRawStringToken(inputLine InputLine) (Token, InputLine) {
-	rawStr := ""
-	var tok Token
+	if receiver.state == inStr {
+		// drop opening quote
+		receiver.openData = inputLine.tokenData(1)
+		inputLine = inputLine.advance(1)
+		receiver.state = inStrBody
+	}
+
+	loc := closeRawStringExp.FindStringIndex(inputLine.line)
+	if loc == nil {
+		receiver.raw.WriteString(inputLine.line)
+		return nil, inputLine.advance(len(inputLine.line))
+	}
+
+	receiver.raw.WriteString(inputLine.line[:loc[0]])
+	remaining := inputLine.advance(loc[1])
+	receiver.state = inBlock
 
-	lineNum := inputLine.lineNum
-	remaining := inputLine.line
+	tokData := receiver.openData
+	tokData.EndCol = remaining.runeCol + 1
+	tok := StrToken{Str: receiver.raw.String(), TokenData: tokData}
+	receiver.raw.Reset()
 
-	for len(remaining) > 0 && receiver.state == inStr {
-		if remaining[0] == '`' {
-			// drop opening quote
-			remaining = remaining[1:]
+	return tok, remaining
+}
+
+// getQuotedStringToken returns the token for the single or double quoted
+// string at the start of the line with its escape sequences replaced
+func (receiver *Lexer) getQuotedStringToken(inputLine InputLine) (Token, InputLine) {
+	line := inputLine.line
+	quote := line[0]
+
+	var str strings.Builder
+	for i := 1; i < len(line); {
+		switch line[i] {
+		case quote:
+			tok := StrToken{Str: str.String(), TokenData: inputLine.tokenData(i + 1)}
+			return tok, inputLine.advance(i + 1)
+		case '\\':
+			char, size, err := unescape(line[i:])
+			if err != nil {
+				escapeData := inputLine.advance(i).tokenData(size)
+				receiver.setError(diagnostic.Errorf(escapeData.LineNum, escapeData.LineCol, "%s", err))
+				str.WriteString(line[i : i+size])
+			} else {
+				str.WriteRune(char)
+			}
+			i += size
+		default:
+			str.WriteByte(line[i])
+			i++
 		}
+	}
 
-		if loc := closeRawStringExp.FindStringIndex(remaining); loc != nil {
-			rawStr += remaining[:loc[0]]
-			remaining = remaining[loc[1]:]
-			tok = StrToken{Str: rawStr, TokenData: TokenData{LineNum: lineNum}}
-			receiver.state = inBlock
-		} else {
-			rawStr += remaining
-			remaining = ""
+	openData := inputLine.tokenData(1)
+	receiver.setError(diagnostic.Errorf(openData.LineNum, openData.LineCol,
+		"string is never closed, expected %q before the end of the line", string(quote)))
+
+	tok := StrToken{Str: str.String(), TokenData: inputLine.tokenData(len(line))}
+	return tok, inputLine.advance(len(line))
+}
+
+// unescape returns the character for the escape sequence at the start
+// of str and the length of the sequence
+func unescape(str string) (rune, int, error) {
+	if len(str) < 2 {
+		return 0, len(str), fmt.Errorf("unfinished escape sequence")
+	}
+
+	switch str[1] {
+	case '"', '\'', '\\':
+		return rune(str[1]), 2, nil
+	case 'n':
+		return '\n', 2, nil
+	case 't':
+		return '\t', 2, nil
+	case 'r':
+		return '\r', 2, nil
+	case 'u':
+		// \u{1F600}
+		end := strings.IndexByte(str, '}')
+		if !strings.HasPrefix(str[2:], "{") || end == -1 {
+			return 0, 2, fmt.Errorf(`expected \u{...} with a hex code point`)
+		}
+
+		code, err := strconv.ParseUint(str[3:end], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return 0, end + 1, fmt.Errorf("invalid code point in escape sequence %q", str[:end+1])
 		}
+		return rune(code), end + 1, nil
+	default:
+		// other escapes like \d in a regex are kept as they are
+		return '\\', 1, nil
 	}
+}
 
-	return tok, InputLine{line: remaining, lineNum: lineNum}
+// setError records err if it is the first error found in the input
+func (receiver *Lexer) setError(err error) {
+	if receiver.err == nil {
+		receiver.err = err
+	}
 }
 
 func (receiver *Lexer) getNextBlockToken(inputLine InputLine) (Token, InputLine) {
-	tokData := TokenData{LineNum: inputLine.lineNum}
+	if loc := openBraceExp.FindStringIndex(inputLine.line); loc != nil { // should come before blockExp
+		receiver.braceDepth++
+		symbol, remaining := extractToken([]int{0, 1}, inputLine)
+		token := SymbolToken{Symbol: symbol, TokenData: inputLine.tokenData(1)}
+		return token, remaining
+	} else if loc := closeBraceExp.FindStringIndex(inputLine.line); loc != nil && receiver.braceDepth > 0 {
+		receiver.braceDepth--
+		symbol, remaining := extractToken(loc, inputLine)
+		token := SymbolToken{Symbol: symbol, TokenData: inputLine.tokenData(loc[1])}
+		return token, remaining
+	} else if loc := trimRightBlockExp.FindStringIndex(inputLine.line); loc != nil { // should come before noWhitespaceBlockExp
+		receiver.state = passthroughNoWhitespace
+		receiver.braceDepth = 0
+		_, remaining := extractToken(loc, inputLine)
+		token := BlockToken{Block: "}}", TokenData: inputLine.tokenData(loc[1])}
 
-	if loc := noWhitespaceBlockExp.FindStringIndex(inputLine.line); loc != nil { // should come before subOp
+		return token, remaining
+	} else if loc := noWhitespaceBlockExp.FindStringIndex(inputLine.line); loc != nil { // should come before subOp
 		receiver.state = passthroughNoWhitespace
 		block, remaining := extractToken(loc, inputLine)
-		token := BlockToken{Block: block}
+		token := BlockToken{Block: block, TokenData: inputLine.tokenData(loc[1])}
 
 		return token, remaining
 	} else if loc := multOp.FindStringIndex(inputLine.line); loc != nil {
 		operator, remaining := extractToken(loc, inputLine)
-		token := MultOpToken{Operator: operator, TokenData: tokData}
+		token := MultOpToken{Operator: operator, TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
 	} else if loc := addOp.FindStringIndex(inputLine.line); loc != nil {
 		_, remaining := extractToken(loc, inputLine)
-		token := AddOpToken{TokenData: tokData}
+		token := AddOpToken{TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
 	} else if loc := subOp.FindStringIndex(inputLine.line); loc != nil {
 		_, remaining := extractToken(loc, inputLine)
-		token := SubOpToken{TokenData: tokData}
+		token := SubOpToken{TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
 	} else if loc := relOp.FindStringIndex(inputLine.line); loc != nil {
 		operator, remaining := extractToken(loc, inputLine)
-		token := RelOpToken{Operator: operator, TokenData: tokData}
+		token := RelOpToken{Operator: operator, TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
 	} else if loc := logicOp.FindStringIndex(inputLine.line); loc != nil {
 		operator, remaining := extractToken(loc, inputLine)
-		token := LogicOpToken{Operator: operator, TokenData: tokData}
+		token := LogicOpToken{Operator: operator, TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
 	} else if loc := assignOp.FindStringIndex(inputLine.line); loc != nil {
 		operator, remaining := extractToken(loc, inputLine)
-		token := AssignOpToken{Operator: operator, TokenData: tokData}
+		token := AssignOpToken{Operator: operator, TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
 	} else if loc := unaryOp.FindStringIndex(inputLine.line); loc != nil {
 		operator, remaining := extractToken(loc, inputLine)
-		token := NegationOpToken{Operator: operator, TokenData: tokData}
+		token := NegationOpToken{Operator: operator, TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
-	} else if loc := strExp.FindStringIndex(inputLine.line); loc != nil {
-		str, remaining := extractToken(loc, inputLine)
-
-		// drop open and close quotes
-		str = str[1 : len(str)-1]
-		token := StrToken{Str: str, TokenData: tokData}
+	} else if strExp.MatchString(inputLine.line) {
+		return receiver.getQuotedStringToken(inputLine)
+	} else if loc := floatExp.FindStringIndex(inputLine.line); loc != nil { // should come before numExp
+		num, remaining := extractToken(loc, inputLine)
+		token := FloatToken{Num: num, TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
 	} else if loc := numExp.FindStringIndex(inputLine.line); loc != nil {
 		num, remaining := extractToken(loc, inputLine)
-		token := NumToken{Num: num, TokenData: tokData}
+		token := NumToken{Num: num, TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
 	} else if loc := boolExp.FindStringIndex(inputLine.line); loc != nil {
 		boolVal, remaining := extractToken(loc, inputLine)
-		token := BoolToken{Value: boolVal, TokenData: tokData}
+		token := BoolToken{Value: boolVal, TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
 	} else if loc := ifExp.FindStringIndex(inputLine.line); loc != nil {
 		_, remaining := extractToken(loc, inputLine)
-		token := IfToken{TokenData: tokData}
+		token := IfToken{TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
 	} else if loc := elseIfExp.FindStringIndex(inputLine.line); loc != nil {
 		_, remaining := extractToken(loc, inputLine)
-		token := ElseIfToken{TokenData: tokData}
+		token := ElseIfToken{TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
 	} else if loc := elseExp.FindStringIndex(inputLine.line); loc != nil {
-		_, remaining := extractToken(loc, inputLine)
-		token := ElseToken{TokenData: tokData}
-		receiver.state = passthrough
+		tag, remaining := extractToken(loc, inputLine)
+		token := ElseToken{TokenData: inputLine.tokenData(loc[1])}
+		receiver.state = getStateAfterTag(tag)
 		return token, remaining
 	} else if loc := forExp.FindStringIndex(inputLine.line); loc != nil {
 		_, remaining := extractToken(loc, inputLine)
-		token := ForToken{TokenData: tokData}
+		token := ForToken{TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
 	} else if loc := inExp.FindStringIndex(inputLine.line); loc != nil {
 		_, remaining := extractToken(loc, inputLine)
-		token := InToken{TokenData: tokData}
+		token := InToken{TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
 	} else if loc := endExp.FindStringIndex(inputLine.line); loc != nil {
+		tag, remaining := extractToken(loc, inputLine)
+		token := EndToken{TokenData: inputLine.tokenData(loc[1])}
+		receiver.state = getStateAfterTag(tag)
+		return token, remaining
+	} else if loc := extendsExp.FindStringSubmatchIndex(inputLine.line); loc != nil {
+		// the tag is only matched when a string follows so variables
+		// can be called extends or block, the string is lexed next
+		loc = loc[2:4]
 		_, remaining := extractToken(loc, inputLine)
-		token := EndToken{TokenData: tokData}
-		receiver.state = passthrough
+		token := ExtendsToken{TokenData: inputLine.tokenData(loc[1])}
+		return token, remaining
+	} else if loc := namedBlockExp.FindStringSubmatchIndex(inputLine.line); loc != nil {
+		loc = loc[2:4]
+		_, remaining := extractToken(loc, inputLine)
+		token := NamedBlockToken{TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
 	} else if loc := identExp.FindStringIndex(inputLine.line); loc != nil {
 		// Ident should come after more specific tokens like bool and var
 		ident, remaining := extractToken(loc, inputLine)
-		token := IdentToken{Identifier: ident, TokenData: tokData}
+		token := IdentToken{Identifier: ident, TokenData: inputLine.tokenData(loc[1])}
 		return token, remaining
 	} else if loc := symbolExp.FindStringIndex(inputLine.line); loc != nil {
 		symbol, remaining := extractToken(loc, inputLine)
-		token := SymbolToken{Symbol: symbol, TokenData: tokData}
+		token := SymbolToken{Symbol: symbol, TokenData: inputLine.tokenData(loc[1])}
+		return token, remaining
+	} else if loc := trimLeftBlockExp.FindStringIndex(inputLine.line); loc != nil { // should come after the tags it could start
+		block, remaining := extractToken(loc, inputLine)
+		token := BlockToken{Block: "{{", TokenData: inputLine.tokenData(loc[1])}
+		if strings.HasSuffix(block, ":") {
+			token.Block = "{{:"
+		}
 		return token, remaining
 	} else if loc := blockExp.FindStringIndex(inputLine.line); loc != nil {
 		block, remaining := extractToken(loc, inputLine)
-		token := BlockToken{Block: block, TokenData: tokData}
+		token := BlockToken{Block: block, TokenData: inputLine.tokenData(loc[1])}
 
 		if block == "}}" {
 			receiver.state = passthrough
+			receiver.braceDepth = 0
 		}
 		return token, remaining
 	} else {
 		// return char as passthrough
-		var (
-			value     = ""
-			remaining = ""
-		)
-
-		if len(inputLine.line) > 0 {
-			value = inputLine.line[:1]
-			remaining = inputLine.line[1:]
-		}
-		return PassthroughToken{Value: value, TokenData: tokData}, InputLine{line: remaining}
+		_, size := utf8.DecodeRuneInString(inputLine.line)
+		value, remaining := extractToken([]int{0, size}, inputLine)
+		return PassthroughToken{Value: value, TokenData: inputLine.tokenData(size)}, remaining
+	}
+}
+
+// getStateAfterTag returns the state to lex the text after a closed
+// tag like {{end}} or {{else -}} in
+func getStateAfterTag(tag string) LexerState {
+	if strings.HasSuffix(tag, "-}}") {
+		return passthroughNoWhitespace
 	}
+	return passthrough
 }
 
 // Extract the token between [loc[0],loc[1]) from the line
 // and return the remaining characters in the line
 func extractToken(loc []int, inputLine InputLine) (string, InputLine) {
 	token := inputLine.line[loc[0]:loc[1]]
-	inputLine.line = inputLine.line[loc[1]:]
-	return token, inputLine
+	return token, inputLine.advance(loc[1])
 }
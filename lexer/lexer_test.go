@@ -31,17 +31,41 @@ func TestGetLineTokensReturnsCorrectTokenType(t *testing.T) {
 		{"||", "LogicOpToken"},
 		{"&&", "LogicOpToken"},
 		{"123", "NumToken"},
+		{"4.5", "FloatToken"},
+		{"10.25", "FloatToken"},
+		{"7.", "NumToken"},
 		{`"foobar"`, "StrToken"},
+		{`'foobar'`, "StrToken"},
+		{`"foo \"bar\""`, "StrToken"},
 		{"{{for", "ForToken"},
 		{"{{if", "IfToken"},
 		{"{{else_if", "ElseIfToken"},
 		{"{{else}}", "ElseToken"},
 		{"{{end}}", "EndToken"},
+		{`{{extends "base.html"`, "ExtendsToken"},
+		{`{{block "main"`, "NamedBlockToken"},
+		{"{{block `main`", "NamedBlockToken"},
+		{"{{blockquote", "BlockToken"},
+		{"{{block = 1", "BlockToken"},
+		{"{{block}}", "BlockToken"},
+		{"{{extends = 1", "BlockToken"},
 		{"post", "IdentToken"},
 		{"true", "BoolToken"},
 		{"false", "BoolToken"},
+		{"include", "IdentToken"},
+		{"index", "IdentToken"},
+		{"trueish", "IdentToken"},
 		{"(", "SymbolToken"},
 		{")", "SymbolToken"},
+		{"[", "SymbolToken"},
+		{"]", "SymbolToken"},
+		{":", "SymbolToken"},
+		{"|", "SymbolToken"},
+		{"| upper", "SymbolToken"},
+		{"|| upper", "LogicOpToken"},
+		{"?", "SymbolToken"},
+		{"?? b", "SymbolToken"},
+		{`{"a"`, "SymbolToken"},
 		{"{{", "BlockToken"},
 	}
 
@@ -218,6 +242,18 @@ func TestLexReturnsCorrectTokenTypes(t *testing.T) {
 				"ElseToken", "PassthroughToken", "EndToken", "EOLToken",
 			},
 		},
+		{
+			"{{extends \"base.html\"}}\n{{block \"main\"}}<h1>test</h1>{{end}}", []string{
+				"ExtendsToken", "StrToken", "BlockToken", "PassthroughToken",
+				"NamedBlockToken", "StrToken", "BlockToken", "PassthroughToken", "EndToken", "EOLToken",
+			},
+		},
+		{
+			"{{block = 1}}{{: block}}{{: extends}}", []string{
+				"BlockToken", "IdentToken", "AssignOpToken", "NumToken", "BlockToken",
+				"BlockToken", "IdentToken", "BlockToken", "BlockToken", "IdentToken", "BlockToken", "EOLToken",
+			},
+		},
 		{
 			"{{for foo in bar}}<h1>test</h1>{{end}}", []string{
 				"ForToken", "IdentToken", "InToken", "IdentToken", "BlockToken",
@@ -366,3 +402,398 @@ func BenchmarkLexer(b *testing.B) {
 		}
 	}
 }
+
+func TestProcessLineSetsColumns(t *testing.T) {
+	type columns struct{ lineCol, byteCol, endCol int }
+
+	var tests = []struct {
+		line     string
+		expected []columns
+	}{
+		{"ü{{: x + 10}}", []columns{{1, 1, 2}, {2, 3, 5}, {6, 7, 7}, {8, 9, 9}, {10, 11, 12}, {12, 13, 14}}},
+		{"{{ `raw` }}é", []columns{{1, 1, 3}, {4, 4, 9}, {10, 10, 12}, {12, 12, 13}}},
+		{"  {{-}}  b", []columns{{1, 1, 3}, {3, 3, 5}, {5, 5, 8}, {10, 10, 11}}},
+		{"é{{raw}}ab{{endraw}}c", []columns{{1, 1, 2}, {9, 10, 11}, {21, 22, 22}}},
+		{"a{{# c #}}b", []columns{{1, 1, 2}, {11, 11, 12}}},
+	}
+
+	for i, test := range tests {
+		lexer := Lexer{}
+		tokens := lexer.processLine(InputLine{line: test.line, lineNum: 1})
+
+		if len(tokens) != len(test.expected) {
+			t.Errorf("%d: expected %d tokens, got %d", i, len(test.expected), len(tokens))
+			continue
+		}
+
+		for j, token := range tokens {
+			actual := columns{token.GetLineCol(), token.GetByteCol(), token.GetEndCol()}
+			if actual != test.expected[j] {
+				t.Errorf("%d: expected token %d %q at %v, got %v", i, j, token.GetValue(), test.expected[j], actual)
+			}
+		}
+	}
+}
+
+func TestMapLiteralBracesAreSymbols(t *testing.T) {
+	var tests = []struct {
+		line     string
+		expected []string
+	}{
+		{`{{: {}}}`, []string{"{{:", "{", "}", "}}"}},
+		{`{{m = {"a": 1}}}`, []string{"{{", "m", "=", "{", "a", ":", "1", "}", "}}"}},
+		{`{{: {"a": {"b": [1]}}}}x`, []string{"{{:", "{", "a", ":", "{", "b", ":", "[", "1", "]", "}", "}", "}}", "x"}},
+		{`{{{"a": 1}}}`, []string{"{{", "{", "a", ":", "1", "}", "}}"}},
+	}
+
+	for i, test := range tests {
+		lexer := Lexer{}
+		toks := lexer.processLine(InputLine{line: test.line, lineNum: 1})
+
+		actual := make([]string, 0, len(toks))
+		for _, tok := range toks {
+			actual = append(actual, tok.GetValue())
+		}
+
+		if strings.Join(actual, " ") != strings.Join(test.expected, " ") {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestCommentsAreDropped(t *testing.T) {
+	var tests = []struct {
+		lines    string
+		expected []string
+	}{
+		{"a{{# note #}}b", []string{"a", "b"}},
+		{"{{# note #}}", []string{}},
+		{"a {{# one\ntwo\nthree #}} b", []string{"a ", " b"}},
+		{"{{# {{: x}} {{for #}}{{: y}}", []string{"{{:", "y", "}}"}},
+		{"{{#}} #}}c", []string{"c"}},
+	}
+
+	for i, test := range tests {
+		lexer := Lexer{}
+		tokChan, errChan := lexer.Lex(strings.NewReader(test.lines), context.Background())
+
+		actual := []string{}
+		for tokens := range tokChan {
+			for _, tok := range tokens {
+				if _, ok := tok.(EOLToken); !ok {
+					actual = append(actual, tok.GetValue())
+				}
+			}
+		}
+
+		if err := <-errChan; err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if strings.Join(actual, "|") != strings.Join(test.expected, "|") {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestRawBlocksAreOnePassthroughToken(t *testing.T) {
+	var tests = []struct {
+		lines    string
+		expected []string
+	}{
+		{"{{raw}}{{: x}}{{endraw}}", []string{"{{: x}}"}},
+		{"a{{raw}}{{#each posts}}\n  {{title}}\n{{/each}}{{endraw}}b", []string{"a", "{{#each posts}}\n  {{title}}\n{{/each}}", "b"}},
+		{"{{raw}}{{endraw}}", []string{}},
+		{"{{raw}}{{# not a comment #}}{{endraw}}{{: 1}}", []string{"{{# not a comment #}}", "{{:", "1", "}}"}},
+		{"{{raw}}`{{endraw}}`", []string{"`", "`"}},
+	}
+
+	for i, test := range tests {
+		lexer := Lexer{}
+		tokChan, errChan := lexer.Lex(strings.NewReader(test.lines), context.Background())
+
+		actual := []string{}
+		for tokens := range tokChan {
+			for _, tok := range tokens {
+				if _, ok := tok.(EOLToken); !ok {
+					actual = append(actual, tok.GetValue())
+				}
+			}
+		}
+
+		if err := <-errChan; err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if strings.Join(actual, "|") != strings.Join(test.expected, "|") {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestUnclosedCommentsAndRawBlocksReturnErrors(t *testing.T) {
+	var tests = []struct {
+		lines    string
+		expected string
+	}{
+		{"a\n  {{# never closed\nb", `2:3: error: comment is never closed, expected "#}}"`},
+		{"{{raw}}{{: x}}", `1:1: error: raw block is never closed, expected "{{endraw}}"`},
+	}
+
+	for i, test := range tests {
+		lexer := Lexer{}
+		tokChan, errChan := lexer.Lex(strings.NewReader(test.lines), context.Background())
+
+		for range tokChan {
+		}
+
+		if err := <-errChan; err == nil {
+			t.Errorf("%d: expected an error", i)
+		} else if err.Error() != test.expected {
+			t.Errorf("%d: expected error %q, got %q", i, test.expected, err)
+		}
+	}
+}
+
+// lexText lexes text and returns the tokens as they would render, with
+// the values of tags in angle brackets
+func lexText(lexer Lexer, text string) string {
+	tokChan, _ := lexer.Lex(strings.NewReader(text), context.Background())
+
+	output := ""
+	for tokens := range tokChan {
+		for _, tok := range tokens {
+			if passthroughTok, ok := tok.(PassthroughToken); ok {
+				output += passthroughTok.Value
+			} else if _, ok := tok.(EOLToken); !ok {
+				output += "<" + tok.GetValue() + ">"
+			}
+		}
+	}
+
+	return output
+}
+
+func TestTrimMarkersTrimWhitespaceUpToTheTag(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{"a b  {{- x -}}  c d", "a b<{{><x><}}>c d"},
+		{"a  {{-: x}}  b", "a<{{:><x><}}>  b"},
+		{"a  {{: x -}}  b", "a  <{{:><x><}}>b"},
+		{"a\n\n  {{- if x -}}\n\n  b", "a<if><x><}}>b"},
+		{"a {{-if x}}b{{- else_if y -}} c {{-else-}} d {{- end -}} e", "a<if><x><}}>b<else_if><y><}}>c<else>d<end>e"},
+		{"a {{- for x in y}} b {{end -}}\n  c", "a<for><x><in><y><}}> b <end>c"},
+		{"a {{-1}}", "a <{{><-><1><}}>"},
+		{"a {{: 1 - 1}} b", "a <{{:><1><-><1><}}> b"},
+		{"{{if x -}}\n{{- end}}", "<if><x><}}><end>"},
+	}
+
+	for i, test := range tests {
+		if actual := lexText(Lexer{}, test.text); actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestTrimBlockLinesDropsLinesWithOnlyTags(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{"<ul>\n  {{for x in y}}\n    <li>{{: x}}</li>\n  {{end}}\n</ul>\n", "<ul>\n<for><x><in><y><}}>    <li><{{:><x><}}></li>\n<end></ul>\n"},
+		{"a\n  {{x = 1}}  \nb", "a\n<{{><x><=><1><}}>b"},
+		{"a\n{{# one\n  two #}}\nb", "a\nb"},
+		{"{{: x}}\n", "<{{:><x><}}>\n"},
+		{"{{if x}} text\n", "<if><x><}}> text\n"},
+		{"{{raw}}\n{{: x}}\n{{endraw}}\n", "\n{{: x}}\n\n"},
+	}
+
+	for i, test := range tests {
+		if actual := lexText(Lexer{TrimBlockLines: true}, test.text); actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+// lexStrings lexes text and returns the values of its string tokens and
+// the lexer's error
+func lexStrings(text string) ([]string, error) {
+	lexer := Lexer{}
+	tokChan, errChan := lexer.Lex(strings.NewReader(text), context.Background())
+
+	strs := []string{}
+	for tokens := range tokChan {
+		for _, tok := range tokens {
+			if strTok, ok := tok.(StrToken); ok {
+				strs = append(strs, strTok.Str)
+			}
+		}
+	}
+
+	return strs, <-errChan
+}
+
+func TestQuotedStringsReplaceEscapes(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{: "plain"}}`, "plain"},
+		{`{{: ""}}`, ""},
+		{`{{: ''}}`, ""},
+		{`{{: "say \"hi\""}}`, `say "hi"`},
+		{`{{: 'it\'s'}}`, "it's"},
+		{`{{: "it's"}}`, "it's"},
+		{`{{: 'say "hi"'}}`, `say "hi"`},
+		{`{{: "a\\b"}}`, `a\b`},
+		{`{{: "\\"}}`, `\`},
+		{`{{: "line\nbreak\ttab\rreturn"}}`, "line\nbreak\ttab\rreturn"},
+		{`{{: "\u{41}\u{e9}\u{1F600}"}}`, "Aé😀"},
+		{`{{: "}}"}}`, "}}"},
+		{`{{: "{{: x}}"}}`, "{{: x}}"},
+		{`{{: "ü\"ü"}}`, `ü"ü`},
+		{`{{: "^\d+\.\w$"}}`, `^\d+\.\w$`},
+		{`{{: "é\é"}}`, `é\é`},
+		{`{{: "\q\""}}`, `\q"`},
+	}
+
+	for i, test := range tests {
+		strs, err := lexStrings(test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if len(strs) != 1 || strs[0] != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, strs)
+		}
+	}
+}
+
+func TestInvalidStringsReturnErrors(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{: "\u41"}}`, `1:6: error: expected \u{...} with a hex code point`},
+		{`{{: "\u{110000}"}}`, `1:6: error: invalid code point in escape sequence "\\u{110000}"`},
+		{`{{: "\u{zz}"}}`, `1:6: error: invalid code point in escape sequence "\\u{zz}"`},
+		{`{{: "open}}`, `1:5: error: string is never closed, expected "\"" before the end of the line`},
+		{"a\n{{: 'open\n'}}", `2:5: error: string is never closed, expected "'" before the end of the line`},
+		{"{{: `open}}\nmore", "1:5: error: raw string is never closed, expected \"`\""},
+		{`{{: "\u41" + "\u{zz}"}}`, `1:6: error: expected \u{...} with a hex code point`},
+	}
+
+	for i, test := range tests {
+		if _, err := lexStrings(test.text); err == nil {
+			t.Errorf("%d: expected an error", i)
+		} else if err.Error() != test.expected {
+			t.Errorf("%d: expected error %q, got %q", i, test.expected, err)
+		}
+	}
+}
+
+func TestRawStringsSpanLines(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+		lineNum  int
+		lineCol  int
+	}{
+		{"{{: `one line`}}", "one line", 1, 5},
+		{"{{: `first\nsecond`}}", "first\nsecond", 1, 5},
+		{"a\n  {{: `first\n\n`}}", "first\n\n", 2, 7},
+		{"{{: `\n`}}\nthird", "\n", 1, 5},
+		{"{{: `\\n \"quoted\" 'x'`}}", `\n "quoted" 'x'`, 1, 5},
+		{"{{: `{{: x}}\n{{end}}`}}", "{{: x}}\n{{end}}", 1, 5},
+	}
+
+	for i, test := range tests {
+		lexer := Lexer{}
+		tokChan, errChan := lexer.Lex(strings.NewReader(test.text), context.Background())
+
+		var strTok *StrToken
+		for tokens := range tokChan {
+			for _, tok := range tokens {
+				if typedTok, ok := tok.(StrToken); ok && strTok == nil {
+					strTok = &typedTok
+				}
+			}
+		}
+
+		if err := <-errChan; err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if strTok == nil {
+			t.Errorf("%d: expected a string token", i)
+		} else if strTok.Str != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, strTok.Str)
+		} else if strTok.LineNum != test.lineNum || strTok.LineCol != test.lineCol {
+			t.Errorf("%d: expected string at %d:%d, got %d:%d",
+				i, test.lineNum, test.lineCol, strTok.LineNum, strTok.LineCol)
+		}
+	}
+}
+
+// quoteString writes str as a double quoted template string
+func quoteString(str string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(str) + `"`
+}
+
+func FuzzQuotedStrings(f *testing.F) {
+	for _, seed := range []string{"", "plain", `say "hi"`, `a\b`, "line\nbreak", "tab\t", "}}", "{{: x}}", "é😀", "'", "\r\n"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, str string) {
+		text := "{{: " + quoteString(str) + "}}"
+		strs, err := lexStrings(text)
+
+		if err != nil {
+			t.Fatalf("expected no error lexing %q, got %q", text, err)
+		} else if len(strs) != 1 || strs[0] != str {
+			t.Fatalf("expected %q from %q, got %q", str, text, strs)
+		}
+	})
+}
+
+func FuzzRawStrings(f *testing.F) {
+	for _, seed := range []string{"", "plain", "first\nsecond", "\n\n", `"quoted" 'x' \n`, "{{: x}}\n{{end}}"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, str string) {
+		// lines are split on \r\n as well as \n so a \r can't be round tripped
+		if strings.ContainsAny(str, "`\r") {
+			t.Skip()
+		}
+
+		text := "{{: `" + str + "`}}"
+		strs, err := lexStrings(text)
+
+		if err != nil {
+			t.Fatalf("expected no error lexing %q, got %q", text, err)
+		} else if len(strs) != 1 || strs[0] != str {
+			t.Fatalf("expected %q from %q, got %q", str, text, strs)
+		}
+	})
+}
+
+func FuzzLex(f *testing.F) {
+	for _, seed := range []string{
+		"<p>{{: title}}</p>",
+		"{{for x in [1, 2] -}}\n  {{- x}}\n{{end}}",
+		`{{: "a\"b" + 'c\'d' + "\u{41}"}}`,
+		"{{: `a\nb`}}",
+		"{{# comment #}}{{raw}}{{x}}{{endraw}}",
+		`{{m = {"a": [1, 2.5]}}}{{: m["a"][0] | round}}`,
+		`{{: "\u{`,
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		lexer := Lexer{TrimBlockLines: true}
+		tokChan, errChan := lexer.Lex(strings.NewReader(text), context.Background())
+
+		for range tokChan {
+		}
+		<-errChan
+	})
+}
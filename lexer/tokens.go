@@ -3,13 +3,21 @@ package lexer
 type Token interface {
 	GetLineNum() int
 	GetLineCol() int
+	GetByteCol() int
+	GetEndCol() int
 	GetValue() string
 	GetGrammarSymbol() string
 }
 
+// TokenData is the position of a token in its source
+// Lines and columns start at 1, LineCol and EndCol count runes while
+// ByteCol counts bytes
 type TokenData struct {
 	LineNum int
 	LineCol int
+	ByteCol int
+	// EndCol is the rune column just after the token
+	EndCol int
 }
 
 func (token TokenData) GetLineNum() int {
@@ -20,6 +28,14 @@ func (token TokenData) GetLineCol() int {
 	return token.LineCol
 }
 
+func (token TokenData) GetByteCol() int {
+	return token.ByteCol
+}
+
+func (token TokenData) GetEndCol() int {
+	return token.EndCol
+}
+
 // The actual value the token represents
 // 5, "foo", post.title, *, etc
 func (token TokenData) GetValue() string {
@@ -219,6 +235,33 @@ func (token EndToken) GetGrammarSymbol() string {
 	return "END"
 }
 
+// ExtendsToken opens a {{extends "layout.html"}} block
+type ExtendsToken struct {
+	TokenData
+}
+
+func (token ExtendsToken) GetValue() string {
+	return "extends"
+}
+
+func (token ExtendsToken) GetGrammarSymbol() string {
+	return "{{extends"
+}
+
+// NamedBlockToken opens a {{block "name"}} region that can be
+// overridden by templates extending the current one
+type NamedBlockToken struct {
+	TokenData
+}
+
+func (token NamedBlockToken) GetValue() string {
+	return "block"
+}
+
+func (token NamedBlockToken) GetGrammarSymbol() string {
+	return "{{block"
+}
+
 // Represents a string in double quotes like
 // "foo", "0129", "bl()#$)(!@"
 type StrToken struct {
@@ -248,6 +291,20 @@ func (token NumToken) GetGrammarSymbol() string {
 	return "NUM"
 }
 
+// Represents a floating point number
+type FloatToken struct {
+	Num string
+	TokenData
+}
+
+func (token FloatToken) GetValue() string {
+	return token.Num
+}
+
+func (token FloatToken) GetGrammarSymbol() string {
+	return "FLOAT"
+}
+
 // Represents a true/false value
 type BoolToken struct {
 	Value string
@@ -0,0 +1,226 @@
+// Package lr1 builds canonical LR(1) parse tables from a list of
+// grammar productions
+package lr1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// Epsilon marks an empty production right side
+	Epsilon = "ε"
+	// EndMarker is the terminal that represents the end of input
+	EndMarker = "$"
+)
+
+// Production is a single grammar rule Left -> Right
+type Production struct {
+	Left  string
+	Right []string
+}
+
+func (receiver Production) String() string {
+	right := Epsilon
+	if len(receiver.Right) > 0 {
+		right = strings.Join(receiver.Right, " ")
+	}
+
+	return fmt.Sprintf("%s -> %s", receiver.Left, right)
+}
+
+// Grammar holds the productions of a context free grammar along
+// with its terminal and non-terminal symbols
+// The left side of the first production is the augmented start symbol
+type Grammar struct {
+	Productions  []Production
+	Terminals    []string
+	NonTerminals []string
+
+	// index of each symbol, terminals first, then non-terminals
+	symbolIds map[string]int
+	symbols   []string
+	// productions for each non-terminal id
+	productionsFor map[int][]int
+	// first sets and nullability by symbol id
+	first    []map[int]bool
+	nullable []bool
+}
+
+// NewGrammar parses productions in the form "A -> b C d" into
+// a Grammar
+// A right side of "ε" represents an empty production
+func NewGrammar(productions []string) (*Grammar, error) {
+	if len(productions) == 0 {
+		return nil, fmt.Errorf("grammar has no productions")
+	}
+
+	grammar := &Grammar{Productions: make([]Production, 0, len(productions))}
+	isNonTerminal := map[string]bool{}
+
+	for i, rule := range productions {
+		pieces := strings.Split(rule, " -> ")
+		if len(pieces) != 2 || strings.TrimSpace(pieces[0]) == "" {
+			return nil, fmt.Errorf("production %d %q is not in the form A -> b", i, rule)
+		}
+
+		left := strings.TrimSpace(pieces[0])
+		right := strings.Fields(pieces[1])
+
+		if len(right) == 1 && right[0] == Epsilon {
+			right = []string{}
+		} else if len(right) == 0 {
+			return nil, fmt.Errorf("production %d %q has an empty right side, use %s", i, rule, Epsilon)
+		}
+
+		grammar.Productions = append(grammar.Productions, Production{Left: left, Right: right})
+		isNonTerminal[left] = true
+	}
+
+	isTerminal := map[string]bool{}
+	for _, production := range grammar.Productions {
+		for _, symbol := range production.Right {
+			if symbol == EndMarker {
+				return nil, fmt.Errorf("production %q uses the reserved end marker %s", production, EndMarker)
+			}
+
+			if !isNonTerminal[symbol] {
+				isTerminal[symbol] = true
+			}
+		}
+	}
+
+	start := grammar.Productions[0].Left
+	for _, production := range grammar.Productions[1:] {
+		if production.Left == start {
+			return nil, fmt.Errorf("start symbol %s must only appear in the first production", start)
+		}
+	}
+
+	for terminal := range isTerminal {
+		grammar.Terminals = append(grammar.Terminals, terminal)
+	}
+	sort.Strings(grammar.Terminals)
+	grammar.Terminals = append(grammar.Terminals, EndMarker)
+
+	for nonTerminal := range isNonTerminal {
+		if nonTerminal != start {
+			grammar.NonTerminals = append(grammar.NonTerminals, nonTerminal)
+		}
+	}
+	sort.Strings(grammar.NonTerminals)
+
+	grammar.indexSymbols()
+	grammar.computeFirstSets()
+
+	return grammar, nil
+}
+
+// Start returns the augmented start symbol
+func (receiver *Grammar) Start() string {
+	return receiver.Productions[0].Left
+}
+
+// Symbols returns the terminals followed by the non-terminals in
+// the same order as the columns of a generated table
+func (receiver *Grammar) Symbols() []string {
+	return receiver.symbols
+}
+
+func (receiver *Grammar) indexSymbols() {
+	receiver.symbols = append([]string{}, receiver.Terminals...)
+	receiver.symbols = append(receiver.symbols, receiver.NonTerminals...)
+	receiver.symbols = append(receiver.symbols, receiver.Start())
+
+	receiver.symbolIds = make(map[string]int, len(receiver.symbols))
+	for i, symbol := range receiver.symbols {
+		receiver.symbolIds[symbol] = i
+	}
+	// the start symbol has an id but is not a table column
+	receiver.symbols = receiver.symbols[:len(receiver.symbols)-1]
+
+	receiver.productionsFor = map[int][]int{}
+	for i, production := range receiver.Productions {
+		left := receiver.symbolIds[production.Left]
+		receiver.productionsFor[left] = append(receiver.productionsFor[left], i)
+	}
+}
+
+func (receiver *Grammar) isTerminalId(id int) bool {
+	return id < len(receiver.Terminals)
+}
+
+// computeFirstSets iterates until the FIRST set and nullability
+// of every symbol stops changing
+func (receiver *Grammar) computeFirstSets() {
+	numSymbols := len(receiver.symbolIds)
+	receiver.first = make([]map[int]bool, numSymbols)
+	receiver.nullable = make([]bool, numSymbols)
+
+	for id := 0; id < numSymbols; id++ {
+		receiver.first[id] = map[int]bool{}
+		if receiver.isTerminalId(id) {
+			receiver.first[id][id] = true
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+
+		for _, production := range receiver.Productions {
+			left := receiver.symbolIds[production.Left]
+			allNullable := true
+
+			for _, symbol := range production.Right {
+				id := receiver.symbolIds[symbol]
+				for terminal := range receiver.first[id] {
+					if !receiver.first[left][terminal] {
+						receiver.first[left][terminal] = true
+						changed = true
+					}
+				}
+
+				if !receiver.nullable[id] {
+					allNullable = false
+					break
+				}
+			}
+
+			if allNullable && !receiver.nullable[left] {
+				receiver.nullable[left] = true
+				changed = true
+			}
+		}
+	}
+}
+
+// firstOfSequence returns the FIRST set of the symbol ids in sequence
+// followed by lookahead
+func (receiver *Grammar) firstOfSequence(sequence []int, lookahead int) []int {
+	firsts := map[int]bool{}
+	allNullable := true
+
+	for _, id := range sequence {
+		for terminal := range receiver.first[id] {
+			firsts[terminal] = true
+		}
+
+		if !receiver.nullable[id] {
+			allNullable = false
+			break
+		}
+	}
+
+	if allNullable {
+		firsts[lookahead] = true
+	}
+
+	ret := make([]int, 0, len(firsts))
+	for terminal := range firsts {
+		ret = append(ret, terminal)
+	}
+	sort.Ints(ret)
+
+	return ret
+}
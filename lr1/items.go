@@ -0,0 +1,133 @@
+package lr1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// item is an LR(1) item [A -> α . β, a] where prod is the index of
+// A -> αβ, dot is the position of the dot in the right side and
+// lookahead is the id of the terminal a
+type item struct {
+	prod      int
+	dot       int
+	lookahead int
+}
+
+func itemLess(a, b item) bool {
+	if a.prod != b.prod {
+		return a.prod < b.prod
+	}
+	if a.dot != b.dot {
+		return a.dot < b.dot
+	}
+	return a.lookahead < b.lookahead
+}
+
+// itemSet is a sorted, duplicate free set of items
+type itemSet []item
+
+func (receiver itemSet) key() string {
+	var builder strings.Builder
+	for _, it := range receiver {
+		fmt.Fprintf(&builder, "%d.%d.%d;", it.prod, it.dot, it.lookahead)
+	}
+	return builder.String()
+}
+
+// itemBuilder computes closures and gotos over the productions of
+// a grammar
+type itemBuilder struct {
+	grammar *Grammar
+	// right side symbol ids for each production
+	rights [][]int
+}
+
+func newItemBuilder(grammar *Grammar) *itemBuilder {
+	rights := make([][]int, len(grammar.Productions))
+	for i, production := range grammar.Productions {
+		rights[i] = make([]int, len(production.Right))
+		for j, symbol := range production.Right {
+			rights[i][j] = grammar.symbolIds[symbol]
+		}
+	}
+
+	return &itemBuilder{grammar: grammar, rights: rights}
+}
+
+// nextSymbol returns the id of the symbol after the dot or false
+// if the item is complete
+func (receiver *itemBuilder) nextSymbol(it item) (int, bool) {
+	right := receiver.rights[it.prod]
+	if it.dot < len(right) {
+		return right[it.dot], true
+	}
+	return 0, false
+}
+
+// closure adds [B -> .γ, b] for every item [A -> α . B β, a] in kernel
+// and every b in FIRST(βa) until no new items are found
+func (receiver *itemBuilder) closure(kernel []item) itemSet {
+	seen := make(map[item]bool, len(kernel))
+	work := make([]item, 0, len(kernel))
+
+	for _, it := range kernel {
+		if !seen[it] {
+			seen[it] = true
+			work = append(work, it)
+		}
+	}
+
+	for i := 0; i < len(work); i++ {
+		it := work[i]
+		symbol, ok := receiver.nextSymbol(it)
+		if !ok || receiver.grammar.isTerminalId(symbol) {
+			continue
+		}
+
+		rest := receiver.rights[it.prod][it.dot+1:]
+		lookaheads := receiver.grammar.firstOfSequence(rest, it.lookahead)
+
+		for _, prod := range receiver.grammar.productionsFor[symbol] {
+			for _, lookahead := range lookaheads {
+				next := item{prod: prod, lookahead: lookahead}
+				if !seen[next] {
+					seen[next] = true
+					work = append(work, next)
+				}
+			}
+		}
+	}
+
+	sort.Slice(work, func(a, b int) bool { return itemLess(work[a], work[b]) })
+	return itemSet(work)
+}
+
+// transitions groups the items of set by the symbol after their dot
+// and returns the advanced kernel for each symbol
+func (receiver *itemBuilder) transitions(set itemSet) map[int][]item {
+	kernels := map[int][]item{}
+	for _, it := range set {
+		if symbol, ok := receiver.nextSymbol(it); ok {
+			advanced := item{prod: it.prod, dot: it.dot + 1, lookahead: it.lookahead}
+			kernels[symbol] = append(kernels[symbol], advanced)
+		}
+	}
+	return kernels
+}
+
+// describe formats an item like [A -> b . C, $]
+func (receiver *itemBuilder) describe(it item) string {
+	production := receiver.grammar.Productions[it.prod]
+	right := make([]string, 0, len(production.Right)+1)
+	right = append(right, production.Right[:it.dot]...)
+	right = append(right, ".")
+	right = append(right, production.Right[it.dot:]...)
+
+	return fmt.Sprintf("[%s -> %s, %s]",
+		production.Left,
+		strings.Join(right, " "),
+		receiver.grammar.symbols[it.lookahead],
+	)
+}
@@ -0,0 +1,220 @@
+package lr1
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AcceptAction is the table action for accepting the input
+const AcceptAction = "acct"
+
+// Table is an LR(1) action and goto table
+// Each row is a parser state and each column is the grammar symbol
+// at the same index in Symbols
+// Action cells are sN to shift to state N, rN to reduce by production N
+// or acct to accept. Goto cells for non-terminals are the bare state number
+type Table struct {
+	Symbols []string
+	Rows    [][]string
+}
+
+// Conflict describes a table cell that has more than one action
+type Conflict struct {
+	State   int
+	Symbol  string
+	Actions []string
+	// Items are the LR(1) items in State that produced Actions
+	Items []string
+}
+
+// Kind returns shift/reduce or reduce/reduce
+func (receiver Conflict) Kind() string {
+	for _, action := range receiver.Actions {
+		if action[0] == 's' {
+			return "shift/reduce"
+		}
+	}
+	return "reduce/reduce"
+}
+
+func (receiver Conflict) String() string {
+	return fmt.Sprintf(
+		"%s conflict in state %d on %q between %s\n    %s",
+		receiver.Kind(),
+		receiver.State,
+		receiver.Symbol,
+		strings.Join(receiver.Actions, " and "),
+		strings.Join(receiver.Items, "\n    "),
+	)
+}
+
+// ConflictError is returned when a grammar is not LR(1)
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (receiver *ConflictError) Error() string {
+	lines := make([]string, 0, len(receiver.Conflicts)+1)
+	lines = append(lines, fmt.Sprintf("grammar has %d conflict(s)", len(receiver.Conflicts)))
+
+	for _, conflict := range receiver.Conflicts {
+		lines = append(lines, conflict.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// BuildTable generates the canonical LR(1) table for grammar
+// If any cell has more than one action a *ConflictError listing every
+// conflict is returned along with the table
+func BuildTable(grammar *Grammar) (*Table, error) {
+	builder := newItemBuilder(grammar)
+	startId := grammar.symbolIds[grammar.Start()]
+	endId := grammar.symbolIds[EndMarker]
+
+	startItems := []item{}
+	for _, prod := range grammar.productionsFor[startId] {
+		startItems = append(startItems, item{prod: prod, lookahead: endId})
+	}
+
+	states := []itemSet{builder.closure(startItems)}
+	stateIds := map[string]int{states[0].key(): 0}
+	gotos := []map[int]int{}
+
+	for i := 0; i < len(states); i++ {
+		kernels := builder.transitions(states[i])
+		symbols := make([]int, 0, len(kernels))
+		for symbol := range kernels {
+			symbols = append(symbols, symbol)
+		}
+		sort.Ints(symbols)
+
+		stateGotos := map[int]int{}
+		for _, symbol := range symbols {
+			next := builder.closure(kernels[symbol])
+			key := next.key()
+
+			nextId, exists := stateIds[key]
+			if !exists {
+				nextId = len(states)
+				stateIds[key] = nextId
+				states = append(states, next)
+			}
+
+			stateGotos[symbol] = nextId
+		}
+
+		gotos = append(gotos, stateGotos)
+	}
+
+	table := &Table{
+		Symbols: append([]string{}, grammar.Symbols()...),
+		Rows:    make([][]string, len(states)),
+	}
+
+	conflicts := []Conflict{}
+	for i, set := range states {
+		row := make([]string, len(table.Symbols))
+		// all actions found for each conflicting column
+		conflicting := map[int][]string{}
+
+		setAction := func(col int, action string) {
+			if row[col] == "" {
+				row[col] = action
+			} else if row[col] != action {
+				if _, ok := conflicting[col]; !ok {
+					conflicting[col] = []string{row[col]}
+				}
+				conflicting[col] = appendUnique(conflicting[col], action)
+			}
+		}
+
+		for symbol, next := range gotos[i] {
+			if grammar.isTerminalId(symbol) {
+				setAction(symbol, fmt.Sprintf("s%d", next))
+			} else {
+				row[symbol] = strconv.Itoa(next)
+			}
+		}
+
+		for _, it := range set {
+			if _, ok := builder.nextSymbol(it); ok {
+				continue
+			}
+
+			if grammar.Productions[it.prod].Left == grammar.Start() {
+				setAction(it.lookahead, AcceptAction)
+			} else {
+				setAction(it.lookahead, fmt.Sprintf("r%d", it.prod))
+			}
+		}
+
+		for _, col := range sortedKeys(conflicting) {
+			conflicts = append(conflicts, Conflict{
+				State:   i,
+				Symbol:  table.Symbols[col],
+				Actions: conflicting[col],
+				Items:   builder.conflictItems(set, col),
+			})
+		}
+
+		table.Rows[i] = row
+	}
+
+	if len(conflicts) > 0 {
+		return table, &ConflictError{Conflicts: conflicts}
+	}
+
+	return table, nil
+}
+
+// conflictItems returns the items of set that shift or reduce on symbol
+func (receiver *itemBuilder) conflictItems(set itemSet, symbol int) []string {
+	items := []string{}
+	for _, it := range set {
+		next, ok := receiver.nextSymbol(it)
+		if (ok && next == symbol) || (!ok && it.lookahead == symbol) {
+			items = append(items, receiver.describe(it))
+		}
+	}
+	return items
+}
+
+// Write outputs the table in the format read by the frizzy parser
+// The first line is the quoted, comma separated list of symbols and
+// each following line is a row with cells separated by ", "
+func (receiver *Table) Write(writer io.Writer) error {
+	header := "'" + strings.Join(receiver.Symbols, "','") + "'\n"
+	if _, err := io.WriteString(writer, header); err != nil {
+		return err
+	}
+
+	for _, row := range receiver.Rows {
+		if _, err := io.WriteString(writer, strings.Join(row, ", ")+"\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func appendUnique(actions []string, action string) []string {
+	for _, existing := range actions {
+		if existing == action {
+			return actions
+		}
+	}
+	return append(actions, action)
+}
+
+func sortedKeys(m map[int][]string) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,213 @@
+package lr1
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var exprGrammar = []string{
+	"S -> E",
+	"E -> E + T",
+	"E -> T",
+	"T -> T * F",
+	"T -> F",
+	"F -> ( E )",
+	"F -> id",
+}
+
+// parses symbols with table and returns the reductions in order
+func runTable(t *testing.T, grammar *Grammar, table *Table, symbols []string) ([]int, bool) {
+	cols := map[string]int{}
+	for i, symbol := range table.Symbols {
+		cols[symbol] = i
+	}
+
+	symbols = append(symbols, EndMarker)
+	stack := []int{0}
+	reductions := []int{}
+
+	for i := 0; i < len(symbols); {
+		action := table.Rows[stack[len(stack)-1]][cols[symbols[i]]]
+
+		switch {
+		case action == "":
+			return reductions, false
+		case action == AcceptAction:
+			return reductions, true
+		case action[0] == 's':
+			next, _ := strconv.Atoi(action[1:])
+			stack = append(stack, next)
+			i++
+		case action[0] == 'r':
+			prod, _ := strconv.Atoi(action[1:])
+			reductions = append(reductions, prod)
+			production := grammar.Productions[prod]
+			stack = stack[:len(stack)-len(production.Right)]
+			next, err := strconv.Atoi(table.Rows[stack[len(stack)-1]][cols[production.Left]])
+			if err != nil {
+				t.Fatalf("missing goto for %s", production.Left)
+			}
+			stack = append(stack, next)
+		}
+	}
+
+	return reductions, false
+}
+
+func TestBuildTableParsesValidInput(t *testing.T) {
+	grammar, err := NewGrammar(exprGrammar)
+	if err != nil {
+		t.Fatalf("expected no grammar errors, got %q", err)
+	}
+
+	table, err := BuildTable(grammar)
+	if err != nil {
+		t.Fatalf("expected no table errors, got %q", err)
+	}
+
+	var tests = []struct {
+		input      string
+		accepted   bool
+		reductions []int
+	}{
+		{"id", true, []int{6, 4, 2}},
+		{"id + id * id", true, []int{6, 4, 2, 6, 4, 6, 3, 1}},
+		{"( id + id ) * id", true, []int{6, 4, 2, 6, 4, 1, 5, 4, 6, 3, 2}},
+		{"id +", false, nil},
+		{"( id", false, nil},
+		{"", false, nil},
+	}
+
+	for i, test := range tests {
+		reductions, accepted := runTable(t, grammar, table, strings.Fields(test.input))
+
+		if accepted != test.accepted {
+			t.Errorf("%d: expected %q accepted to be %t, got %t", i, test.input, test.accepted, accepted)
+		} else if accepted && !intSlicesEqual(reductions, test.reductions) {
+			t.Errorf("%d: expected reductions %v, got %v", i, test.reductions, reductions)
+		}
+	}
+}
+
+func TestBuildTableReportsShiftReduceConflicts(t *testing.T) {
+	grammar, _ := NewGrammar([]string{
+		"S -> E",
+		"E -> E + E",
+		"E -> id",
+	})
+
+	_, err := BuildTable(grammar)
+	conflictErr, ok := err.(*ConflictError)
+
+	if !ok {
+		t.Fatalf("expected a *ConflictError, got %v", err)
+	}
+
+	conflict := conflictErr.Conflicts[0]
+	if conflict.Kind() != "shift/reduce" || conflict.Symbol != "+" {
+		t.Errorf("expected shift/reduce conflict on +, got %s on %q", conflict.Kind(), conflict.Symbol)
+	}
+
+	expectedItems := []string{"[E -> E + E ., +]", "[E -> E . + E, +]"}
+	for _, expected := range expectedItems {
+		if !strings.Contains(conflict.String(), expected) {
+			t.Errorf("expected conflict to list item %s, got\n%s", expected, conflict)
+		}
+	}
+}
+
+func TestBuildTableReportsReduceReduceConflicts(t *testing.T) {
+	grammar, _ := NewGrammar([]string{
+		"S -> X",
+		"X -> A",
+		"X -> B",
+		"A -> id",
+		"B -> id",
+	})
+
+	_, err := BuildTable(grammar)
+	conflictErr, ok := err.(*ConflictError)
+
+	if !ok {
+		t.Fatalf("expected a *ConflictError, got %v", err)
+	}
+
+	conflict := conflictErr.Conflicts[0]
+	if conflict.Kind() != "reduce/reduce" || conflict.Symbol != EndMarker {
+		t.Errorf("expected reduce/reduce conflict on $, got %s on %q", conflict.Kind(), conflict.Symbol)
+	}
+
+	if len(conflict.Actions) != 2 || conflict.Actions[0] != "r3" || conflict.Actions[1] != "r4" {
+		t.Errorf("expected actions [r3 r4], got %v", conflict.Actions)
+	}
+}
+
+func TestBuildTableHandlesEmptyProductions(t *testing.T) {
+	grammar, _ := NewGrammar([]string{
+		"S -> call",
+		"call -> id ( args )",
+		"args -> id",
+		"args -> ε",
+	})
+
+	table, err := BuildTable(grammar)
+	if err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	for _, input := range []string{"id ( )", "id ( id )"} {
+		if _, accepted := runTable(t, grammar, table, strings.Fields(input)); !accepted {
+			t.Errorf("expected %q to be accepted", input)
+		}
+	}
+}
+
+func TestNewGrammarRejectsInvalidProductions(t *testing.T) {
+	var tests = [][]string{
+		{},
+		{"S"},
+		{"S -> "},
+		{"S -> a $"},
+		{"S -> a", "S -> b"},
+	}
+
+	for i, test := range tests {
+		if _, err := NewGrammar(test); err == nil {
+			t.Errorf("%d: expected an error for %v", i, test)
+		}
+	}
+}
+
+func TestWriteOutputsHeaderAndRows(t *testing.T) {
+	table := &Table{
+		Symbols: []string{"id", "$", "E"},
+		Rows: [][]string{
+			{"s2", "", "1"},
+			{"", "acct", ""},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	table.Write(buf)
+
+	expected := "'id','$','E'\ns2, , 1\n, acct, \n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func intSlicesEqual(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"log"
 	"os"
 
 	"mettlach.codes/frizzy/config"
+	"mettlach.codes/frizzy/diagnostic"
 	"mettlach.codes/frizzy/file"
 	"mettlach.codes/frizzy/pipeline"
 )
@@ -18,7 +21,8 @@ func main() {
 
 	startDevServer := flag.Bool("d", false, "start a web server to serve files in output directory")
 	devServerPort := flag.Int("p", 8080, "the port the web server will listen on")
-	clearOutput := flag.Bool("c", false, "clear any existing output")
+	clearOutput := flag.Bool("c", false, "clear any existing output and build cache")
+	watch := flag.Bool("w", false, "rebuild when templates, content or pages change and reload pages served by -d")
 	flag.Parse()
 
 	configPath := os.Args[len(os.Args)-1]
@@ -33,40 +37,32 @@ func main() {
 				log.Print(err)
 				return
 			}
-		}
-
-		templatePathChan, _ := pipeline.WalkFiles(config.GetTemplatePath())
-		contentPathChan, _ := pipeline.WalkFiles(config.GetContentPath())
-		pagesPathChan, _ := pipeline.WalkFiles(config.GetPagesPath())
-		// have to process templates first, then content, then pages
-		log.Println("pipelining template files")
-		if err := pipeline.RunPipeline(templatePathChan, pipeline.TemplateCacheHandler); err != nil {
-			log.Println("exiting")
-			return
-		} else {
-			log.Println("finished template files")
-		}
 
-		log.Println("pipelining content files")
-		if err := pipeline.RunPipeline(contentPathChan, pipeline.FullPipelineHtmlRenderer); err != nil {
-			log.Println("exiting")
-			return
-		} else {
-			log.Println("finished content files")
+			log.Printf("removing %s\n", config.GetCachePath())
+			if err := clearOutputDirectory(config.GetCachePath()); err != nil {
+				log.Print(err)
+				return
+			}
 		}
 
-		log.Println("pipelining page files")
-		if err := pipeline.RunPipeline(pagesPathChan, pipeline.FullPipelineHtmlRenderer); err != nil {
+		buildErr := pipeline.Build(config, pipeline.FullPipelineHtmlRenderer)
+		if failed := reportDiagnostics(buildErr); failed && !*watch {
 			log.Println("exiting")
-			return
-		} else {
-			log.Println("finished page files")
+			os.Exit(1)
 		}
 
+		server := &file.DevServer{ServerRoot: config.OutputPath, Port: *devServerPort, LiveReload: *watch}
 		if *startDevServer {
 			log.Println("starting development server...")
-			server := file.DevServer{ServerRoot: config.OutputPath, Port: *devServerPort}
-			server.ListenAndServe()
+			if *watch {
+				go func() { log.Println(server.ListenAndServe()) }()
+			} else {
+				server.ListenAndServe()
+			}
+		}
+
+		if *watch {
+			watchAndRebuild(config, server)
 		}
 
 		log.Println("Done")
@@ -74,7 +70,45 @@ func main() {
 }
 
 func printUsage() {
-	log.Println("usage: frizzy [-c] /path/to/config.json")
+	log.Println("usage: frizzy [-c] [-d [-p port]] [-w] /path/to/config.json")
+}
+
+// watchAndRebuild rebuilds whatever is affected each time files in the
+// template, content or pages directories change and then reloads any
+// pages open in the dev server
+func watchAndRebuild(config *config.Config, server *file.DevServer) {
+	watcher := file.Watcher{
+		Paths: []string{config.GetTemplatePath(), config.GetContentPath(), config.GetPagesPath()},
+	}
+
+	log.Println("watching for changes...")
+	for changed := range watcher.Watch(context.Background()) {
+		log.Printf("%d file(s) changed, rebuilding\n", len(changed))
+		buildErr := pipeline.Rebuild(config, pipeline.FullPipelineHtmlRenderer, changed)
+		if failed := reportDiagnostics(buildErr); failed {
+			log.Println("rebuild failed")
+			continue
+		}
+
+		server.Reload()
+	}
+}
+
+// reportDiagnostics prints every problem in buildErr to stderr and
+// reports whether any of them were errors
+func reportDiagnostics(buildErr error) bool {
+	if buildErr == nil {
+		return false
+	}
+
+	var diagnostics diagnostic.Diagnostics
+	if !errors.As(buildErr, &diagnostics) {
+		log.Println(buildErr)
+		return true
+	}
+
+	diagnostics.Print(os.Stderr)
+	return diagnostics.HasErrors()
 }
 
 func clearOutputDirectory(outputDir string) error {
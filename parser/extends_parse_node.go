@@ -0,0 +1,23 @@
+package parser
+
+import "fmt"
+
+// ExtendsParseNode represents a {{extends "layout.html"}} block
+// The children are {"extends", "layout.html", "}}"}
+type ExtendsParseNode struct {
+	ParseNode
+}
+
+func (receiver *ExtendsParseNode) String() string {
+	return fmt.Sprintf("%T", *receiver)
+}
+
+func (node *ExtendsParseNode) IsTerminal() bool {
+	return false
+}
+
+// GetTemplatePath returns the path of the template being extended
+// relative to the template directory
+func (receiver *ExtendsParseNode) GetTemplatePath() string {
+	return receiver.children[1].(*StringParseNode).Value
+}
@@ -21,19 +21,38 @@ func (node *ForLoopParseNode) IsTerminal() bool {
 }
 
 // GetLoopIdent returns the loop identifier
-// Given {{for foo in bar}}, returns TreeNode{foo}
+// Given {{for foo in bar}} or {{for key, foo in bar}}, returns TreeNode{foo}
 func (receiver *ForLoopParseNode) GetLoopIdent() TreeNode {
 	// expects children to be {"for", "foo", "in", "bar", "}}", content}
-	// where "foo" is the loop ident
+	// where "foo" is the loop ident or {"key", ",", "foo"}
+	if idents, ok := receiver.children[1].(*NonTerminalParseNode); ok {
+		return idents.children[2]
+	}
 	return receiver.children[1]
 }
 
+// GetLoopKeyIdent returns the identifier bound to each key or false if
+// the loop only binds values
+// Given {{for key, foo in bar}}, returns TreeNode{key}
+func (receiver *ForLoopParseNode) GetLoopKeyIdent() (TreeNode, bool) {
+	if idents, ok := receiver.children[1].(*NonTerminalParseNode); ok {
+		return idents.children[0], true
+	}
+	return nil, false
+}
+
 // GetLoopInput returns the loop input
 // Given {{for foo in bar}}, returns TreeNode{bar}
 func (receiver *ForLoopParseNode) GetLoopInput() TreeNode {
 	// expects children to be {"for", "foo", "in", "bar", "}}", content}
 	// where "bar" is the loop input
-	return receiver.children[3]
+	input := receiver.children[3]
+
+	// ( expression ) keeps its parentheses
+	if parenthesised, ok := input.(*NonTerminalParseNode); ok && parenthesised.Value == "loop_input" {
+		return parenthesised.children[1]
+	}
+	return input
 }
 
 // GetLoopBody returns the ContentParseNode body of this loop
@@ -41,3 +60,15 @@ func (receiver *ForLoopParseNode) GetLoopInput() TreeNode {
 func (receiver *ForLoopParseNode) GetLoopBody() TreeNode {
 	return receiver.children[5]
 }
+
+// GetElseBody returns the body rendered when the loop input is empty
+// or false if the loop has no else
+// Given {{for foo in bar}} content {{else}} empty {{end}}, returns TreeNode{empty}
+func (receiver *ForLoopParseNode) GetElseBody() (TreeNode, bool) {
+	// expects children to be {"for", "foo", "in", "bar", "}}", content, "{{else}}", empty, "{{end}}"}
+	if len(receiver.children) < 9 {
+		return nil, false
+	}
+
+	return receiver.children[7], true
+}
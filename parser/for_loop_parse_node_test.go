@@ -0,0 +1,239 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"mettlach.codes/frizzy/lexer"
+)
+
+func TestForLoopReturnsElseBody(t *testing.T) {
+	var tests = []struct {
+		tokens   []lexer.Token
+		expected string
+	}{
+		{
+			[]lexer.Token{
+				lexer.ForToken{},
+				lexer.IdentToken{Identifier: "foo"},
+				lexer.InToken{},
+				lexer.StrToken{Str: "bar"},
+				lexer.BlockToken{Block: "}}"},
+				lexer.PassthroughToken{Value: "<p>foo</p>"},
+				lexer.EndToken{},
+				lexer.EOLToken{},
+			},
+			"",
+		},
+		{
+			[]lexer.Token{
+				lexer.ForToken{},
+				lexer.IdentToken{Identifier: "foo"},
+				lexer.InToken{},
+				lexer.StrToken{Str: "bar"},
+				lexer.BlockToken{Block: "}}"},
+				lexer.PassthroughToken{Value: "<p>foo</p>"},
+				lexer.ElseToken{},
+				lexer.PassthroughToken{Value: "<p>empty</p>"},
+				lexer.EndToken{},
+				lexer.EOLToken{},
+			},
+			"<p>empty</p>",
+		},
+		{
+			[]lexer.Token{
+				lexer.ForToken{},
+				lexer.IdentToken{Identifier: "foo"},
+				lexer.InToken{},
+				lexer.IdentToken{Identifier: "posts"},
+				lexer.BlockToken{Block: "}}"},
+				lexer.PassthroughToken{Value: "<p>foo</p>"},
+				lexer.ElseToken{},
+				lexer.PassthroughToken{Value: "<p>no posts</p>"},
+				lexer.EndToken{},
+				lexer.EOLToken{},
+			},
+			"<p>no posts</p>",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("for tokens %d", i), func(t *testing.T) {
+			stateStack := []int{}
+			nodeStack := []TreeNode{}
+
+			_, head, err := parseTokens(test.tokens, &stateStack, &nodeStack)
+			if err != nil {
+				t.Fatalf("expected no error, got %q", err)
+			}
+
+			forLoop := extractToken(head, []int{0}).(*ForLoopParseNode)
+			elseBody, ok := forLoop.GetElseBody()
+
+			if test.expected == "" {
+				if ok {
+					t.Errorf("expected no else body, got %s", elseBody)
+				}
+				return
+			}
+
+			if !ok {
+				t.Fatalf("expected an else body")
+			}
+
+			passthrough := extractToken(elseBody, []int{0}).(*StringParseNode)
+			if passthrough.Value != test.expected {
+				t.Errorf("expected else body %q, got %q", test.expected, passthrough.Value)
+			}
+		})
+	}
+}
+
+func TestForLoopReturnsKeyIdent(t *testing.T) {
+	var tests = []struct {
+		tokens        []lexer.Token
+		expectedKey   string
+		expectedValue string
+	}{
+		{
+			[]lexer.Token{
+				lexer.ForToken{},
+				lexer.IdentToken{Identifier: "foo"},
+				lexer.InToken{},
+				lexer.IdentToken{Identifier: "bar"},
+				lexer.BlockToken{Block: "}}"},
+				lexer.PassthroughToken{Value: "<p>foo</p>"},
+				lexer.EndToken{},
+				lexer.EOLToken{},
+			},
+			"",
+			"foo",
+		},
+		{
+			[]lexer.Token{
+				lexer.ForToken{},
+				lexer.IdentToken{Identifier: "key"},
+				lexer.SymbolToken{Symbol: ","},
+				lexer.IdentToken{Identifier: "foo"},
+				lexer.InToken{},
+				lexer.IdentToken{Identifier: "bar"},
+				lexer.BlockToken{Block: "}}"},
+				lexer.PassthroughToken{Value: "<p>foo</p>"},
+				lexer.EndToken{},
+				lexer.EOLToken{},
+			},
+			"key",
+			"foo",
+		},
+		{
+			[]lexer.Token{
+				lexer.ForToken{},
+				lexer.IdentToken{Identifier: "path"},
+				lexer.SymbolToken{Symbol: ","},
+				lexer.IdentToken{Identifier: "post"},
+				lexer.InToken{},
+				lexer.StrToken{Str: "posts"},
+				lexer.BlockToken{Block: "}}"},
+				lexer.PassthroughToken{Value: "<p>foo</p>"},
+				lexer.ElseToken{},
+				lexer.PassthroughToken{Value: "<p>no posts</p>"},
+				lexer.EndToken{},
+				lexer.EOLToken{},
+			},
+			"path",
+			"post",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("for tokens %d", i), func(t *testing.T) {
+			stateStack := []int{}
+			nodeStack := []TreeNode{}
+
+			_, head, err := parseTokens(test.tokens, &stateStack, &nodeStack)
+			if err != nil {
+				t.Fatalf("expected no error, got %q", err)
+			}
+
+			forLoop := extractToken(head, []int{0}).(*ForLoopParseNode)
+
+			if value := forLoop.GetLoopIdent().(*IdentParseNode); value.Value != test.expectedValue {
+				t.Errorf("expected value ident %q, got %q", test.expectedValue, value.Value)
+			}
+
+			key, ok := forLoop.GetLoopKeyIdent()
+			if test.expectedKey == "" {
+				if ok {
+					t.Errorf("expected no key ident, got %s", key)
+				}
+				return
+			}
+
+			if !ok {
+				t.Fatalf("expected a key ident")
+			}
+
+			if key.(*IdentParseNode).Value != test.expectedKey {
+				t.Errorf("expected key ident %q, got %q", test.expectedKey, key.(*IdentParseNode).Value)
+			}
+		})
+	}
+}
+
+func TestForLoopReturnsInput(t *testing.T) {
+	var tests = []struct {
+		tokens   []lexer.Token
+		expected string
+	}{
+		// {{for foo in "a b" | split}}
+		{
+			[]lexer.Token{
+				lexer.ForToken{},
+				lexer.IdentToken{Identifier: "foo"},
+				lexer.InToken{},
+				lexer.StrToken{Str: "a b"},
+				lexer.SymbolToken{Symbol: "|"},
+				lexer.IdentToken{Identifier: "split"},
+				lexer.BlockToken{Block: "}}"},
+				lexer.PassthroughToken{Value: "<p>foo</p>"},
+				lexer.EndToken{},
+				lexer.EOLToken{},
+			},
+			"parser.FuncCallParseNode",
+		},
+		// {{for foo in (bar)}}
+		{
+			[]lexer.Token{
+				lexer.ForToken{},
+				lexer.IdentToken{Identifier: "foo"},
+				lexer.InToken{},
+				lexer.SymbolToken{Symbol: "("},
+				lexer.IdentToken{Identifier: "bar"},
+				lexer.SymbolToken{Symbol: ")"},
+				lexer.BlockToken{Block: "}}"},
+				lexer.PassthroughToken{Value: "<p>foo</p>"},
+				lexer.EndToken{},
+				lexer.EOLToken{},
+			},
+			"parser.VarNameParseNode",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("for tokens %d", i), func(t *testing.T) {
+			stateStack := []int{}
+			nodeStack := []TreeNode{}
+
+			_, head, err := parseTokens(test.tokens, &stateStack, &nodeStack)
+			if err != nil {
+				t.Fatalf("expected no error, got %q", err)
+			}
+
+			forLoop := extractToken(head, []int{0}).(*ForLoopParseNode)
+
+			if input := forLoop.GetLoopInput().String(); input != test.expected {
+				t.Errorf("expected input %s, got %s", test.expected, input)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 )
 
 // FuncCallParseNode represents a function call in our grammar
@@ -18,17 +19,61 @@ func (node *FuncCallParseNode) IsTerminal() bool {
 }
 
 // GetFuncName returns the name of the function that
-// this node represents
+// this node represents, namespaced functions are dot separated
+// e.g. str.upper(title) returns "str.upper"
 func (receiver *FuncCallParseNode) GetFuncName() string {
-	nameNode := receiver.children[0].(*IdentParseNode)
-	return nameNode.Value
+	nameNode := receiver.children[0].(*VarNameParseNode)
+	return strings.Join(nameNode.GetVarNameParts(), ".")
+}
+
+// GetNameIndex returns the first index in the function name or false
+// if the name is only idents, functions can't be looked up by index
+// e.g. upper[0]("abc") returns TreeNode{0}
+func (receiver *FuncCallParseNode) GetNameIndex() (TreeNode, bool) {
+	nameNode := receiver.children[0].(*VarNameParseNode)
+	for _, key := range nameNode.GetKeys() {
+		if _, ok := key.(*IdentParseNode); !ok {
+			return key, true
+		}
+	}
+	return nil, false
 }
 
 // GetArgs returns a slice of nodes representing the arguments
 // to this function
 func (receiver *FuncCallParseNode) GetArguments() []TreeNode {
-	if argsList, ok := receiver.children[2].(*ArgsListParseNode); ok {
-		return argsList.GetArguments()
+	switch args := receiver.children[2].(type) {
+	case *ArgsListParseNode:
+		return args.GetArguments()
+	case *ArgsParseNode:
+		return args.GetArguments()
 	}
 	return []TreeNode{receiver.children[2]}
 }
+
+// newPipedFuncCall returns the call that value | filter stands for
+// Given title | truncate(20), returns the node for truncate(title, 20)
+func newPipedFuncCall(value, filter TreeNode) *FuncCallParseNode {
+	var name TreeNode
+	args := []TreeNode{value}
+
+	switch typedFilter := filter.(type) {
+	case *FuncCallParseNode:
+		name = typedFilter.children[0]
+		for _, arg := range typedFilter.GetArguments() {
+			args = append(args, &SymbolParseNode{Value: ","}, arg)
+		}
+	default:
+		// filter -> var_name is collapsed into the var name
+		name = filter
+	}
+
+	argList := &ArgsListParseNode{}
+	argList.SetChildren(args)
+
+	call := &FuncCallParseNode{}
+	call.SetChildren([]TreeNode{name, &SymbolParseNode{Value: "("}, argList, &SymbolParseNode{Value: ")"}})
+	call.SetSpan(Span{Start: value.GetSpan().Start, End: filter.GetSpan().End})
+
+	return call
+}
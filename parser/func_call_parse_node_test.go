@@ -72,3 +72,166 @@ func TestArgsReturnsCorrectNameParts(t *testing.T) {
 		})
 	}
 }
+
+func TestPipesDesugarToFuncCalls(t *testing.T) {
+	var tests = []struct {
+		toks     []lexer.Token
+		funcName string
+		args     int
+	}{
+		// {{: title | upper}}
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.IdentToken{Identifier: "title"},
+			lexer.SymbolToken{Symbol: "|"},
+			lexer.IdentToken{Identifier: "upper"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, "upper", 1},
+		// {{: title | truncate(20, "...")}}
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.IdentToken{Identifier: "title"},
+			lexer.SymbolToken{Symbol: "|"},
+			lexer.IdentToken{Identifier: "truncate"},
+			lexer.SymbolToken{Symbol: "("},
+			lexer.NumToken{Num: "20"},
+			lexer.SymbolToken{Symbol: ","},
+			lexer.StrToken{Str: "..."},
+			lexer.SymbolToken{Symbol: ")"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, "truncate", 3},
+		// {{: title | truncate(20) | upper}}
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.IdentToken{Identifier: "title"},
+			lexer.SymbolToken{Symbol: "|"},
+			lexer.IdentToken{Identifier: "truncate"},
+			lexer.SymbolToken{Symbol: "("},
+			lexer.NumToken{Num: "20"},
+			lexer.SymbolToken{Symbol: ")"},
+			lexer.SymbolToken{Symbol: "|"},
+			lexer.IdentToken{Identifier: "upper"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, "upper", 1},
+		// {{: posts() | first}}
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.IdentToken{Identifier: "posts"},
+			lexer.SymbolToken{Symbol: "("},
+			lexer.SymbolToken{Symbol: ")"},
+			lexer.SymbolToken{Symbol: "|"},
+			lexer.IdentToken{Identifier: "first"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, "first", 1},
+		// {{: title | str.upper}}
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.IdentToken{Identifier: "title"},
+			lexer.SymbolToken{Symbol: "|"},
+			lexer.IdentToken{Identifier: "str"},
+			lexer.SymbolToken{Symbol: "."},
+			lexer.IdentToken{Identifier: "upper"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, "str.upper", 1},
+		// {{: title | str.truncate(20)}}
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.IdentToken{Identifier: "title"},
+			lexer.SymbolToken{Symbol: "|"},
+			lexer.IdentToken{Identifier: "str"},
+			lexer.SymbolToken{Symbol: "."},
+			lexer.IdentToken{Identifier: "truncate"},
+			lexer.SymbolToken{Symbol: "("},
+			lexer.NumToken{Num: "20"},
+			lexer.SymbolToken{Symbol: ")"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, "str.truncate", 2},
+		// {{: site.str.upper(title)}}
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.IdentToken{Identifier: "site"},
+			lexer.SymbolToken{Symbol: "."},
+			lexer.IdentToken{Identifier: "str"},
+			lexer.SymbolToken{Symbol: "."},
+			lexer.IdentToken{Identifier: "upper"},
+			lexer.SymbolToken{Symbol: "("},
+			lexer.IdentToken{Identifier: "title"},
+			lexer.SymbolToken{Symbol: ")"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, "site.str.upper", 1},
+	}
+
+	for i, test := range tests {
+		stateStack := []int{}
+		nodeStack := []TreeNode{}
+
+		_, head, err := parseTokens(test.toks, &stateStack, &nodeStack)
+		if err != nil {
+			t.Fatalf("%d: expected no error, got %q", i, err)
+		}
+
+		funcCall, ok := extractToken(head, []int{0, 1}).(*FuncCallParseNode)
+		if !ok {
+			t.Fatalf("%d: expected a FuncCallParseNode, got %s", i, extractToken(head, []int{0, 1}))
+		}
+
+		if funcCall.GetFuncName() != test.funcName {
+			t.Errorf("%d: expected func %q, got %q", i, test.funcName, funcCall.GetFuncName())
+		}
+
+		if args := funcCall.GetArguments(); len(args) != test.args {
+			t.Errorf("%d: expected %d args, got %d", i, test.args, len(args))
+		}
+	}
+}
+
+func TestPipedValueIsTheFirstArgument(t *testing.T) {
+	// {{: title | truncate(20) | upper}} is upper(truncate(title, 20))
+	toks := []lexer.Token{
+		lexer.BlockToken{Block: "{{:"},
+		lexer.IdentToken{Identifier: "title"},
+		lexer.SymbolToken{Symbol: "|"},
+		lexer.IdentToken{Identifier: "truncate"},
+		lexer.SymbolToken{Symbol: "("},
+		lexer.NumToken{Num: "20"},
+		lexer.SymbolToken{Symbol: ")"},
+		lexer.SymbolToken{Symbol: "|"},
+		lexer.IdentToken{Identifier: "upper"},
+		lexer.BlockToken{Block: "}}"},
+		lexer.EOLToken{},
+	}
+
+	stateStack := []int{}
+	nodeStack := []TreeNode{}
+
+	_, head, err := parseTokens(toks, &stateStack, &nodeStack)
+	if err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	upper := extractToken(head, []int{0, 1}).(*FuncCallParseNode)
+	truncate, ok := upper.GetArguments()[0].(*FuncCallParseNode)
+	if !ok {
+		t.Fatalf("expected truncate to be piped into upper, got %s", upper.GetArguments()[0])
+	}
+
+	if truncate.GetFuncName() != "truncate" {
+		t.Errorf("expected func %q, got %q", "truncate", truncate.GetFuncName())
+	}
+
+	truncateArgs := truncate.GetArguments()
+	if title, ok := truncateArgs[0].(*VarNameParseNode); !ok || title.GetVarNameParts()[0] != "title" {
+		t.Errorf("expected title as the first argument, got %s", truncateArgs[0])
+	}
+
+	if num, ok := extractToken(truncateArgs[1], []int{0}).(*NumParseNode); !ok || num.Value != 20 {
+		t.Errorf("expected 20 as the second argument, got %s", truncateArgs[1])
+	}
+}
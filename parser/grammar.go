@@ -0,0 +1,147 @@
+package parser
+
+//go:generate go run mettlach.codes/frizzy/cmd/lr1gen -o lr1_table.txt
+
+// GrammarProductions is a list of the grammar productions
+// Rule numbers in the parse table are indices into this list so
+// lr1_table.txt must be regenerated with `go generate` after any change
+var GrammarProductions = []string{
+	"A -> program",
+	"program -> content",
+
+	"content -> content PASSTHROUGH",
+	"content -> PASSTHROUGH",
+	"content -> content blocks",
+	"content -> blocks",
+
+	"blocks -> block",
+	"blocks -> print_block",
+	"blocks -> if_statement_block",
+	"blocks -> for_block",
+	"blocks -> extends_block",
+	"blocks -> named_block",
+
+	"block -> {{ statement }}",
+	"block -> {{ statement -}",
+
+	"print_block -> {{: statement }}",
+	"print_block -> {{: statement -}",
+
+	"if_statement_block -> {{if expression }} content END",
+	"if_statement_block -> {{if expression }} content else_if_list END",
+	"if_statement_block -> {{if expression }} content {{else}} content END",
+	"if_statement_block -> {{if expression }} content else_if_list {{else}} content END",
+
+	"else_if_list -> else_if_list {{else_if expression }} content",
+	"else_if_list -> {{else_if expression }} content",
+
+	"for_block -> {{for ID in STRING }} content END",
+	"for_block -> {{for ID in STRING }} content {{else}} content END",
+	"for_block -> {{for ID in loop_input }} content END",
+	"for_block -> {{for ID in loop_input }} content {{else}} content END",
+	"for_block -> {{for loop_idents in STRING }} content END",
+	"for_block -> {{for loop_idents in STRING }} content {{else}} content END",
+	"for_block -> {{for loop_idents in loop_input }} content END",
+	"for_block -> {{for loop_idents in loop_input }} content {{else}} content END",
+
+	"loop_idents -> ID , ID",
+
+	"loop_input -> var_name",
+	"loop_input -> func_call",
+	"loop_input -> list_literal",
+	"loop_input -> map_literal",
+	"loop_input -> index_expression",
+	"loop_input -> loop_pipe",
+	"loop_input -> ( expression )",
+
+	"loop_pipe -> loop_pipe | filter",
+	"loop_pipe -> loop_value | filter",
+
+	"loop_value -> STRING",
+	"loop_value -> var_name",
+	"loop_value -> func_call",
+	"loop_value -> list_literal",
+	"loop_value -> map_literal",
+	"loop_value -> index_expression",
+
+	"extends_block -> {{extends STRING }}",
+
+	"named_block -> {{block STRING }} content END",
+	"named_block -> {{block STRING }} END",
+
+	"statement -> expression",
+
+	"var_name -> var_name . ID",
+	"var_name -> var_name [ expression ]",
+	"var_name -> ID",
+
+	"func_call -> var_name ( args )",
+
+	"args -> arg_list",
+	"args -> ε",
+
+	"arg_list -> arg_list , expression",
+	"arg_list -> expression",
+
+	"expression -> var_name = expression",
+	"expression -> pipe_expression",
+
+	"pipe_expression -> pipe_expression | filter",
+	"pipe_expression -> conditional_expression",
+
+	"filter -> var_name",
+	"filter -> func_call",
+
+	"conditional_expression -> coalesce_expression ? expression : conditional_expression",
+	"conditional_expression -> coalesce_expression",
+
+	"coalesce_expression -> coalesce_expression ?? logic_expression",
+	"coalesce_expression -> logic_expression",
+
+	"logic_expression -> logic_expression LOGIC_OP rel_expression",
+	"logic_expression -> rel_expression",
+
+	"rel_expression -> rel_expression REL_OP add_expression",
+	"rel_expression -> add_expression",
+
+	"add_expression -> add_expression + mult_expression",
+	"add_expression -> add_expression - mult_expression",
+	"add_expression -> mult_expression",
+
+	"mult_expression -> mult_expression MULT_OP unary_expression",
+	"mult_expression -> unary_expression",
+
+	"unary_expression -> ! unary_expression",
+	"unary_expression -> - unary_expression",
+	"unary_expression -> term_expression",
+
+	"term_expression -> STRING",
+	"term_expression -> NUM",
+	"term_expression -> FLOAT",
+	"term_expression -> BOOL",
+	"term_expression -> var_name",
+	"term_expression -> func_call",
+	"term_expression -> list_literal",
+	"term_expression -> map_literal",
+	"term_expression -> index_expression",
+	"term_expression -> ( expression )",
+
+	"index_expression -> index_expression [ expression ]",
+	"index_expression -> index_expression . ID",
+	"index_expression -> indexable [ expression ]",
+
+	"indexable -> func_call",
+	"indexable -> list_literal",
+	"indexable -> map_literal",
+	"indexable -> ( expression )",
+
+	"list_literal -> [ args ]",
+
+	"map_literal -> { map_entries }",
+	"map_literal -> { }",
+
+	"map_entries -> map_entries , map_entry",
+	"map_entries -> map_entry",
+
+	"map_entry -> STRING : expression",
+}
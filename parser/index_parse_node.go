@@ -0,0 +1,35 @@
+package parser
+
+import "fmt"
+
+// IndexParseNode represents indexing the result of an expression that
+// isn't a variable like split(tags, ",")[0] or {"a": {"b": 1}}["a"].b
+type IndexParseNode struct {
+	ParseNode
+}
+
+func (receiver *IndexParseNode) String() string {
+	return fmt.Sprintf("%T", *receiver)
+}
+
+func (node *IndexParseNode) IsTerminal() bool {
+	return false
+}
+
+// GetTarget returns the node of the expression being indexed
+func (receiver *IndexParseNode) GetTarget() TreeNode {
+	target := receiver.children[0]
+
+	// ( expression ) keeps its parentheses
+	if parenthesised, ok := target.(*NonTerminalParseNode); ok && parenthesised.Value == "indexable" {
+		return parenthesised.children[1]
+	}
+	return target
+}
+
+// GetKeys returns a node for each key after the target from left to
+// right, like VarNameParseNode.GetKeys
+// e.g. f()[1].name returns [1, name]
+func (receiver *IndexParseNode) GetKeys() []TreeNode {
+	return getKeys(receiver.children[1:])
+}
@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"testing"
+
+	"mettlach.codes/frizzy/lexer"
+)
+
+func TestIndexReturnsTargetAndKeys(t *testing.T) {
+	var tests = []struct {
+		toks   []lexer.Token
+		target string
+		keys   int
+	}{
+		// {{: [1][0]}}
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.SymbolToken{Symbol: "["},
+			lexer.NumToken{Num: "1"},
+			lexer.SymbolToken{Symbol: "]"},
+			lexer.SymbolToken{Symbol: "["},
+			lexer.NumToken{Num: "0"},
+			lexer.SymbolToken{Symbol: "]"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, "parser.ListLiteralParseNode", 1},
+		// {{: split(s)[1].name}}
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.IdentToken{Identifier: "split"},
+			lexer.SymbolToken{Symbol: "("},
+			lexer.IdentToken{Identifier: "s"},
+			lexer.SymbolToken{Symbol: ")"},
+			lexer.SymbolToken{Symbol: "["},
+			lexer.NumToken{Num: "1"},
+			lexer.SymbolToken{Symbol: "]"},
+			lexer.SymbolToken{Symbol: "."},
+			lexer.IdentToken{Identifier: "name"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, "parser.FuncCallParseNode", 2},
+		// {{: (s)["a"]}}
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.SymbolToken{Symbol: "("},
+			lexer.IdentToken{Identifier: "s"},
+			lexer.SymbolToken{Symbol: ")"},
+			lexer.SymbolToken{Symbol: "["},
+			lexer.StrToken{Str: "a"},
+			lexer.SymbolToken{Symbol: "]"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, "parser.VarNameParseNode", 1},
+	}
+
+	for i, test := range tests {
+		stateStack := []int{}
+		nodeStack := []TreeNode{}
+
+		_, head, err := parseTokens(test.toks, &stateStack, &nodeStack)
+		if err != nil {
+			t.Fatalf("%d: expected no error, got %q", i, err)
+		}
+
+		index, ok := extractToken(head, []int{0, 1}).(*IndexParseNode)
+		if !ok {
+			t.Fatalf("%d: expected an IndexParseNode, got %s", i, extractToken(head, []int{0, 1}))
+		}
+
+		if target := index.GetTarget().String(); target != test.target {
+			t.Errorf("%d: expected target %s, got %s", i, test.target, target)
+		}
+
+		if keys := index.GetKeys(); len(keys) != test.keys {
+			t.Errorf("%d: expected %d keys, got %d", i, test.keys, len(keys))
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package parser
+
+import "fmt"
+
+// ListLiteralParseNode represents a list written in a block
+// like ["a", "b"]
+type ListLiteralParseNode struct {
+	ParseNode
+}
+
+func (receiver *ListLiteralParseNode) String() string {
+	return fmt.Sprintf("%T", *receiver)
+}
+
+func (node *ListLiteralParseNode) IsTerminal() bool {
+	return false
+}
+
+// GetElements returns the nodes of each element in the list from
+// left to right
+func (receiver *ListLiteralParseNode) GetElements() []TreeNode {
+	switch elements := receiver.children[1].(type) {
+	case *ArgsListParseNode:
+		return elements.GetArguments()
+	case *ArgsParseNode:
+		return elements.GetArguments()
+	}
+	return []TreeNode{receiver.children[1]}
+}
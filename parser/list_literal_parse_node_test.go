@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"mettlach.codes/frizzy/lexer"
+)
+
+func TestListLiteralReturnsElements(t *testing.T) {
+	var tests = []struct {
+		toks     []lexer.Token
+		elements []string
+	}{
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.SymbolToken{Symbol: "["},
+			lexer.SymbolToken{Symbol: "]"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, []string{}},
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.SymbolToken{Symbol: "["},
+			lexer.StrToken{Str: "a"},
+			lexer.SymbolToken{Symbol: "]"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, []string{"a"}},
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.SymbolToken{Symbol: "["},
+			lexer.StrToken{Str: "a"},
+			lexer.SymbolToken{Symbol: ","},
+			lexer.StrToken{Str: "b"},
+			lexer.SymbolToken{Symbol: ","},
+			lexer.StrToken{Str: "c"},
+			lexer.SymbolToken{Symbol: "]"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, []string{"a", "b", "c"}},
+	}
+
+	for _, test := range tests {
+		t.Run(strings.Join(test.elements, ","), func(t *testing.T) {
+			stateStack := []int{}
+			nodeStack := []TreeNode{}
+
+			_, head, err := parseTokens(test.toks, &stateStack, &nodeStack)
+			if err != nil {
+				t.Fatalf("expected no error, got %q", err)
+			}
+
+			list := extractToken(head, []int{0, 1}).(*ListLiteralParseNode)
+			got := []string{}
+			for _, element := range list.GetElements() {
+				got = append(got, element.GetChildren()[0].(*StringParseNode).Value)
+			}
+
+			if strings.Join(got, ",") != strings.Join(test.elements, ",") {
+				t.Errorf("expected %q, got %q", test.elements, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package parser
+
+import "fmt"
+
+// MapLiteralParseNode represents a map written in a block
+// like {"title": "Hello", "draft": false}
+type MapLiteralParseNode struct {
+	ParseNode
+}
+
+func (receiver *MapLiteralParseNode) String() string {
+	return fmt.Sprintf("%T", *receiver)
+}
+
+func (node *MapLiteralParseNode) IsTerminal() bool {
+	return false
+}
+
+// GetEntries returns each key: value entry of the map from left
+// to right
+// Return will be empty for {}
+func (receiver *MapLiteralParseNode) GetEntries() []*MapEntryParseNode {
+	switch entries := receiver.children[1].(type) {
+	case *MapEntriesParseNode:
+		return entries.GetEntries()
+	case *MapEntryParseNode:
+		return []*MapEntryParseNode{entries}
+	}
+	return []*MapEntryParseNode{}
+}
+
+// MapEntriesParseNode represents the comma separated entries of a map
+type MapEntriesParseNode struct {
+	ParseNode
+}
+
+func (receiver *MapEntriesParseNode) String() string {
+	return fmt.Sprintf("%T", *receiver)
+}
+
+func (node *MapEntriesParseNode) IsTerminal() bool {
+	return false
+}
+
+// GetEntries returns each entry from left to right
+func (receiver *MapEntriesParseNode) GetEntries() []*MapEntryParseNode {
+	entries := make([]*MapEntryParseNode, 0, (len(receiver.children)+1)/2)
+
+	for i := 0; i < len(receiver.children); i += 2 {
+		entries = append(entries, receiver.children[i].(*MapEntryParseNode))
+	}
+
+	return entries
+}
+
+// MapEntryParseNode represents a single "key": value entry of a map
+type MapEntryParseNode struct {
+	ParseNode
+}
+
+func (receiver *MapEntryParseNode) String() string {
+	return fmt.Sprintf("%T", *receiver)
+}
+
+func (node *MapEntryParseNode) IsTerminal() bool {
+	return false
+}
+
+// GetKey returns the key of the entry
+func (receiver *MapEntryParseNode) GetKey() string {
+	return receiver.children[0].(*StringParseNode).Value
+}
+
+// GetValue returns the node of the value of the entry
+func (receiver *MapEntryParseNode) GetValue() TreeNode {
+	return receiver.children[2]
+}
@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"mettlach.codes/frizzy/lexer"
+)
+
+func TestMapLiteralReturnsEntries(t *testing.T) {
+	var tests = []struct {
+		toks []lexer.Token
+		keys []string
+	}{
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.SymbolToken{Symbol: "{"},
+			lexer.SymbolToken{Symbol: "}"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, []string{}},
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.SymbolToken{Symbol: "{"},
+			lexer.StrToken{Str: "a"},
+			lexer.SymbolToken{Symbol: ":"},
+			lexer.NumToken{Num: "1"},
+			lexer.SymbolToken{Symbol: "}"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, []string{"a"}},
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.SymbolToken{Symbol: "{"},
+			lexer.StrToken{Str: "a"},
+			lexer.SymbolToken{Symbol: ":"},
+			lexer.NumToken{Num: "1"},
+			lexer.SymbolToken{Symbol: ","},
+			lexer.StrToken{Str: "b"},
+			lexer.SymbolToken{Symbol: ":"},
+			lexer.NumToken{Num: "2"},
+			lexer.SymbolToken{Symbol: ","},
+			lexer.StrToken{Str: "c"},
+			lexer.SymbolToken{Symbol: ":"},
+			lexer.NumToken{Num: "3"},
+			lexer.SymbolToken{Symbol: "}"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, []string{"a", "b", "c"}},
+	}
+
+	for _, test := range tests {
+		t.Run(strings.Join(test.keys, ","), func(t *testing.T) {
+			stateStack := []int{}
+			nodeStack := []TreeNode{}
+
+			_, head, err := parseTokens(test.toks, &stateStack, &nodeStack)
+			if err != nil {
+				t.Fatalf("expected no error, got %q", err)
+			}
+
+			mapLiteral := extractToken(head, []int{0, 1}).(*MapLiteralParseNode)
+			got := []string{}
+			for i, entry := range mapLiteral.GetEntries() {
+				got = append(got, entry.GetKey())
+
+				value := entry.GetValue().GetChildren()[0].(*NumParseNode).Value
+				if value != i+1 {
+					t.Errorf("expected %s to be %d, got %d", entry.GetKey(), i+1, value)
+				}
+			}
+
+			if strings.Join(got, ",") != strings.Join(test.keys, ",") {
+				t.Errorf("expected %q, got %q", test.keys, got)
+			}
+		})
+	}
+}
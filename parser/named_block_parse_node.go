@@ -0,0 +1,35 @@
+package parser
+
+import "fmt"
+
+// NamedBlockParseNode represents a {{block "name"}}...{{end}} region
+// In a layout the body is the default content of the region and in
+// a template extending the layout the body replaces that default
+// The children are {"block", "name", "}}", content, "end"} or
+// {"block", "name", "}}", "end"} when the body is empty
+type NamedBlockParseNode struct {
+	ParseNode
+}
+
+func (receiver *NamedBlockParseNode) String() string {
+	return fmt.Sprintf("%T", *receiver)
+}
+
+func (node *NamedBlockParseNode) IsTerminal() bool {
+	return false
+}
+
+// GetName returns the name of this block
+func (receiver *NamedBlockParseNode) GetName() string {
+	return receiver.children[1].(*StringParseNode).Value
+}
+
+// GetBody returns the content of this block or false if
+// the block is empty
+func (receiver *NamedBlockParseNode) GetBody() (TreeNode, bool) {
+	if len(receiver.children) == 5 {
+		return receiver.children[3], true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"testing"
+
+	"mettlach.codes/frizzy/lexer"
+)
+
+func TestNamedBlockReturnsNameAndBody(t *testing.T) {
+	var tests = []struct {
+		toks    []lexer.Token
+		name    string
+		hasBody bool
+	}{
+		{[]lexer.Token{
+			lexer.NamedBlockToken{},
+			lexer.StrToken{Str: "main"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.PassthroughToken{Value: "<p>default</p>"},
+			lexer.EndToken{},
+			lexer.EOLToken{},
+		}, "main", true},
+		{[]lexer.Token{
+			lexer.NamedBlockToken{},
+			lexer.StrToken{Str: "sidebar"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EndToken{},
+			lexer.EOLToken{},
+		}, "sidebar", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stateStack := []int{}
+			nodeStack := []TreeNode{}
+
+			_, head, err := parseTokens(test.toks, &stateStack, &nodeStack)
+			if err != nil {
+				t.Fatalf("expected no errors, got %q", err)
+			}
+
+			namedBlock := extractToken(head, []int{0}).(*NamedBlockParseNode)
+			if namedBlock.GetName() != test.name {
+				t.Errorf("expected name %q, got %q", test.name, namedBlock.GetName())
+			}
+
+			if _, ok := namedBlock.GetBody(); ok != test.hasBody {
+				t.Errorf("expected has body to be %t, got %t", test.hasBody, ok)
+			}
+		})
+	}
+}
+
+func TestExtendsReturnsTemplatePath(t *testing.T) {
+	toks := []lexer.Token{
+		lexer.ExtendsToken{},
+		lexer.StrToken{Str: "layouts/base.html"},
+		lexer.BlockToken{Block: "}}"},
+		lexer.NamedBlockToken{},
+		lexer.StrToken{Str: "main"},
+		lexer.BlockToken{Block: "}}"},
+		lexer.PassthroughToken{Value: "<p>override</p>"},
+		lexer.EndToken{},
+		lexer.EOLToken{},
+	}
+
+	stateStack := []int{}
+	nodeStack := []TreeNode{}
+
+	_, head, err := parseTokens(toks, &stateStack, &nodeStack)
+	if err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	extends := extractToken(head, []int{0, 0}).(*ExtendsParseNode)
+	if extends.GetTemplatePath() != "layouts/base.html" {
+		t.Errorf("expected template path %q, got %q", "layouts/base.html", extends.GetTemplatePath())
+	}
+}
@@ -0,0 +1,27 @@
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"mettlach.codes/frizzy/lr1"
+)
+
+func TestEmbeddedTableMatchesGrammar(t *testing.T) {
+	grammar, err := lr1.NewGrammar(GrammarProductions)
+	if err != nil {
+		t.Fatalf("invalid grammar: %s", err)
+	}
+
+	table, err := lr1.BuildTable(grammar)
+	if err != nil {
+		t.Fatalf("grammar is not LR(1): %s", err)
+	}
+
+	buf := &bytes.Buffer{}
+	table.Write(buf)
+
+	if buf.String() != rawTable {
+		t.Error("lr1_table.txt is out of date with GrammarProductions, run `go generate ./parser`")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strconv"
 
+	"mettlach.codes/frizzy/diagnostic"
 	"mettlach.codes/frizzy/lexer"
 )
 
@@ -170,7 +171,7 @@ func handleReduceAction(action string, token lexer.Token, stateStack *[]int, nod
 		copy(children, (*nodeStack)[len(*nodeStack)-numToPop:])
 
 		// If we have nested types e.g. nested ArgLists, then flatten the tree
-		if len(children) > 0 && reflect.TypeOf(children[0]) == reflect.TypeOf(node) {
+		if len(children) > 0 && isFlattenable(children[0], node) {
 			grandChildren := children[0].GetChildren()
 			// Drop the nested child and append remaining children to dropped child's children
 			children = append(grandChildren, children[1:]...)
@@ -179,6 +180,11 @@ func handleReduceAction(action string, token lexer.Token, stateStack *[]int, nod
 		// Create non-terminal
 		node.SetChildren(children)
 
+		// value | filter is sugar for filter(value)
+		if left == "pipe_expression" || left == "loop_pipe" {
+			node = newPipedFuncCall(children[0], children[2])
+		}
+
 		// Actually pop symbols
 		*nodeStack = (*nodeStack)[:len(*nodeStack)-numToPop]
 
@@ -188,6 +194,17 @@ func handleReduceAction(action string, token lexer.Token, stateStack *[]int, nod
 	return
 }
 
+// Nested nodes of the same list type can be flattened into their parent.
+// Generic non-terminals are never flattened since their children are
+// positional operands e.g. the left side of 1 + 2 + 3
+func isFlattenable(child, parent TreeNode) bool {
+	if _, ok := parent.(*NonTerminalParseNode); ok {
+		return false
+	}
+
+	return reflect.TypeOf(child) == reflect.TypeOf(parent)
+}
+
 // Creates the appropriate tree node for a given token
 func getTerminalNodeForToken(token lexer.Token) TreeNode {
 	var node TreeNode
@@ -196,20 +213,31 @@ func getTerminalNodeForToken(token lexer.Token) TreeNode {
 	case lexer.NumToken:
 		num, _ := strconv.Atoi(tok.Num)
 		node = &NumParseNode{Value: num}
+	case lexer.FloatToken:
+		num, _ := strconv.ParseFloat(tok.Num, 64)
+		node = &FloatParseNode{Value: num}
 	case lexer.BoolToken:
 		truthy := tok.Value == "true"
 		node = &BoolParseNode{Value: truthy}
-	case lexer.IdentToken, lexer.ForToken, lexer.InToken, lexer.IfToken, lexer.ElseIfToken, lexer.ElseToken, lexer.EndToken:
+	case lexer.IdentToken, lexer.ForToken, lexer.InToken, lexer.IfToken, lexer.ElseIfToken, lexer.ElseToken, lexer.EndToken,
+		lexer.ExtendsToken, lexer.NamedBlockToken:
 		ident := tok.GetValue()
 		node = &IdentParseNode{Value: ident}
 	case lexer.SymbolToken:
 		symbol := tok.Symbol
 		node = &SymbolParseNode{Value: symbol}
+	case lexer.PassthroughToken:
+		node = &StringParseNode{Value: tok.GetValue(), Passthrough: true}
 	default:
 		str := tok.GetValue()
 		node = &StringParseNode{Value: str}
 	}
 
+	node.SetSpan(Span{
+		Start: Position{Line: token.GetLineNum(), Col: token.GetLineCol()},
+		End:   Position{Line: token.GetLineNum(), Col: token.GetEndCol()},
+	})
+
 	return node
 }
 
@@ -231,6 +259,20 @@ func getNonTerminalNodeForReduction(reduction string) TreeNode {
 		return &ElseIfListParseNode{}
 	case "var_name":
 		return &VarNameParseNode{}
+	case "index_expression":
+		return &IndexParseNode{}
+	case "list_literal":
+		return &ListLiteralParseNode{}
+	case "map_literal":
+		return &MapLiteralParseNode{}
+	case "map_entries":
+		return &MapEntriesParseNode{}
+	case "map_entry":
+		return &MapEntryParseNode{}
+	case "extends_block":
+		return &ExtendsParseNode{}
+	case "named_block":
+		return &NamedBlockParseNode{}
 	case "block", "print_block":
 		return &BlockParseNode{}
 	default:
@@ -238,10 +280,10 @@ func getNonTerminalNodeForReduction(reduction string) TreeNode {
 	}
 }
 
-// Returns an parse error formatted for the current token
+// Returns an parse error positioned at the current token
 func getParseError(token lexer.Token, msg string, msgFmt ...interface{}) error {
 	lineNum, lineCol := token.GetLineNum(), token.GetLineCol()
 
 	fmtedMsg := fmt.Sprintf(msg, msgFmt...)
-	return fmt.Errorf("parse error line %d col %d: %s", lineNum, lineCol, fmtedMsg)
+	return diagnostic.Errorf(lineNum, lineCol, "parse error: %s", fmtedMsg)
 }
@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"testing"
+
+	"mettlach.codes/frizzy/lexer"
+)
+
+func TestChainedOperandsAreNotFlattened(t *testing.T) {
+	var tests = []struct {
+		toks     []lexer.Token
+		children int
+	}{
+		// {{: 1 + 2 + 3}} is (1 + 2) + 3
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.NumToken{Num: "1"},
+			lexer.AddOpToken{},
+			lexer.NumToken{Num: "2"},
+			lexer.AddOpToken{},
+			lexer.NumToken{Num: "3"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, 3},
+		// {{: - - 2}} is -(-2)
+		{[]lexer.Token{
+			lexer.BlockToken{Block: "{{:"},
+			lexer.SubOpToken{},
+			lexer.SubOpToken{},
+			lexer.NumToken{Num: "2"},
+			lexer.BlockToken{Block: "}}"},
+			lexer.EOLToken{},
+		}, 2},
+	}
+
+	for i, test := range tests {
+		stateStack := []int{}
+		nodeStack := []TreeNode{}
+
+		_, head, err := parseTokens(test.toks, &stateStack, &nodeStack)
+		if err != nil {
+			t.Fatalf("%d: expected no error, got %q", i, err)
+		}
+
+		expression := extractToken(head, []int{0, 1})
+		if children := expression.GetChildren(); len(children) != test.children {
+			t.Errorf("%d: expected %d operands and operators, got %d", i, test.children, len(children))
+		}
+	}
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"strings"
@@ -594,6 +595,19 @@ func TestBlockParsesNoErrors(t *testing.T) {
 		tokens [][]lexer.Token
 		nodes  []TreeNode
 	}{
+		{
+			tokens: [][]lexer.Token{{
+				lexer.BlockToken{Block: "{{:"},
+				lexer.FloatToken{Num: "4.5"},
+				lexer.MultOpToken{Operator: "*"},
+				lexer.NumToken{Num: "2"},
+				lexer.BlockToken{Block: "}}"},
+				lexer.EOLToken{},
+			}},
+			nodes: []TreeNode{
+				&NonTerminalParseNode{},
+			},
+		},
 		{
 			tokens: [][]lexer.Token{{
 				lexer.BlockToken{Block: "{{"},
@@ -1025,10 +1039,10 @@ func TestParserSendsErrorWithIncorrectToken(t *testing.T) {
 					lexer.BlockToken{Block: "}}"},
 				},
 				{
-					lexer.SymbolToken{Symbol: "?"},
+					lexer.SymbolToken{Symbol: "@"},
 				},
 			},
-			errMsg: `unrecognized symbol "?"`,
+			errMsg: `unrecognized symbol "@"`,
 		},
 		{
 			tokens: [][]lexer.Token{
@@ -1036,11 +1050,11 @@ func TestParserSendsErrorWithIncorrectToken(t *testing.T) {
 					lexer.BlockToken{Block: "{{"},
 				},
 				{
-					lexer.SymbolToken{Symbol: "?"},
+					lexer.SymbolToken{Symbol: "@"},
 					lexer.BlockToken{Block: "}}"},
 				},
 			},
-			errMsg: `unrecognized symbol "?"`,
+			errMsg: `unrecognized symbol "@"`,
 		},
 	}
 
@@ -1162,3 +1176,91 @@ func generateBufferedTokChan() <-chan []lexer.Token {
 
 	return bufferedChan
 }
+
+func TestParseSetsNodeSpans(t *testing.T) {
+	lex := lexer.Lexer{}
+	text := "ab {{: foo(1 + 2)}}\n{{: \"é\" + x}}"
+	tokChan, _ := lex.Lex(strings.NewReader(text), context.Background())
+	nodeChan, errChan := Parse(tokChan, context.Background())
+
+	spans := map[string]Span{}
+	var walk func(node TreeNode)
+	walk = func(node TreeNode) {
+		key := fmt.Sprintf("%T %s", node, node)
+		if _, seen := spans[key]; !seen {
+			spans[key] = node.GetSpan()
+		}
+		for _, child := range node.GetChildren() {
+			walk(child)
+		}
+	}
+
+	for node := range nodeChan {
+		walk(node)
+	}
+
+	if err := <-errChan; err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	var tests = []struct {
+		node     string
+		expected Span
+	}{
+		{"*parser.NumParseNode parser.NumParseNode: 1", Span{Position{1, 12}, Position{1, 13}}},
+		{"*parser.NonTerminalParseNode parser.NonTerminalParseNode: add_expression", Span{Position{1, 12}, Position{1, 17}}},
+		{"*parser.FuncCallParseNode parser.FuncCallParseNode", Span{Position{1, 8}, Position{1, 18}}},
+		{"*parser.StringParseNode parser.StringParseNode: \"é\"", Span{Position{2, 5}, Position{2, 8}}},
+	}
+
+	for i, test := range tests {
+		if actual, ok := spans[test.node]; !ok {
+			t.Errorf("%d: expected a %s node, got none", i, test.node)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %s to span %v, got %v", i, test.node, test.expected, actual)
+		}
+	}
+}
+
+func TestOnlyPassthroughTextIsMarkedPassthrough(t *testing.T) {
+	// <p>{{: "text"}}</p>
+	toks := []lexer.Token{
+		lexer.PassthroughToken{Value: "<p>"},
+		lexer.BlockToken{Block: "{{:"},
+		lexer.StrToken{Str: "text"},
+		lexer.BlockToken{Block: "}}"},
+		lexer.PassthroughToken{Value: "</p>"},
+		lexer.EOLToken{},
+	}
+
+	stateStack := []int{}
+	nodeStack := []TreeNode{}
+
+	_, head, err := parseTokens(toks, &stateStack, &nodeStack)
+	if err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	expected := map[string]bool{"<p>": true, "text": false, "</p>": true}
+	found := 0
+
+	var walk func(node TreeNode)
+	walk = func(node TreeNode) {
+		if str, ok := node.(*StringParseNode); ok {
+			if _, ok := expected[str.Value]; ok {
+				found++
+			}
+			if str.Passthrough != expected[str.Value] {
+				t.Errorf("expected %q to have Passthrough %t", str.Value, expected[str.Value])
+			}
+		}
+		for _, child := range node.GetChildren() {
+			walk(child)
+		}
+	}
+	walk(head)
+
+	if found != len(expected) {
+		t.Errorf("expected %d string nodes, got %d", len(expected), found)
+	}
+}
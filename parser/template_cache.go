@@ -22,7 +22,7 @@ func GetTemplateCache() *TemplateCache {
 	return cache
 }
 
-var mut sync.Mutex
+var mut sync.RWMutex
 
 // Insert inserts the key, value pair of the export context
 // represented by filename
@@ -32,8 +32,18 @@ func (receiver *TemplateCache) Insert(key string, value TreeNode) {
 	receiver.cache[key] = append(receiver.cache[key], value)
 }
 
-// Get returns the export context of the given filename
+// Get returns the nodes cached for key
+// It can be called while templates are being cached again
 func (receiver *TemplateCache) Get(key string) *[]TreeNode {
+	mut.RLock()
+	defer mut.RUnlock()
 	nodes := receiver.cache[key]
 	return &nodes
 }
+
+// Remove deletes the nodes cached for key so it can be cached again
+func (receiver *TemplateCache) Remove(key string) {
+	mut.Lock()
+	defer mut.Unlock()
+	delete(receiver.cache, key)
+}
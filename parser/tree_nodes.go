@@ -9,11 +9,28 @@ type TreeNode interface {
 	PrintTree()
 	SetChildren(children []TreeNode)
 	IsTerminal() bool
+	GetSpan() Span
+	SetSpan(span Span)
 	fmt.Stringer
 }
 
+// Position is a line and rune column in a source file
+// Both start at 1, a zero Line means the position isn't known
+type Position struct {
+	Line int
+	Col  int
+}
+
+// Span is the source a node was parsed from, End is the position just
+// after the node
+type Span struct {
+	Start Position
+	End   Position
+}
+
 type ParseNode struct {
 	children []TreeNode
+	span     Span
 }
 
 func (node *ParseNode) String() string {
@@ -24,8 +41,27 @@ func (node *ParseNode) GetChildren() []TreeNode {
 	return node.children
 }
 
+// SetChildren also sets the span of node to cover all of its children
 func (node *ParseNode) SetChildren(children []TreeNode) {
 	node.children = children
+	node.span = Span{}
+
+	for _, child := range children {
+		if span := child.GetSpan(); span.Start.Line > 0 {
+			if node.span.Start.Line == 0 {
+				node.span.Start = span.Start
+			}
+			node.span.End = span.End
+		}
+	}
+}
+
+func (node *ParseNode) GetSpan() Span {
+	return node.span
+}
+
+func (node *ParseNode) SetSpan(span Span) {
+	node.span = span
 }
 
 func (node *ParseNode) PrintTree() {
@@ -110,8 +146,20 @@ func (node NonTerminalParseNode) IsUnary() bool {
 	return node.Value == "unary_expression" && len(node.children) > 1
 }
 
+// IsConditional reports whether node is a ternary like a ? b : c
+func (node NonTerminalParseNode) IsConditional() bool {
+	return node.Value == "conditional_expression" && len(node.children) > 1
+}
+
+// IsCoalesce reports whether node is a null-coalescing a ?? b
+func (node NonTerminalParseNode) IsCoalesce() bool {
+	return node.Value == "coalesce_expression" && len(node.children) > 1
+}
+
 type StringParseNode struct {
 	Value string
+	// Passthrough is true for template text outside of blocks
+	Passthrough bool
 	ParseNode
 }
 
@@ -136,6 +184,19 @@ func (node NumParseNode) IsTerminal() bool {
 	return true
 }
 
+type FloatParseNode struct {
+	Value float64
+	ParseNode
+}
+
+func (node FloatParseNode) String() string {
+	return fmt.Sprintf("%T: %g", node, node.Value)
+}
+
+func (node FloatParseNode) IsTerminal() bool {
+	return true
+}
+
 type BoolParseNode struct {
 	Value bool
 	ParseNode
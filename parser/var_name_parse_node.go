@@ -2,12 +2,11 @@ package parser
 
 import (
 	"fmt"
-	"math"
 )
 
 // VarNameParseNode represents a variable name
-// Can be dot separated like "post.title" or a single
-// identifier like "title"
+// Can be dot separated like "post.title", indexed like
+// items[0] or a single identifier like "title"
 type VarNameParseNode struct {
 	ParseNode
 	flattenedChildren []*VarNameParseNode
@@ -24,14 +23,46 @@ func (node *VarNameParseNode) IsTerminal() bool {
 // GetVarNameParts returns an array of string ident names represented
 // by this VarNameParseNode tree
 // e.g. "foo" will return ["foo"] and "foo.bar" will return ["foo", "bar"]
+// Index expressions are skipped, use GetKeys to evaluate them
 func (receiver *VarNameParseNode) GetVarNameParts() []string {
-	numParts := float64(len(receiver.children)) / float64(2)
-	numNames := int(math.Ceil(numParts))
-	nameParts := make([]string, 0, numNames)
+	keys := receiver.GetKeys()
+	nameParts := make([]string, 0, len(keys))
 
-	for i := 0; i < len(receiver.children); i += 2 {
-		nameParts = append(nameParts, receiver.children[i].(*IdentParseNode).Value)
+	for _, key := range keys {
+		if ident, ok := key.(*IdentParseNode); ok {
+			nameParts = append(nameParts, ident.Value)
+		}
 	}
 
 	return nameParts
 }
+
+// GetKeys returns a node for each key of the variable name from left
+// to right
+// Dotted names are *IdentParseNode and indexes are the expression
+// between the brackets e.g. foo.bar[1 + 2] returns [foo, bar, 1 + 2]
+func (receiver *VarNameParseNode) GetKeys() []TreeNode {
+	return getKeys(receiver.children)
+}
+
+// getKeys returns the idents and the index expressions in children,
+// which are idents, dots and bracketed expressions
+func getKeys(children []TreeNode) []TreeNode {
+	keys := []TreeNode{}
+
+	for i := 0; i < len(children); i++ {
+		child := children[i]
+		if symbol, ok := child.(*SymbolParseNode); ok {
+			if symbol.Value == "[" && i+1 < len(children) {
+				keys = append(keys, children[i+1])
+				// skip the closing bracket
+				i += 2
+			}
+			continue
+		}
+
+		keys = append(keys, child)
+	}
+
+	return keys
+}
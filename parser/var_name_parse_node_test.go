@@ -57,3 +57,51 @@ func TestNestedVarNameReturnsCorrectNameParts(t *testing.T) {
 		})
 	}
 }
+
+func TestIndexedVarNameReturnsKeys(t *testing.T) {
+	toks := []lexer.Token{
+		lexer.BlockToken{Block: "{{"},
+		lexer.IdentToken{Identifier: "posts"},
+		lexer.SymbolToken{Symbol: "["},
+		lexer.NumToken{Num: "0"},
+		lexer.SymbolToken{Symbol: "]"},
+		lexer.SymbolToken{Symbol: "."},
+		lexer.IdentToken{Identifier: "tags"},
+		lexer.SymbolToken{Symbol: "["},
+		lexer.StrToken{Str: "first"},
+		lexer.SymbolToken{Symbol: "]"},
+		lexer.BlockToken{Block: "}}"},
+		lexer.EOLToken{},
+	}
+
+	stateStack := []int{}
+	nodeStack := []TreeNode{}
+
+	_, head, err := parseTokens(toks, &stateStack, &nodeStack)
+	if err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	varName := extractToken(head, []int{0, 1}).(*VarNameParseNode)
+	keys := varName.GetKeys()
+	if len(keys) != 4 {
+		t.Fatalf("expected 4 keys, got %d", len(keys))
+	}
+
+	if ident, ok := keys[0].(*IdentParseNode); !ok || ident.Value != "posts" {
+		t.Errorf("expected first key to be posts, got %s", keys[0])
+	}
+	if num, ok := keys[1].GetChildren()[0].(*NumParseNode); !ok || num.Value != 0 {
+		t.Errorf("expected second key to be 0, got %s", keys[1])
+	}
+	if ident, ok := keys[2].(*IdentParseNode); !ok || ident.Value != "tags" {
+		t.Errorf("expected third key to be tags, got %s", keys[2])
+	}
+	if str, ok := keys[3].GetChildren()[0].(*StringParseNode); !ok || str.Value != "first" {
+		t.Errorf("expected fourth key to be first, got %s", keys[3])
+	}
+
+	if parts := varName.GetVarNameParts(); strings.Join(parts, ".") != "posts.tags" {
+		t.Errorf("expected name parts to be posts.tags, got %q", parts)
+	}
+}
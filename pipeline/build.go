@@ -0,0 +1,219 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"mettlach.codes/frizzy/config"
+	"mettlach.codes/frizzy/depgraph"
+	"mettlach.codes/frizzy/diagnostic"
+	"mettlach.codes/frizzy/parser"
+	"mettlach.codes/frizzy/processor"
+)
+
+// RenderHandler renders inputFile, tracking what each of its outputs is
+// rendered from in graph
+type RenderHandler func(ctx context.Context, inputFile *os.File, graph *depgraph.Graph) <-chan error
+
+// Build runs every stage of a site build
+// Templates are cached first, then the front matter and assignments of
+// every content and page file are exported before any of them is
+// rendered with renderHandler, so a file can reference the exports of
+// any other file regardless of the order files are processed in
+// Only files whose outputs are missing or whose dependencies changed
+// since the last build are rendered, the dependency graph is kept in
+// the config's cache directory
+func Build(config *config.Config, renderHandler RenderHandler) error {
+	templatePaths := collectPaths(WalkAllFiles(config.GetTemplatePath()))
+	sources := collectPaths(WalkAllFiles(config.GetContentPath(), config.GetPagesPath()))
+
+	return build(config, renderHandler, templatePaths, sources)
+}
+
+// Rebuild runs the stages of a build affected by changedPaths
+// Changed templates are cached again and changed content and page files
+// have their exports collected again before every file whose
+// dependencies changed is rendered
+// Paths that no longer exist are removed from the template cache and
+// export store
+func Rebuild(config *config.Config, renderHandler RenderHandler, changedPaths []string) error {
+	templatePath := config.GetTemplatePath()
+	inputDirs := []string{config.GetContentPath(), config.GetPagesPath()}
+	changedTemplates, changedSources := []string{}, []string{}
+
+	for _, changedPath := range changedPaths {
+		_, statErr := os.Stat(changedPath)
+		removed := statErr != nil
+
+		switch {
+		case isInDir(changedPath, templatePath) && removed:
+			parser.GetTemplateCache().Remove(templateCacheKey(changedPath))
+		case isInDir(changedPath, templatePath):
+			changedTemplates = append(changedTemplates, changedPath)
+		case isInDir(changedPath, inputDirs...) && removed:
+			processor.GetExportStore().Remove(changedPath)
+		case isInDir(changedPath, inputDirs...):
+			changedSources = append(changedSources, changedPath)
+		}
+	}
+
+	return build(config, renderHandler, changedTemplates, changedSources)
+}
+
+// build caches templatePaths, collects the exports of exportPaths and
+// then renders every content and page file that needs it
+func build(config *config.Config, renderHandler RenderHandler, templatePaths, exportPaths []string) error {
+	graph, err := depgraph.Load(config.GetCachePath())
+	if err != nil {
+		log.Printf("could not load dependency graph, rendering everything, %s\n", err)
+		graph = depgraph.NewGraph()
+	}
+	graph.UseBuildHash(buildHash(config))
+
+	// stages keep going after errors so every problem is reported at once
+	diagnostics := diagnostic.Diagnostics{}
+
+	log.Println("pipelining template files")
+	diagnostics.Add(RunPipeline(sendPaths(templatePaths), TemplateCacheHandler))
+	log.Println("finished template files")
+
+	log.Println("collecting exports")
+	diagnostics.Add(RunPipeline(sendPaths(exportPaths), CollectExportsHandler))
+	log.Println("finished collecting exports")
+
+	sources := collectPaths(WalkAllFiles(config.GetContentPath(), config.GetPagesPath()))
+	stale := []string{}
+	for _, source := range sources {
+		if graph.NeedsRender(source) {
+			stale = append(stale, source)
+		}
+	}
+
+	// files read the exports collected above while they're rendered so
+	// nothing can change them until rendering is done
+	exportStore := processor.GetExportStore()
+	exportStore.Freeze()
+	defer exportStore.Thaw()
+	log.Printf("rendering %d of %d content and page files\n", len(stale), len(sources))
+	diagnostics.Add(RunPipeline(sendPaths(stale), recordDependencies(graph, renderHandler)))
+	log.Println("finished content and page files")
+
+	if err := graph.Prune(sources); err != nil {
+		log.Printf("could not delete the outputs of removed files, %s\n", err)
+	}
+	if err := graph.Save(config.GetCachePath()); err != nil {
+		log.Printf("could not save dependency graph, %s\n", err)
+	}
+
+	if len(diagnostics) == 0 {
+		return nil
+	}
+
+	return diagnostics
+}
+
+// recordDependencies wraps handler so the dependencies tracked while
+// rendering a file are committed to graph once it renders without
+// errors and discarded otherwise, warnings don't stop a commit
+func recordDependencies(graph *depgraph.Graph, handler RenderHandler) func(context.Context, *os.File) <-chan error {
+	return func(ctx context.Context, inputFile *os.File) <-chan error {
+		source := inputFile.Name()
+		handlerErrChan := handler(ctx, inputFile, graph)
+		errChan := make(chan error)
+
+		go func() {
+			defer close(errChan)
+
+			failed := false
+			for err := range handlerErrChan {
+				failed = failed || diagnostic.IsError(err)
+				select {
+				case errChan <- err:
+				case <-ctx.Done():
+				}
+			}
+
+			if failed {
+				graph.Discard(source)
+			} else {
+				graph.Commit(source)
+			}
+		}()
+
+		return errChan
+	}
+}
+
+// buildHash returns a hash of what outputs are rendered with besides
+// their dependencies, the config and the running binary, so changing
+// either renders everything again
+func buildHash(config *config.Config) string {
+	hasher := sha256.New()
+	if configJSON, err := json.Marshal(config); err == nil {
+		hasher.Write(configJSON)
+	}
+	hasher.Write([]byte(getBinaryHash()))
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+var binaryHashOnce sync.Once
+var binaryHash string
+
+// getBinaryHash returns the hex encoded SHA-256 of the running binary,
+// it is only read once, or an empty string if it can't be read
+func getBinaryHash() string {
+	binaryHashOnce.Do(func() {
+		path, err := os.Executable()
+		if err != nil {
+			return
+		}
+
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return
+		}
+
+		sum := sha256.Sum256(bytes)
+		binaryHash = hex.EncodeToString(sum[:])
+	})
+
+	return binaryHash
+}
+
+// isInDir reports whether path is below any of dirs
+func isInDir(path string, dirs ...string) bool {
+	for _, dir := range dirs {
+		if relative, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(relative, "..") {
+			return true
+		}
+	}
+	return false
+}
+
+func collectPaths(pathChan <-chan string) []string {
+	paths := []string{}
+	for path := range pathChan {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func sendPaths(paths []string) <-chan string {
+	pathChan := make(chan string)
+
+	go func() {
+		defer close(pathChan)
+		for _, path := range paths {
+			pathChan <- path
+		}
+	}()
+
+	return pathChan
+}
@@ -0,0 +1,450 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mettlach.codes/frizzy/config"
+	"mettlach.codes/frizzy/diagnostic"
+)
+
+// writeSite creates the files in site under a temp root and
+// loads a config pointing at it
+func writeSite(t *testing.T, site map[string]string) *config.Config {
+	root := t.TempDir()
+
+	for path, content := range site {
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	configPath := filepath.Join(root, "config.json")
+	configJSON := fmt.Sprintf(`{"RootPath": %q, "OutputPath": %q}`, root, filepath.Join(root, "output"))
+	if err := os.WriteFile(configPath, []byte(configJSON), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return loaded
+}
+
+func TestBuildCollectsExportsBeforeRendering(t *testing.T) {
+	site := map[string]string{
+		"templates/base.html":       `<ul>{{block "main"}}{{end}}</ul>`,
+		"content/index/index.html":  `{{for post in "posts"}}<li>{{: post.title}}</li>{{end}}`,
+		"pages/about.html":          `{{extends "base.html"}}{{block "main"}}{{for post in "posts"}}{{: post.title}};{{end}}{{end}}`,
+		"content/posts/fm.md":       "---\ntitle: Front Matter\n---\nbody\n",
+		"content/posts/assigned.md": "{{title = `Assigned`}}\nbody\n",
+	}
+
+	// enough posts that some are likely to be rendered after the index
+	for i := 0; i < 20; i++ {
+		site[fmt.Sprintf("content/posts/post_%02d.md", i)] = fmt.Sprintf("{{title = `Post %d`}}\n", i)
+	}
+
+	siteConfig := writeSite(t, site)
+	if err := Build(siteConfig, FullPipelineHtmlRenderer); err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	for _, output := range []string{"content/index/index.html", "pages/about.html"} {
+		rendered, err := os.ReadFile(filepath.Join(siteConfig.OutputPath, output))
+		if err != nil {
+			t.Fatalf("expected %s to be rendered, got %q", output, err)
+		}
+
+		for _, title := range []string{"Front Matter", "Assigned", "Post 0", "Post 19"} {
+			if !strings.Contains(string(rendered), title) {
+				t.Errorf("expected %s to contain %q, got %q", output, title, rendered)
+			}
+		}
+	}
+}
+
+func TestBuildRendersWithCollectedExports(t *testing.T) {
+	site := map[string]string{
+		"templates/post.html": `{{for post in content}}{{: post.title}};{{end}}`,
+		"pages/list.html":     `{{: paginate("posts", "post.html", 3)}}`,
+		"pages/index.html":    `{{for post in "posts"}}{{: post.title}};{{end}}`,
+	}
+
+	// assignments in if blocks are collected, those in loops over
+	// content are only made while rendering so they aren't exported
+	expected := ""
+	for i := 0; i < 30; i++ {
+		site[fmt.Sprintf("content/posts/post_%02d.md", i)] = fmt.Sprintf(
+			"---\ndraft: %t\n---\n{{title = `Post %d`}}{{if draft}}{{title = `Draft %d`}}{{end}}{{for p in \"posts\"}}{{title = `Changed`}}{{end}}\nbody\n",
+			i%2 == 0, i, i,
+		)
+		if i%2 == 0 {
+			expected += fmt.Sprintf("Draft %d;", i)
+		} else {
+			expected += fmt.Sprintf("Post %d;", i)
+		}
+	}
+
+	siteConfig := writeSite(t, site)
+	if err := Build(siteConfig, FullPipelineHtmlRenderer); err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	for _, output := range []string{"pages/index.html", "pages/list_001.html", "pages/list_010.html"} {
+		rendered, err := os.ReadFile(filepath.Join(siteConfig.OutputPath, output))
+		if err != nil {
+			t.Fatalf("expected %s to be rendered, got %q", output, err)
+		}
+
+		if strings.Contains(string(rendered), "Changed") {
+			t.Errorf("expected %s to only show collected titles, got %q", output, rendered)
+		}
+	}
+
+	rendered, err := os.ReadFile(filepath.Join(siteConfig.OutputPath, "pages/index.html"))
+	if err != nil {
+		t.Fatalf("expected pages/index.html to be rendered, got %q", err)
+	}
+	if string(rendered) != expected {
+		t.Errorf("expected pages/index.html to be %q, got %q", expected, rendered)
+	}
+}
+
+func TestBuildEscapesValuesPrintedInEveryFile(t *testing.T) {
+	siteConfig := writeSite(t, map[string]string{
+		"content/posts/a.md": "{{s = \"<script>alert(1)</script>\"}}# {{: s}}\n",
+		"content/posts/b.md": "{{: \"Tom & Jerry\"}}\n\n<div>{{: \"<b>\"}}</div>\n",
+		"pages/notes.txt":    "{{s = \"<script>alert(1)</script>\"}}{{: s}}\n",
+		"pages/safe.md":      "{{: safe(\"<i>kept</i>\")}}\n",
+		"pages/prose.md":     "Use the <script> tag {{: \"Hello\"}}\n\n`{{: \"<i>\"}}`\n",
+	})
+
+	if err := Build(siteConfig, FullPipelineHtmlRenderer); err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	var tests = []struct {
+		output   string
+		expected string
+	}{
+		{"content/posts/a.html", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{"content/posts/b.html", "<p>Tom &amp; Jerry</p>"},
+		{"content/posts/b.html", "<div>&lt;b&gt;</div>"},
+		{"pages/notes.html", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{"pages/safe.html", "<i>kept</i>"},
+		{"pages/prose.html", "Use the <script> tag Hello"},
+		{"pages/prose.html", "<code>&lt;i&gt;</code>"},
+	}
+
+	for i, test := range tests {
+		rendered, err := os.ReadFile(filepath.Join(siteConfig.OutputPath, test.output))
+		if err != nil {
+			t.Errorf("%d: expected %s to be rendered, got %q", i, test.output, err)
+		} else if !strings.Contains(string(rendered), test.expected) {
+			t.Errorf("%d: expected %s to contain %q, got %q", i, test.output, test.expected, rendered)
+		}
+	}
+}
+
+func TestBuildPaginatesCollections(t *testing.T) {
+	site := map[string]string{
+		"templates/post.html": `{{: curPage}}/{{: numPages}}{{for post in content}} {{: post.title}}{{end}}`,
+		"content/posts/a.md":  "---\ntitle: A\ndate: 2021-01-01\n---\nbody\n",
+		"content/posts/b.md":  "---\ntitle: B\ndate: 2021-03-01\ndraft: true\n---\nbody\n",
+		"content/posts/c.md":  "---\ntitle: C\ndate: 2021-02-01\n---\nbody\n",
+		"content/posts/d.md":  "---\ntitle: D\ndate: 2021-04-01\n---\nbody\n",
+		"pages/list.html":     `{{: paginate(sort(where("posts", "draft", false), "date", "desc"), "post.html", 2)}}`,
+	}
+
+	siteConfig := writeSite(t, site)
+	if err := Build(siteConfig, FullPipelineHtmlRenderer); err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	var tests = []struct {
+		output   string
+		expected string
+	}{
+		{"pages/list_001.html", "1/2 D C"},
+		{"pages/list_002.html", "2/2 A"},
+	}
+
+	for i, test := range tests {
+		rendered, err := os.ReadFile(filepath.Join(siteConfig.OutputPath, test.output))
+		if err != nil {
+			t.Errorf("%d: expected %s to be rendered, got %q", i, test.output, err)
+		} else if !strings.Contains(string(rendered), test.expected) {
+			t.Errorf("%d: expected %s to contain %q, got %q", i, test.output, test.expected, rendered)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(siteConfig.OutputPath, "pages/list_003.html")); err == nil {
+		t.Errorf("expected only 2 pages to be rendered")
+	}
+}
+
+func TestBuildOnlyRendersChangedDependencies(t *testing.T) {
+	site := map[string]string{
+		"templates/base.html":      `<main>{{block "main"}}{{end}}</main>`,
+		"templates/post.html":      `{{for post in content}}{{: post.title}};{{end}}`,
+		"content/index/index.html": `{{for post in "posts"}}{{: post.title}};{{end}}`,
+		"content/posts/a.md":       "{{title = `A`}}\n",
+		"content/posts/b.md":       "{{title = `B`}}\n",
+		"pages/about.html":         `{{extends "base.html"}}{{block "main"}}about{{end}}`,
+		"pages/list.html":          `{{: paginate("posts", "post.html", 1)}}`,
+		"templates/raw.txt":        `raw`,
+		"pages/raw.html":           `{{: template("raw.txt")}}`,
+	}
+
+	siteConfig := writeSite(t, site)
+	if err := Build(siteConfig, FullPipelineHtmlRenderer); err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	outputs := []string{
+		"content/index/index.html",
+		"content/posts/a.html",
+		"content/posts/b.html",
+		"pages/about.html",
+		"pages/list_001.html",
+		"pages/list_002.html",
+		"pages/raw.html",
+	}
+
+	// rebuild after changing a file and return which outputs were rendered
+	rebuild := func(changed, content string) map[string]bool {
+		for _, output := range outputs {
+			if err := os.WriteFile(filepath.Join(siteConfig.OutputPath, output), []byte("stale"), 0640); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := os.WriteFile(filepath.Join(siteConfig.RootPath, changed), []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := Build(siteConfig, FullPipelineHtmlRenderer); err != nil {
+			t.Fatalf("expected no errors, got %q", err)
+		}
+
+		rendered := map[string]bool{}
+		for _, output := range outputs {
+			bytes, _ := os.ReadFile(filepath.Join(siteConfig.OutputPath, output))
+			rendered[output] = string(bytes) != "stale"
+		}
+		return rendered
+	}
+
+	var tests = []struct {
+		changed  string
+		content  string
+		rendered []string
+	}{
+		// every page of a paginated file is rendered together
+		{"content/posts/a.md", "{{title = `A2`}}\n", []string{"content/index/index.html", "content/posts/a.html", "pages/list_001.html", "pages/list_002.html"}},
+		{"templates/base.html", `<div>{{block "main"}}{{end}}</div>`, []string{"pages/about.html"}},
+		{"templates/post.html", `{{for post in content}}{{: post.title}}!{{end}}`, []string{"pages/list_001.html", "pages/list_002.html"}},
+		{"pages/about.html", `about`, []string{"pages/about.html"}},
+		{"templates/raw.txt", `changed`, []string{"pages/raw.html"}},
+	}
+
+	for i, test := range tests {
+		rendered := rebuild(test.changed, test.content)
+
+		expected := map[string]bool{}
+		for _, output := range test.rendered {
+			expected[output] = true
+		}
+
+		for _, output := range outputs {
+			if rendered[output] != expected[output] {
+				t.Errorf("%d: after changing %s expected %s rendered to be %t, got %t", i, test.changed, output, expected[output], rendered[output])
+			}
+		}
+	}
+}
+
+func TestBuildRendersEverythingWhenTheConfigChanges(t *testing.T) {
+	siteConfig := writeSite(t, map[string]string{
+		"pages/a.html": "{{if true}}\na\n{{end}}\n",
+		"pages/b.html": "b\n",
+	})
+
+	if err := Build(siteConfig, FullPipelineHtmlRenderer); err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	outputs := []string{"pages/a.html", "pages/b.html"}
+	for _, output := range outputs {
+		if err := os.WriteFile(filepath.Join(siteConfig.OutputPath, output), []byte("stale"), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	siteConfig.TrimBlockLines = true
+	if err := Build(siteConfig, FullPipelineHtmlRenderer); err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	for _, output := range outputs {
+		rendered, _ := os.ReadFile(filepath.Join(siteConfig.OutputPath, output))
+		if string(rendered) == "stale" {
+			t.Errorf("expected %s to be rendered after the config changed", output)
+		}
+	}
+
+	if rendered, _ := os.ReadFile(filepath.Join(siteConfig.OutputPath, "pages/a.html")); string(rendered) != "a\n" {
+		t.Errorf("expected pages/a.html to be rendered with the new config, got %q", rendered)
+	}
+}
+
+func TestBuildDeletesOutputsOfRemovedFiles(t *testing.T) {
+	siteConfig := writeSite(t, map[string]string{
+		"content/posts/a.md": "a\n",
+		"content/posts/b.md": "b\n",
+	})
+
+	if err := Build(siteConfig, FullPipelineHtmlRenderer); err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	if err := os.Remove(filepath.Join(siteConfig.RootPath, "content/posts/b.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Build(siteConfig, FullPipelineHtmlRenderer); err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(siteConfig.OutputPath, "content/posts/b.html")); !os.IsNotExist(err) {
+		t.Errorf("expected the output of a removed file to be deleted, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(siteConfig.OutputPath, "content/posts/a.html")); err != nil {
+		t.Errorf("expected the output of a remaining file to be kept, got %v", err)
+	}
+}
+
+func TestRebuildRunsAffectedStages(t *testing.T) {
+	siteConfig := writeSite(t, map[string]string{
+		"templates/base.html":      `<main>{{block "main"}}{{end}}</main>`,
+		"content/index/index.html": `{{extends "base.html"}}{{block "main"}}{{for post in "posts"}}{{: post.title}};{{end}}{{end}}`,
+		"content/posts/a.md":       "{{title = `A`}}\n",
+		"content/posts/b.md":       "{{title = `B`}}\n",
+	})
+
+	if err := Build(siteConfig, FullPipelineHtmlRenderer); err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	root := siteConfig.RootPath
+	changes := map[string]string{
+		"templates/base.html": `<div>{{block "main"}}{{end}}</div>`,
+		"content/posts/a.md":  "{{title = `A2`}}\n",
+	}
+
+	changed := []string{filepath.Join(root, "content/posts/b.md")}
+	os.Remove(changed[0])
+	for path, content := range changes {
+		fullPath := filepath.Join(root, path)
+		if err := os.WriteFile(fullPath, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+		changed = append(changed, fullPath)
+	}
+
+	if err := Rebuild(siteConfig, FullPipelineHtmlRenderer, changed); err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	expected := "<div>A2;</div>"
+	rendered, _ := os.ReadFile(filepath.Join(siteConfig.OutputPath, "content/index/index.html"))
+	if string(rendered) != expected {
+		t.Errorf("expected %q, got %q", expected, rendered)
+	}
+}
+
+// TestRecachingTemplatesWhileRendering is meant to be run with -race,
+// it caches the templates again while pages that use them render
+func TestRecachingTemplatesWhileRendering(t *testing.T) {
+	siteConfig := writeSite(t, map[string]string{
+		"templates/base.html":   `<main>{{block "main"}}{{end}}</main>`,
+		"templates/footer.html": `<footer>{{: year}}</footer>`,
+		"pages/a.html":          `{{extends "base.html"}}{{block "main"}}a{{end}}`,
+		"pages/b.html":          `{{year = 2021}}{{: include("footer.html")}}`,
+	})
+
+	if err := Build(siteConfig, FullPipelineHtmlRenderer); err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	templatePaths := collectPaths(WalkAllFiles(siteConfig.GetTemplatePath()))
+	sources := collectPaths(WalkAllFiles(siteConfig.GetPagesPath()))
+	render := func(ctx context.Context, inputFile *os.File) <-chan error {
+		return FullPipelineHtmlRenderer(ctx, inputFile, nil)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			RunPipeline(sendPaths(templatePaths), TemplateCacheHandler)
+		}
+	}()
+
+	// pages can fail to find a template that is being cached again,
+	// only the data race matters here
+	for i := 0; i < 20; i++ {
+		RunPipeline(sendPaths(sources), render)
+	}
+	<-done
+}
+
+func TestBuildReportsEveryFailingFile(t *testing.T) {
+	siteConfig := writeSite(t, map[string]string{
+		"pages/parse.html":    "fine\n{{: 1 + }}\n",
+		"pages/function.html": "{{: missing()}}\n",
+		"pages/good.html":     "{{: 1 + 2}}\n",
+	})
+
+	err := Build(siteConfig, FullPipelineHtmlRenderer)
+
+	var diagnostics diagnostic.Diagnostics
+	if !errors.As(err, &diagnostics) {
+		t.Fatalf("expected diagnostics, got %v", err)
+	}
+
+	failed := map[string]int{}
+	reported := map[string]int{}
+	for _, fileDiagnostic := range diagnostics {
+		failed[filepath.Base(fileDiagnostic.Filename)] = fileDiagnostic.Line
+		reported[filepath.Base(fileDiagnostic.Filename)]++
+	}
+
+	if line, ok := failed["parse.html"]; !ok || line != 2 {
+		t.Errorf("expected a parse error on line 2 of parse.html, got %v", diagnostics)
+	} else if reported["parse.html"] != 1 {
+		t.Errorf("expected the parse error in parse.html to be reported once, got %v", diagnostics)
+	}
+
+	if _, ok := failed["function.html"]; !ok {
+		t.Errorf("expected an error in function.html, got %v", diagnostics)
+	}
+
+	rendered, _ := os.ReadFile(filepath.Join(siteConfig.OutputPath, "pages/good.html"))
+	if string(rendered) != "3\n" {
+		t.Errorf("expected good.html to still be rendered, got %q", rendered)
+	}
+}
@@ -1,17 +1,17 @@
 package pipeline
 
 import (
+	"bytes"
 	"context"
 	"io"
-	"log"
-	"math"
 	"os"
 	"regexp"
-	"strconv"
 	"strings"
 	"sync"
 
 	"mettlach.codes/frizzy/config"
+	"mettlach.codes/frizzy/depgraph"
+	"mettlach.codes/frizzy/diagnostic"
 	"mettlach.codes/frizzy/file"
 	"mettlach.codes/frizzy/lexer"
 	"mettlach.codes/frizzy/parser"
@@ -20,85 +20,117 @@ import (
 )
 
 func TemplateCacheHandler(ctx context.Context, templateFile *os.File) <-chan error {
-	lexer := lexer.Lexer{}
-	tokChan, lexErrChan := lexer.Lex(templateFile, ctx)
-	nodeChan, parserErrChan := parser.Parse(tokChan, ctx)
+	fileCtx, cancel := context.WithCancel(ctx)
+	lexer := lexer.Lexer{TrimBlockLines: config.GetLoadedConfig().TrimBlockLines}
+	tokChan, lexErrChan := lexer.Lex(templateFile, fileCtx)
+	nodeChan, parserErrChan := parser.Parse(tokChan, fileCtx)
 
-	config := config.GetLoadedConfig()
-	templatePath := config.GetTemplatePath()
 	templateCache := parser.GetTemplateCache()
-	cacheKey := strings.TrimPrefix(templateFile.Name(), templatePath)
-	if cacheKey[0] == '/' {
-		cacheKey = cacheKey[1:]
-	}
+	cacheKey := templateCacheKey(templateFile.Name())
+	// drop the nodes from any earlier build so they aren't appended to
+	templateCache.Remove(cacheKey)
 
 	cacherErrs := renderer.CacheTemplateResults(nodeChan, templateCache, cacheKey)
-	return mergeIntoStandardErrs(ctx, templateFile.Name(), lexErrChan, parserErrChan, cacherErrs)
+	return mergeIntoDiagnostics(ctx, cancel, templateFile.Name(), lexErrChan, parserErrChan, cacherErrs)
 }
 
-func getNumPages(inputFile *os.File) (int, bool) {
-	var (
-		numPages int
-		ok       bool = true
-	)
-
-	paginationRegex := regexp.MustCompile(`[{{|{{:]\s*paginate\(("[^"]+"),\s*("[^"]+"),\s*(\d+)\)\s*}}`)
-	if bytes, err := io.ReadAll(inputFile); err != nil {
-		ok = false
-	} else if found := paginationRegex.FindSubmatch(bytes); found != nil {
-		contentPath := strings.ReplaceAll(string(found[1]), "\"", "")
-		if numPerPage, err := strconv.Atoi(string(found[3])); err != nil {
-			ok = false
-		} else {
-			contentPaths := file.GetContentPaths(contentPath)
-			numPages = int(math.Ceil(float64(len(contentPaths)) / float64(numPerPage)))
-		}
-	} else {
-		ok = false
+// templateCacheKey returns the path of templatePath relative to the
+// template directory
+func templateCacheKey(templatePath string) string {
+	config := config.GetLoadedConfig()
+	cacheKey := strings.TrimPrefix(templatePath, config.GetTemplatePath())
+	return strings.TrimPrefix(cacheKey, "/")
+}
+
+// getNumPages returns the number of pages a paginated file renders, it
+// is false if the file isn't paginated
+// The paginate call's content is evaluated so every export must have
+// been collected
+func getNumPages(ctx context.Context, inputPath string, fileContext *processor.Context, body []byte, numFrontMatterLines int) (int, bool, error) {
+	if !isPaginated(body) {
+		return 0, false, nil
+	}
+
+	parseCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lexer := lexer.Lexer{LineOffset: numFrontMatterLines, TrimBlockLines: config.GetLoadedConfig().TrimBlockLines}
+	tokChan, _ := lexer.Lex(bytes.NewReader(body), parseCtx)
+	nodeChan, parserErrChan := parser.Parse(tokChan, parseCtx)
+
+	nodes := []parser.TreeNode{}
+	for node := range nodeChan {
+		nodes = append(nodes, node)
+	}
+
+	// parse errors are reported when the file is processed
+	if err := <-parserErrChan; err != nil {
+		return 0, false, nil
 	}
 
-	return numPages, ok
+	nodeProcessor := processor.NewNodeProcessor(inputPath, fileContext.Copy(), nil, nil, nil, 1, 0)
+	return nodeProcessor.PageCount(nodes)
+}
+
+var paginateCallExp = regexp.MustCompile(`\bpaginate\s*\(`)
+
+// isPaginated reports whether body looks like it calls paginate
+func isPaginated(body []byte) bool {
+	return paginateCallExp.Match(body)
 }
 
-func FullPipelineHtmlRenderer(ctx context.Context, contentFile *os.File) <-chan error {
-	renderer := processAndRender
+func FullPipelineHtmlRenderer(ctx context.Context, contentFile *os.File, graph *depgraph.Graph) <-chan error {
+	renderer := func(ctx context.Context, inputPath string, fileContext *processor.Context, nodeChan <-chan parser.TreeNode, curPage, numPages int) (<-chan error, <-chan error) {
+		return processAndRender(ctx, graph, inputPath, fileContext, nodeChan, curPage, numPages)
+	}
 	return FullPipelineHandler(ctx, contentFile, renderer)
 }
 
-func FullPipelineNullRenderer(ctx context.Context, contentFile *os.File) <-chan error {
+func FullPipelineNullRenderer(ctx context.Context, contentFile *os.File, graph *depgraph.Graph) <-chan error {
 	renderer := renderer.RenderNullResults
 	return FullPipelineHandler(ctx, contentFile, renderer)
 }
 
-func FullPipelineHandler(ctx context.Context, contentFile *os.File, renderer func(context.Context, string, <-chan parser.TreeNode, int, int) (<-chan error, <-chan error)) <-chan error {
-	numPages, paginated := getNumPages(contentFile)
-	// Rewind the file so it can be lexed from the start
-	contentFile.Seek(0, 0)
-
+// FullPipelineHandler lexes, parses, processes and renders contentFile
+// Exports from other files are expected to have already been collected
+// with CollectExportsHandler
+func FullPipelineHandler(ctx context.Context, contentFile *os.File, renderer func(context.Context, string, *processor.Context, <-chan parser.TreeNode, int, int) (<-chan error, <-chan error)) <-chan error {
 	inputPath := contentFile.Name()
-	lexer := lexer.Lexer{}
-	tokChan, lexErrChan := lexer.Lex(contentFile, ctx)
-	nodeChan, parserErrChan := parser.Parse(tokChan, ctx)
+	fileCtx, cancel := context.WithCancel(ctx)
+	fileContext, body, numFrontMatterLines, err := readFileInput(contentFile)
+	if err != nil {
+		return mergeIntoDiagnostics(ctx, cancel, inputPath, errorChan(err))
+	}
+
+	numPages, paginated, err := getNumPages(fileCtx, inputPath, fileContext, body, numFrontMatterLines)
+	if err != nil {
+		return mergeIntoDiagnostics(ctx, cancel, inputPath, errorChan(err))
+	}
+
+	lexer := lexer.Lexer{LineOffset: numFrontMatterLines, TrimBlockLines: config.GetLoadedConfig().TrimBlockLines}
+	tokChan, lexErrChan := lexer.Lex(bytes.NewReader(body), fileCtx)
+	nodeChan, parserErrChan := parser.Parse(tokChan, fileCtx)
 
 	if paginated {
 		// fan out node chan to processors for each page
-		nodeChans := fanOutNodes(nodeChan, numPages)
+		nodeChans := fanOutNodes(fileCtx, nodeChan, numPages)
 		// processor and renderer chans for each page plus
 		// lexer and parser err chans
 		pagedErrChans := make([]<-chan error, 0, numPages*2+2)
 
 		for i, fannedNodeChan := range nodeChans {
 			curPage := i + 1
-			processorErrChan, rendererErrChan := renderer(ctx, inputPath, fannedNodeChan, curPage, numPages)
+			processorErrChan, rendererErrChan := renderer(fileCtx, inputPath, fileContext, fannedNodeChan, curPage, numPages)
 			pagedErrChans = append(pagedErrChans, processorErrChan, rendererErrChan)
 		}
 
 		pagedErrChans = append(pagedErrChans, lexErrChan, parserErrChan)
-		return mergeIntoStandardErrs(ctx, contentFile.Name(), pagedErrChans...)
+		return mergeIntoDiagnostics(ctx, cancel, contentFile.Name(), pagedErrChans...)
 	} else {
-		processorErrChan, rendererErrChan := renderer(ctx, inputPath, nodeChan, 0, 0)
-		return mergeIntoStandardErrs(
+		processorErrChan, rendererErrChan := renderer(fileCtx, inputPath, fileContext, nodeChan, 0, 0)
+		return mergeIntoDiagnostics(
 			ctx,
+			cancel,
 			contentFile.Name(),
 			lexErrChan,
 			parserErrChan,
@@ -108,40 +140,110 @@ func FullPipelineHandler(ctx context.Context, contentFile *os.File, renderer fun
 	}
 }
 
-func mergeIntoStandardErrs(ctx context.Context, filename string, errChans ...<-chan error) <-chan error {
+// CollectExportsHandler exports the front matter and top level assignments
+// of contentFile without rendering it
+func CollectExportsHandler(ctx context.Context, contentFile *os.File) <-chan error {
+	inputPath := contentFile.Name()
+	fileCtx, cancel := context.WithCancel(ctx)
+	fileContext, body, numFrontMatterLines, err := readFileInput(contentFile)
+	if err != nil {
+		return mergeIntoDiagnostics(ctx, cancel, inputPath, errorChan(err))
+	}
+
+	exportStore := processor.GetExportStore()
+	// start over so exports removed from the file since the last
+	// collection don't linger
+	exportStore.Remove(inputPath)
+	exportStore.InsertContext(inputPath, fileContext)
+
+	// the number of pages isn't known until every export is collected
+	curPage, numPages := 0, 0
+	if isPaginated(body) {
+		curPage = 1
+	}
+
+	// lex and parse errors are reported when the file is rendered
+	lexer := lexer.Lexer{LineOffset: numFrontMatterLines, TrimBlockLines: config.GetLoadedConfig().TrimBlockLines}
+	tokChan, _ := lexer.Lex(bytes.NewReader(body), fileCtx)
+	nodeChan, _ := parser.Parse(tokChan, fileCtx)
+
+	nodeProcessor := processor.NewNodeProcessor(inputPath, fileContext.Copy(), nil, nil, nil, curPage, numPages)
+	outputPath := processor.GetMarkdownOutputPath(inputPath, curPage)
+	nodeProcessor.ExportStore.Insert([]string{"_href"}, processor.StringResult(outputPath))
+	collectErrChan := nodeProcessor.Collect(nodeChan, fileCtx)
+
+	return mergeIntoDiagnostics(ctx, cancel, inputPath, collectErrChan)
+}
+
+// readFileInput reads inputFile and splits off its front matter
+// The front matter is returned as the context each processor for this
+// file starts with
+func readFileInput(inputFile *os.File) (*processor.Context, []byte, int, error) {
+	input, err := io.ReadAll(inputFile)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	frontMatter, body, numFrontMatterLines, err := file.SplitFrontMatter(input)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	return processor.NewContextFromMap(frontMatter), body, numFrontMatterLines, nil
+}
+
+// mergeIntoDiagnostics merges the error channels of the stages handling
+// filename into one channel of *diagnostic.Diagnostic
+// cancel stops the remaining stages once one of them reports an error
+// so they don't block waiting on each other
+func mergeIntoDiagnostics(ctx context.Context, cancel context.CancelFunc, filename string, errChans ...<-chan error) <-chan error {
 	wg := sync.WaitGroup{}
 	wg.Add(len(errChans))
 
 	errChan := make(chan error)
 
-	merge := func(idx int, ec <-chan error) {
+	merge := func(ec <-chan error) {
 		defer wg.Done()
 		for err := range ec {
 			if err == nil {
-				log.Println(ec)
+				continue
+			}
+
+			fileDiagnostic := diagnostic.FromError(filename, err)
+			if fileDiagnostic.Severity == diagnostic.Error {
+				cancel()
 			}
-			stdErr := &StandardError{Filename: filename, Message: err.Error()}
+
 			select {
-			case errChan <- stdErr:
+			case errChan <- fileDiagnostic:
 			case <-ctx.Done():
 				return
 			}
 		}
 	}
 
-	for i, ec := range errChans {
-		go merge(i, ec)
+	for _, ec := range errChans {
+		go merge(ec)
 	}
 
 	go func() {
 		wg.Wait()
+		cancel()
 		close(errChan)
 	}()
 
 	return errChan
 }
 
-func fanOutNodes(nodeChan <-chan parser.TreeNode, numPages int) []chan parser.TreeNode {
+// errorChan returns a closed channel holding only err
+func errorChan(err error) <-chan error {
+	errChan := make(chan error, 1)
+	errChan <- err
+	close(errChan)
+	return errChan
+}
+
+func fanOutNodes(ctx context.Context, nodeChan <-chan parser.TreeNode, numPages int) []chan parser.TreeNode {
 	nodeChanFan := make([]chan parser.TreeNode, numPages)
 	for i := range nodeChanFan {
 		nodeChanFan[i] = make(chan parser.TreeNode, 10)
@@ -156,7 +258,11 @@ func fanOutNodes(nodeChan <-chan parser.TreeNode, numPages int) []chan parser.Tr
 
 		for node := range nodeChan {
 			for _, fanChan := range nodeChanFan {
-				fanChan <- node
+				select {
+				case fanChan <- node:
+				case <-ctx.Done():
+					return
+				}
 			}
 		}
 	}()
@@ -164,11 +270,19 @@ func fanOutNodes(nodeChan <-chan parser.TreeNode, numPages int) []chan parser.Tr
 	return nodeChanFan
 }
 
-func processAndRender(ctx context.Context, inputPath string, nodeChan <-chan parser.TreeNode, curPage, numPages int) (<-chan error, <-chan error) {
-	nodeProcessor := processor.NewNodeProcessor(inputPath, nil, nil, nil, nil, curPage, numPages)
+// processAndRender renders one page of inputPath, tracking what it is
+// rendered from in graph unless graph is nil
+func processAndRender(ctx context.Context, graph *depgraph.Graph, inputPath string, fileContext *processor.Context, nodeChan <-chan parser.TreeNode, curPage, numPages int) (<-chan error, <-chan error) {
+	nodeProcessor := processor.NewNodeProcessor(inputPath, fileContext.Copy(), nil, nil, nil, curPage, numPages)
 
 	outputPath := processor.GetMarkdownOutputPath(inputPath, curPage)
 	nodeProcessor.ExportStore.Insert([]string{"_href"}, processor.StringResult(outputPath))
+	if graph != nil {
+		recorder := depgraph.NewRecorder(inputPath)
+		nodeProcessor.Dependencies = recorder
+		graph.Track(outputPath, recorder)
+	}
+
 	processorChan, processorErrChan := nodeProcessor.Process(nodeChan, ctx)
 	resultChan := processor.PostProcessMarkdown(inputPath, processorChan)
 	rendererErrChan := renderer.RenderHtmlResults(resultChan, outputPath)
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+
+	"mettlach.codes/frizzy/diagnostic"
 )
 
 func WalkFiles(inputPath string) (<-chan string, <-chan error) {
@@ -37,12 +39,40 @@ func WalkFiles(inputPath string) (<-chan string, <-chan error) {
 	return pathChan, errChan
 }
 
+// WalkAllFiles walks each of inputPaths in order and sends the path
+// of every file found on a single channel
+func WalkAllFiles(inputPaths ...string) <-chan string {
+	pathChan := make(chan string)
+
+	go func() {
+		defer close(pathChan)
+
+		for _, inputPath := range inputPaths {
+			walkPathChan, walkErrChan := WalkFiles(inputPath)
+			for path := range walkPathChan {
+				pathChan <- path
+			}
+
+			if err := <-walkErrChan; err != nil {
+				log.Printf("    pipeline error: failed to walk %s, %s\n", inputPath, err)
+			}
+		}
+	}()
+
+	return pathChan
+}
+
+// RunPipeline calls handler on every file in pathChan and waits for all
+// of them to finish
+// Files are handled independently so one failing doesn't stop the others,
+// every problem reported is returned as diagnostic.Diagnostics
 func RunPipeline(pathChan <-chan string, handler func(context.Context, *os.File) <-chan error) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	errChans := []<-chan error{}
 	files := make([]*os.File, len(pathChan))
+	diagnostics := diagnostic.Diagnostics{}
 
 	defer func() {
 		for _, f := range files {
@@ -57,7 +87,7 @@ func RunPipeline(pathChan <-chan string, handler func(context.Context, *os.File)
 		files = append(files, f)
 
 		if err != nil {
-			log.Printf("    pipeline error: failed to open %s, %s\n", inputPath, err)
+			diagnostics = append(diagnostics, diagnostic.FromError(inputPath, err))
 			continue
 		}
 
@@ -68,12 +98,15 @@ func RunPipeline(pathChan <-chan string, handler func(context.Context, *os.File)
 
 	for err := range errChan {
 		if err != nil {
-			log.Println(err)
-			return err
+			diagnostics = append(diagnostics, diagnostic.FromError("", err))
 		}
 	}
 
-	return nil
+	if len(diagnostics) == 0 {
+		return nil
+	}
+
+	return diagnostics
 }
 
 func mergeErrChans(ctx context.Context, errChans []<-chan error) <-chan error {
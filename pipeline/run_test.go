@@ -15,14 +15,6 @@ func TestMain(m *testing.M) {
 
 func BenchmarkTestRun(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		templatePathChan, _ := WalkFiles(testConfig.GetTemplatePath())
-		contentPathChan, _ := WalkFiles(testConfig.GetContentPath())
-		pagesPathChan, _ := WalkFiles(testConfig.GetPagesPath())
-
-		RunPipeline(templatePathChan, TemplateCacheHandler)
-
-		RunPipeline(contentPathChan, FullPipelineNullRenderer)
-
-		RunPipeline(pagesPathChan, FullPipelineNullRenderer)
+		Build(testConfig, FullPipelineNullRenderer)
 	}
 }
@@ -1,9 +1,6 @@
 package processor
 
-import (
-	"fmt"
-	"strconv"
-)
+import "strconv"
 
 // BoolResult represents a boolean processor result
 type BoolResult bool
@@ -30,6 +27,8 @@ func convertToBool(result Result) (bool, bool) {
 		return false, false
 	case IntResult:
 		return typedResult != 0, true
+	case FloatResult:
+		return typedResult != 0, true
 	default:
 		return false, false
 	}
@@ -41,7 +40,7 @@ func (receiver BoolResult) EqualTo(right Result) (Result, error) {
 	if rightInt, ok := convertToBool(right); ok {
 		return BoolResult(bool(receiver) == rightInt), nil
 	}
-	return nil, fmt.Errorf("Cannot determine %T == %T", receiver, right)
+	return nil, newBinaryError("==", receiver, right)
 }
 
 // NotEqualTo checks if the provided result is logically equal to
@@ -50,7 +49,7 @@ func (receiver BoolResult) NotEqualTo(right Result) (Result, error) {
 	if rightInt, ok := convertToBool(right); ok {
 		return BoolResult(bool(receiver) != rightInt), nil
 	}
-	return nil, fmt.Errorf("Cannot determine %T != %T", receiver, right)
+	return nil, newBinaryError("!=", receiver, right)
 }
 
 // LogicalAnd determines if left and right are both logically true
@@ -58,7 +57,7 @@ func (receiver BoolResult) LogicalAnd(right Result) (Result, error) {
 	if rightInt, ok := convertToBool(right); ok {
 		return BoolResult(bool(receiver) && rightInt), nil
 	}
-	return nil, fmt.Errorf("Cannot determine %T && %T", receiver, right)
+	return nil, newBinaryError("&&", receiver, right)
 }
 
 // LogicalOr determines if left and right are both logically true
@@ -66,7 +65,7 @@ func (receiver BoolResult) LogicalOr(right Result) (Result, error) {
 	if rightInt, ok := convertToBool(right); ok {
 		return BoolResult(bool(receiver) || rightInt), nil
 	}
-	return nil, fmt.Errorf("Cannot determine %T || %T", receiver, right)
+	return nil, newBinaryError("||", receiver, right)
 }
 
 // Not returns the inverse of the receiver
@@ -0,0 +1,388 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SortRaw returns the values of a container sorted by the value at the
+// key given as its second argument, or by the values themselves if the
+// key is "" or not given, in "asc" or "desc" order
+// Values without the key are put last
+// e.g. sort(posts, "date", "desc") lists the latest posts first
+func SortRaw(args ...Result) (Result, error) {
+	if err := expectArgs("sort", args, 1, 3); err != nil {
+		return nil, err
+	}
+
+	items, err := collectionArg("sort", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	strs, err := stringArgs("sort", args[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	key, order := "", "asc"
+	if len(strs) > 0 {
+		key = strs[0]
+	}
+	if len(strs) > 1 {
+		order = strs[1]
+	}
+
+	if order != "asc" && order != "desc" {
+		return nil, fmt.Errorf("`sort` expects order to be \"asc\" or \"desc\", got %q", order)
+	}
+
+	values := make([]Result, len(items))
+	for i, item := range items {
+		values[i], _ = itemValue(item, key)
+	}
+
+	sorted := make([]int, len(items))
+	for i := range sorted {
+		sorted[i] = i
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		left, right := values[sorted[i]], values[sorted[j]]
+		if left == nil || right == nil {
+			return right == nil && left != nil
+		}
+
+		if order == "desc" {
+			return compareResults(left, right) > 0
+		}
+		return compareResults(left, right) < 0
+	})
+
+	sortedItems := make([]*ContextNode, len(items))
+	for i, index := range sorted {
+		sortedItems[i] = items[index]
+	}
+
+	return newList(sortedItems), nil
+}
+
+// WhereRaw returns the values of a container whose value at the key
+// given as its second argument equals its third
+// Values without the key match false, 0 and ""
+// e.g. where(posts, "draft", false) leaves out drafts
+func WhereRaw(args ...Result) (Result, error) {
+	if err := expectArgs("where", args, 3, 3); err != nil {
+		return nil, err
+	}
+
+	items, err := collectionArg("where", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := stringArg("where", args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []*ContextNode{}
+	for _, item := range items {
+		if value, ok := itemValue(item, key); ok && equalResults(value, args[2]) {
+			matches = append(matches, item)
+		} else if !ok && !isTruthy(args[2]) {
+			matches = append(matches, item)
+		}
+	}
+
+	return newList(matches), nil
+}
+
+// FirstRaw returns the first n values of a container
+func FirstRaw(args ...Result) (Result, error) {
+	items, n, err := collectionAndCount("first", args)
+	if err != nil {
+		return nil, err
+	}
+
+	return newList(items[:minInt(n, len(items))]), nil
+}
+
+// LastRaw returns the last n values of a container
+func LastRaw(args ...Result) (Result, error) {
+	items, n, err := collectionAndCount("last", args)
+	if err != nil {
+		return nil, err
+	}
+
+	return newList(items[len(items)-minInt(n, len(items)):]), nil
+}
+
+// ReverseRaw returns the values of a container in reverse order
+func ReverseRaw(args ...Result) (Result, error) {
+	if err := expectArgs("reverse", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	items, err := collectionArg("reverse", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	reversed := make([]*ContextNode, len(items))
+	for i, item := range items {
+		reversed[len(items)-1-i] = item
+	}
+
+	return newList(reversed), nil
+}
+
+// GroupByRaw returns a map of the values of a container keyed by their
+// value at the key given as its second argument, each holding a list
+// of the values in the group in order
+// Values without the key are grouped under ""
+// e.g. {{for category, posts in groupBy("posts", "category")}}
+func GroupByRaw(args ...Result) (Result, error) {
+	if err := expectArgs("groupBy", args, 2, 2); err != nil {
+		return nil, err
+	}
+
+	items, err := collectionArg("groupBy", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := stringArg("groupBy", args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string][]*ContextNode{}
+	for _, item := range items {
+		group := ""
+		if value, ok := itemValue(item, key); ok {
+			if _, isContainer := value.(ContainerResult); isContainer {
+				return nil, fmt.Errorf("`groupBy` expects the values at %q not to be containers", key)
+			}
+			group = value.String()
+		}
+		groups[group] = append(groups[group], item)
+	}
+
+	context := &Context{}
+	for group, groupItems := range groups {
+		(*context)[group] = &ContextNode{child: newList(groupItems).context}
+	}
+
+	return ContainerResult{context}, nil
+}
+
+// UniqRaw returns the values of a container with any repeated values
+// left out
+func UniqRaw(args ...Result) (Result, error) {
+	if err := expectArgs("uniq", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	items, err := collectionArg("uniq", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	unique := []*ContextNode{}
+	for _, item := range items {
+		encoded, err := json.Marshal(jsonValue(nodeResult(item)))
+		if err != nil {
+			return nil, fmt.Errorf("`uniq` could not compare values: %s", err)
+		}
+
+		if !seen[string(encoded)] {
+			seen[string(encoded)] = true
+			unique = append(unique, item)
+		}
+	}
+
+	return newList(unique), nil
+}
+
+// LenRaw returns the number of values in a container or the number of
+// characters in a string
+// Unlike the collection functions a string isn't read as a content path
+func LenRaw(args ...Result) (Result, error) {
+	if err := expectArgs("len", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	switch arg := args[0].(type) {
+	case ContainerResult:
+		return IntResult(len(containerKeys(arg))), nil
+	case StringResult, SafeResult:
+		return IntResult(utf8.RuneCountInString(arg.String())), nil
+	}
+
+	return nil, fmt.Errorf("`len` expects a container or string, got %s", typeName(args[0]))
+}
+
+// contentClosure lets f be passed a content path as its first argument,
+// like the input of a for loop, in place of a container of the
+// content files' contexts
+func (receiver *NodeProcessor) contentClosure(f func(...Result) (Result, error)) func(...Result) (Result, error) {
+	return func(args ...Result) (Result, error) {
+		if len(args) > 0 {
+			if contentPath, ok := args[0].(StringResult); ok {
+				args = append([]Result{receiver.getContentList(string(contentPath))}, args[1:]...)
+			}
+		}
+		return f(args...)
+	}
+}
+
+// getContentList returns a list of the contexts of the files in the
+// content path subpath
+func (receiver *NodeProcessor) getContentList(subpath string) ContainerResult {
+	contentPaths := receiver.getPaths(subpath)
+	items := make([]*ContextNode, len(contentPaths))
+	for i, contentPath := range contentPaths {
+		items[i] = &ContextNode{child: receiver.doGetContext(contentPath)}
+	}
+
+	return newList(items)
+}
+
+// collectionArg returns the values of arg in key order
+func collectionArg(funcName string, arg Result) ([]*ContextNode, error) {
+	container, ok := arg.(ContainerResult)
+	if !ok {
+		return nil, fmt.Errorf("`%s` expects a container or content path, got %s", funcName, typeName(arg))
+	}
+
+	keys := containerKeys(container)
+	items := make([]*ContextNode, len(keys))
+	for i, key := range keys {
+		items[i] = (*container.context)[key]
+	}
+
+	return items, nil
+}
+
+// collectionAndCount returns the values and the number of values to
+// take for first and last
+func collectionAndCount(funcName string, args []Result) ([]*ContextNode, int, error) {
+	if err := expectArgs(funcName, args, 2, 2); err != nil {
+		return nil, 0, err
+	}
+
+	items, err := collectionArg(funcName, args[0])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	n, ok := args[1].(IntResult)
+	if !ok || n < 0 {
+		return nil, 0, fmt.Errorf("`%s` expects the number of values to be an int of at least 0, got %s", funcName, args[1])
+	}
+
+	return items, int(n), nil
+}
+
+// newList returns a container holding items keyed by their index
+func newList(items []*ContextNode) ContainerResult {
+	context := make(Context, len(items))
+	for i, item := range items {
+		context[strconv.Itoa(i)] = item
+	}
+
+	return ContainerResult{&context}
+}
+
+// itemValue returns the value at the dot separated key of item, or item
+// itself if key is ""
+func itemValue(item *ContextNode, key string) (Result, bool) {
+	if key == "" {
+		return nodeResult(item), true
+	}
+
+	if !item.HasContext() {
+		return nil, false
+	}
+
+	node, ok := item.child.AtNested(strings.Split(key, "."))
+	if !ok {
+		return nil, false
+	}
+
+	return nodeResult(node), true
+}
+
+// compareResults returns a negative number if left sorts before right,
+// a positive number if it sorts after and 0 if neither does
+// Numbers are compared by value, strings alphabetically and false
+// before true. Results of different types are ordered by type name
+func compareResults(left, right Result) int {
+	leftNum, leftIsNum := numberValue(left)
+	rightNum, rightIsNum := numberValue(right)
+	if leftIsNum && rightIsNum {
+		switch {
+		case leftNum < rightNum:
+			return -1
+		case leftNum > rightNum:
+			return 1
+		}
+		return 0
+	}
+
+	leftBool, leftIsBool := left.(BoolResult)
+	rightBool, rightIsBool := right.(BoolResult)
+	if leftIsBool && rightIsBool {
+		switch {
+		case leftBool == rightBool:
+			return 0
+		case bool(rightBool):
+			return -1
+		}
+		return 1
+	}
+
+	leftStr, leftIsStr := convertToString(left)
+	rightStr, rightIsStr := convertToString(right)
+	if leftIsStr && rightIsStr {
+		return strings.Compare(leftStr, rightStr)
+	}
+
+	return strings.Compare(typeName(left), typeName(right))
+}
+
+// equalResults reports whether left and right are equal like ==
+// Numbers and strings holding the same number are equal, bools only
+// equal bools and containers are never equal
+func equalResults(left, right Result) bool {
+	_, leftIsContainer := left.(ContainerResult)
+	_, rightIsContainer := right.(ContainerResult)
+	if leftIsContainer || rightIsContainer {
+		return false
+	}
+
+	_, leftIsBool := left.(BoolResult)
+	_, rightIsBool := right.(BoolResult)
+	if leftIsBool != rightIsBool {
+		return false
+	}
+
+	return compareResults(left, right) == 0
+}
+
+func numberValue(result Result) (float64, bool) {
+	switch typedResult := result.(type) {
+	case IntResult:
+		return float64(typedResult), true
+	case FloatResult:
+		return float64(typedResult), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,270 @@
+package processor
+
+import (
+	"testing"
+)
+
+func TestCollectionFunctions(t *testing.T) {
+	posts := listResult(
+		mapResult("title", StringResult("b"), "date", IntResult(2), "draft", BoolResult(false)),
+		mapResult("title", StringResult("c"), "date", IntResult(3), "draft", BoolResult(true)),
+		mapResult("title", StringResult("a"), "date", IntResult(1)),
+		mapResult("title", StringResult("d")),
+	)
+
+	var tests = []struct {
+		function func(...Result) (Result, error)
+		args     []Result
+		expected []string
+	}{
+		{SortRaw, []Result{listResult(IntResult(3), FloatResult(1.5), IntResult(2))}, []string{"1.5", "2", "3"}},
+		{SortRaw, []Result{listResult(StringResult("b"), StringResult("a")), StringResult(""), StringResult("desc")}, []string{"b", "a"}},
+		{SortRaw, []Result{listResult(BoolResult(true), BoolResult(false))}, []string{"false", "true"}},
+		{SortRaw, []Result{posts, StringResult("date")}, []string{"a", "b", "c", "d"}},
+		{SortRaw, []Result{posts, StringResult("date"), StringResult("desc")}, []string{"c", "b", "a", "d"}},
+		{WhereRaw, []Result{posts, StringResult("draft"), BoolResult(false)}, []string{"b", "a", "d"}},
+		{WhereRaw, []Result{posts, StringResult("draft"), BoolResult(true)}, []string{"c"}},
+		{WhereRaw, []Result{posts, StringResult("date"), StringResult("2")}, []string{"b"}},
+		{FirstRaw, []Result{posts, IntResult(2)}, []string{"b", "c"}},
+		{FirstRaw, []Result{posts, IntResult(10)}, []string{"b", "c", "a", "d"}},
+		{LastRaw, []Result{posts, IntResult(1)}, []string{"d"}},
+		{LastRaw, []Result{posts, IntResult(0)}, []string{}},
+		{ReverseRaw, []Result{posts}, []string{"d", "a", "c", "b"}},
+		{UniqRaw, []Result{listResult(IntResult(1), StringResult("1"), IntResult(1), StringResult("a"))}, []string{"1", "1", "a"}},
+		{UniqRaw, []Result{listResult(posts, posts)}, []string{"container"}},
+	}
+
+	for i, test := range tests {
+		actual, err := test.function(test.args...)
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+			continue
+		}
+
+		values := listValues(actual.(ContainerResult))
+		if len(values) != len(test.expected) {
+			t.Errorf("%d: expected %v, got %v", i, test.expected, values)
+			continue
+		}
+
+		for j := range values {
+			if values[j] != test.expected[j] {
+				t.Errorf("%d: expected %v, got %v", i, test.expected, values)
+				break
+			}
+		}
+	}
+}
+
+func TestGroupByRaw(t *testing.T) {
+	posts := listResult(
+		mapResult("title", StringResult("a"), "category", StringResult("go")),
+		mapResult("title", StringResult("b")),
+		mapResult("title", StringResult("c"), "category", StringResult("go")),
+	)
+
+	result, err := GroupByRaw(posts, StringResult("category"))
+	if err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	var tests = []struct {
+		group    string
+		expected []string
+	}{
+		{"go", []string{"a", "c"}},
+		{"", []string{"b"}},
+	}
+
+	groups := result.(ContainerResult).context
+	if len(*groups) != len(tests) {
+		t.Errorf("expected %d groups, got %d", len(tests), len(*groups))
+	}
+
+	for i, test := range tests {
+		node, ok := (*groups)[test.group]
+		if !ok {
+			t.Errorf("%d: expected group %q", i, test.group)
+			continue
+		}
+
+		values := listValues(ContainerResult{node.child})
+		if len(values) != len(test.expected) || values[0] != test.expected[0] {
+			t.Errorf("%d: expected %v, got %v", i, test.expected, values)
+		}
+	}
+}
+
+func TestLenRaw(t *testing.T) {
+	var tests = []struct {
+		arg      Result
+		expected IntResult
+	}{
+		{listResult(), 0},
+		{listResult(IntResult(1), IntResult(2)), 2},
+		{mapResult("a", IntResult(1)), 1},
+		{StringResult("hello"), 5},
+		{StringResult("héllo"), 5},
+		{StringResult(""), 0},
+	}
+
+	for i, test := range tests {
+		actual, err := LenRaw(test.arg)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %d, got %s", i, test.expected, actual)
+		}
+	}
+}
+
+func TestCollectionFunctionsRejectInvalidArgs(t *testing.T) {
+	var tests = []struct {
+		function func(...Result) (Result, error)
+		args     []Result
+		expected string
+	}{
+		{SortRaw, []Result{}, "`sort` expects 1 to 3 args, got 0"},
+		{SortRaw, []Result{IntResult(1)}, "`sort` expects a container or content path, got int"},
+		{SortRaw, []Result{listResult(), StringResult(""), StringResult("up")}, "`sort` expects order to be \"asc\" or \"desc\", got \"up\""},
+		{WhereRaw, []Result{listResult(), StringResult("draft")}, "`where` expects 3 args, got 2"},
+		{FirstRaw, []Result{listResult(), StringResult("2")}, "`first` expects the number of values to be an int of at least 0, got 2"},
+		{LastRaw, []Result{listResult(), IntResult(-1)}, "`last` expects the number of values to be an int of at least 0, got -1"},
+		{ReverseRaw, []Result{BoolResult(true)}, "`reverse` expects a container or content path, got bool"},
+		{GroupByRaw, []Result{listResult(mapResult("tags", listResult())), StringResult("tags")}, "`groupBy` expects the values at \"tags\" not to be containers"},
+		{LenRaw, []Result{listResult(), listResult()}, "`len` expects one argument, got 2"},
+		{LenRaw, []Result{IntResult(1)}, "`len` expects a container or string, got int"},
+	}
+
+	for i, test := range tests {
+		_, err := test.function(test.args...)
+
+		if err == nil {
+			t.Errorf("%d: expected error %q, got nil", i, test.expected)
+		} else if err.Error() != test.expected {
+			t.Errorf("%d: expected error %q, got %q", i, test.expected, err)
+		}
+	}
+}
+
+func TestCollectionFunctionsInTemplates(t *testing.T) {
+	posts := `{{posts = [{"title": "b", "date": 2, "cat": "go"}, {"title": "c", "date": 3, "draft": true}, {"title": "a", "date": 1, "cat": "go"}]}}`
+
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{for p in first(sort(posts, "date", "desc"), 2)}}{{: p.title}}{{end}}`, "cb"},
+		{`{{for p in sort(where(posts, "draft", false), "title")}}{{: p.title}}{{end}}`, "ab"},
+		{`{{for p in reverse(posts)}}{{: loop.index}}{{: p.title}}{{end}}`, "0a1c2b"},
+		{`{{for cat, ps in groupBy(posts, "cat")}}[{{: cat}}:{{for p in ps}}{{: p.title}}{{end}}]{{end}}`, "[:c][go:ba]"},
+		{`{{: len(posts)}} {{: posts | last(1) | len}}`, "3 1"},
+		{`{{: len("hello")}} {{: posts[0].title | len}}`, "5 1"},
+		{`{{: [3, 1, 2, 1] | uniq | sort | join(",")}}`, "1,2,3"},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, posts+test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestPaginateRawWithContainer(t *testing.T) {
+	cacheTemplate(t, "collection/paginated.html", `{{: curPage}}/{{: numPages}}{{for item in content}} {{: item}}{{end}}`)
+
+	items := listResult(StringResult("a"), StringResult("b"), StringResult("c"))
+
+	var tests = []struct {
+		curPage  int
+		expected string
+	}{
+		{1, "1/2 a b"},
+		{2, "2/2 c"},
+		{3, "3/2"},
+	}
+
+	for i, test := range tests {
+		actual, err := PaginateRaw(IntResult(test.curPage), items, StringResult("collection/paginated.html"), IntResult(2))
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual.String() != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual.String())
+		}
+	}
+}
+
+func TestPageCount(t *testing.T) {
+	context := &Context{
+		"posts": &ContextNode{child: listResult(
+			mapResult("draft", BoolResult(true)),
+			mapResult("draft", BoolResult(false)),
+			mapResult("draft", BoolResult(false)),
+		).context},
+	}
+
+	var tests = []struct {
+		text      string
+		numPages  int
+		paginated bool
+	}{
+		{`{{: 1 + 1}}`, 0, false},
+		{`{{: paginate(posts, "post.html", 2)}}`, 2, true},
+		{`{{: paginate(posts, "post.html", 3)}}`, 1, true},
+		{`{{if true}}{{: paginate(where(posts, "draft", false), "post.html", 1)}}{{end}}`, 2, true},
+	}
+
+	for i, test := range tests {
+		processor := NewNodeProcessor("", context, nil, &TestExportStore{}, nil, 1, 0)
+		numPages, paginated, err := processor.PageCount(parseText(t, test.text))
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if numPages != test.numPages || paginated != test.paginated {
+			t.Errorf("%d: expected %d, %t, got %d, %t", i, test.numPages, test.paginated, numPages, paginated)
+		}
+	}
+
+	processor := NewNodeProcessor("", context, nil, &TestExportStore{}, nil, 1, 0)
+	_, _, err := processor.PageCount(parseText(t, `{{: paginate(posts, "post.html", 0)}}`))
+	if err == nil {
+		t.Errorf("expected an error for 0 items per page")
+	}
+}
+
+// mapResult returns a container holding the key value pairs in pairs
+func mapResult(pairs ...interface{}) ContainerResult {
+	context := &Context{}
+	for i := 0; i < len(pairs); i += 2 {
+		key, value := pairs[i].(string), pairs[i+1].(Result)
+		if container, ok := value.(ContainerResult); ok {
+			(*context)[key] = &ContextNode{child: container.context}
+		} else {
+			(*context)[key] = &ContextNode{result: value}
+		}
+	}
+	return ContainerResult{context}
+}
+
+// listValues returns the values of a list, or the title of each value
+// that is a map
+func listValues(list ContainerResult) []string {
+	values := []string{}
+	for _, key := range containerKeys(list) {
+		node := (*list.context)[key]
+		if !node.HasContext() {
+			values = append(values, node.result.String())
+		} else if title, ok := (*node.child)["title"]; ok {
+			values = append(values, title.result.String())
+		} else {
+			values = append(values, "container")
+		}
+	}
+	return values
+}
@@ -2,6 +2,7 @@ package processor
 
 import (
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -51,17 +52,48 @@ func (receiver *Context) Merge(other *Context) *Context {
 	return merged
 }
 
+// Copy returns a deep copy of receiver so that inserting into the
+// copy does not change receiver
+func (receiver *Context) Copy() *Context {
+	copied := make(Context, len(*receiver))
+	for k, v := range *receiver {
+		node := &ContextNode{result: v.result}
+		if v.child != nil {
+			node.child = v.child.Copy()
+		}
+		copied[k] = node
+	}
+	return &copied
+}
+
 // Keys returns the sorted keys of receiver
+// Integer keys like list indices are sorted numerically and come
+// before all other keys
 func (receiver *Context) Keys() []string {
 	keys := make([]string, 0, len(*receiver))
 	for key := range *receiver {
 		keys = append(keys, key)
 	}
 
-	sort.Strings(keys)
+	sort.Slice(keys, func(i, j int) bool {
+		return keyLess(keys[i], keys[j])
+	})
 	return keys
 }
 
+func keyLess(a, b string) bool {
+	aInt, aErr := strconv.Atoi(a)
+	bInt, bErr := strconv.Atoi(b)
+
+	if aErr == nil && bErr == nil {
+		return aInt < bInt
+	} else if aErr == nil || bErr == nil {
+		return aErr == nil
+	}
+
+	return a < b
+}
+
 // Values converts a nested context into an array of contexts
 // reducing its level by one
 func (receiver *Context) Values() []*Context {
@@ -96,22 +128,31 @@ func (receiver *Context) AtNested(keys []string) (*ContextNode, bool) {
 	return current, true
 }
 
-// Insert iterates through keys, adding or looking up nested
-// context levels, then inserting the result at the last key
+// Insert iterates through keys, adding or copying nested context
+// levels, then inserting the result at the last key
+// Nested levels can be shared with other contexts, like the exports of
+// other files, so only receiver itself is changed
 func (receiver *Context) Insert(keys []string, value Result) {
-	current := &ContextNode{child: receiver}
-	for _, key := range keys {
-		if at, ok := current.At(key); ok {
-			current = at
-		} else {
-			next := &ContextNode{}
-			if current.child == nil {
-				current.child = &Context{key: next}
-			} else {
-				(*current.child)[key] = next
-			}
-			current = next
+	if len(keys) == 0 {
+		return
+	}
+
+	level := receiver
+	for _, key := range keys[:len(keys)-1] {
+		next := &Context{}
+		if at, ok := (*level)[key]; ok && at.HasContext() {
+			next = at.child.Merge(next)
 		}
+		(*level)[key] = &ContextNode{child: next}
+		level = next
+	}
+
+	// containers are stored as nested contexts so their keys can be
+	// looked up through the keys they're inserted at
+	node := &ContextNode{result: value}
+	if container, ok := value.(ContainerResult); ok && container.context != nil {
+		node = &ContextNode{child: container.context}
 	}
-	current.result = value
+
+	(*level)[keys[len(keys)-1]] = node
 }
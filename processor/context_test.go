@@ -122,3 +122,57 @@ func TestContextAtNestedReturnsUnnestedNode(t *testing.T) {
 		t.Errorf("expected nested result to equal %q, got %q", expectedStr, at.result)
 	}
 }
+
+func TestCopyDoesNotShareNodes(t *testing.T) {
+	context := &Context{}
+	context.Insert([]string{"post", "title"}, StringResult("original"))
+
+	copied := context.Copy()
+	copied.Insert([]string{"post", "title"}, StringResult("changed"))
+
+	if title, _ := context.AtNested([]string{"post", "title"}); title.result != StringResult("original") {
+		t.Errorf("expected original context to be unchanged, got %q", title.result)
+	}
+
+	if title, _ := copied.AtNested([]string{"post", "title"}); title.result != StringResult("changed") {
+		t.Errorf("expected copied context to be changed, got %q", title.result)
+	}
+}
+
+func TestInsertContainerNestsItsContext(t *testing.T) {
+	child := &Context{}
+	child.Insert([]string{"title"}, StringResult("nested"))
+
+	context := &Context{}
+	context.Insert([]string{"post"}, ContainerResult{child})
+
+	node, ok := context.At("post.title")
+	if !ok || node.result != StringResult("nested") {
+		t.Errorf("expected post.title to be looked up through the container, got %v", node)
+	}
+
+	if post, _ := context.At("post"); post.HasResult() {
+		t.Errorf("expected a container to be stored as a nested context, not a result")
+	}
+}
+
+func TestInsertDoesNotChangeSharedLevels(t *testing.T) {
+	shared := &Context{}
+	shared.Insert([]string{"meta", "title"}, StringResult("original"))
+
+	context := &Context{}
+	context.Insert([]string{"post"}, ContainerResult{shared})
+	context.Insert([]string{"post", "meta", "title"}, StringResult("changed"))
+	context.Insert([]string{"post", "draft"}, BoolResult(true))
+
+	if title, _ := shared.At("meta.title"); title.result != StringResult("original") {
+		t.Errorf("expected shared context to be unchanged, got %q", title.result)
+	}
+	if _, ok := shared.At("draft"); ok {
+		t.Errorf("expected no key to be added to the shared context")
+	}
+
+	if title, _ := context.At("post.meta.title"); title.result != StringResult("changed") {
+		t.Errorf("expected context to be changed, got %q", title.result)
+	}
+}
@@ -0,0 +1,34 @@
+package processor
+
+// DependencyRecorder is told about every input a NodeProcessor reads
+// while rendering a file so the output can be rebuilt when one of
+// those inputs changes
+type DependencyRecorder interface {
+	// RecordTemplate is called with each template path fetched from
+	// the template cache
+	RecordTemplate(templatePath string)
+	// RecordContentPath is called with each content subpath whose
+	// files are listed
+	RecordContentPath(subpath string)
+	// RecordExport is called with the path of each file whose exports
+	// are read
+	RecordExport(filePath string)
+}
+
+func (receiver *NodeProcessor) recordTemplate(templatePath string) {
+	if receiver.Dependencies != nil {
+		receiver.Dependencies.RecordTemplate(templatePath)
+	}
+}
+
+func (receiver *NodeProcessor) recordContentPath(subpath string) {
+	if receiver.Dependencies != nil {
+		receiver.Dependencies.RecordContentPath(subpath)
+	}
+}
+
+func (receiver *NodeProcessor) recordExport(filePath string) {
+	if receiver.Dependencies != nil {
+		receiver.Dependencies.RecordExport(filePath)
+	}
+}
@@ -52,9 +52,26 @@ func (receiver *ExportFileStore) GetNamespace() string {
 	return receiver.Filepath
 }
 
+// ReadOnlyExportStore is an ExportFileStore that doesn't export
+// assignments, for files like partials whose variables shouldn't be
+// shared
+type ReadOnlyExportStore struct {
+	ExportFileStore
+}
+
+func NewReadOnlyExportStore(filepath string) *ReadOnlyExportStore {
+	return &ReadOnlyExportStore{ExportFileStore{Filepath: filepath}}
+}
+
+// Insert does nothing as nothing is exported
+func (receiver *ReadOnlyExportStore) Insert(contextKeys []string, value Result) {}
+
 // ExportStore is a singleton to read and write export vars
 type ExportStore struct {
 	exports map[string]*Context
+	// frozen stores ignore Insert so files being rendered can read
+	// the exports collected before rendering without them changing
+	frozen bool
 }
 
 var once sync.Once
@@ -76,18 +93,74 @@ var mut sync.Mutex
 
 // Insert inserts the key, value pair of the export context
 // represented by filename
+// It does nothing while receiver is frozen
 func (receiver *ExportStore) Insert(filename string, contextKeys []string, value Result) {
 	mut.Lock()
 	defer mut.Unlock()
 
-	if _, ok := receiver.exports[filename]; !ok {
-		receiver.exports[filename] = &Context{}
+	if receiver.frozen {
+		return
+	}
+
+	exports := receiver.copyExports(filename)
+	exports.Insert(contextKeys, value)
+	receiver.exports[filename] = exports
+}
+
+// InsertContext merges the keys and values of context into the
+// export context represented by filename
+func (receiver *ExportStore) InsertContext(filename string, context *Context) {
+	mut.Lock()
+	defer mut.Unlock()
+
+	exports := receiver.copyExports(filename)
+	for key, node := range *context {
+		(*exports)[key] = node
+	}
+	receiver.exports[filename] = exports
+}
+
+// copyExports returns a copy of the top level of the export context of
+// filename to change and replace it with
+// Contexts returned by Get are never changed, Context.Insert doesn't
+// change nested levels, so only the top level is copied
+func (receiver *ExportStore) copyExports(filename string) *Context {
+	if exports, ok := receiver.exports[filename]; ok {
+		return exports.Merge(&Context{})
 	}
+	return &Context{}
+}
+
+// Remove deletes the export context of filename so its exports can
+// be collected again
+func (receiver *ExportStore) Remove(filename string) {
+	mut.Lock()
+	defer mut.Unlock()
+	delete(receiver.exports, filename)
+}
+
+// Freeze stops Insert from changing any export until Thaw is called
+// Files are rendered with a frozen store so the exports every file
+// reads are the ones collected before rendering
+func (receiver *ExportStore) Freeze() {
+	mut.Lock()
+	defer mut.Unlock()
+	receiver.frozen = true
+}
 
-	receiver.exports[filename].Insert(contextKeys, value)
+// Thaw lets Insert change exports again so they can be collected
+func (receiver *ExportStore) Thaw() {
+	mut.Lock()
+	defer mut.Unlock()
+	receiver.frozen = false
 }
 
-// Get returns the export context of the given filename
+// Get returns the export context of the given filename, or nil if it
+// has none
+// The context is a read only view, exporting replaces it rather than
+// changing it, so it can be read while other files export
 func (receiver *ExportStore) Get(filename string) *Context {
+	mut.Lock()
+	defer mut.Unlock()
 	return receiver.exports[filename]
 }
@@ -0,0 +1,165 @@
+package processor
+
+import (
+	"math"
+	"strconv"
+)
+
+// FloatResult represents a result containing a floating point value
+// Operations between a FloatResult and an IntResult promote the int
+// to a float
+type FloatResult float64
+
+// GetResult returns this result value
+func (receiver FloatResult) GetResult() interface{} {
+	return receiver
+}
+
+func (receiver FloatResult) String() string {
+	return strconv.FormatFloat(float64(receiver), 'f', -1, 64)
+}
+
+// convertToFloat returns right as a float if it is a number or a string
+// containing one
+func convertToFloat(right Result) (float64, bool) {
+	switch typedResult := right.(type) {
+	case FloatResult:
+		return float64(typedResult), true
+	case IntResult:
+		return float64(typedResult), true
+	case StringResult:
+		if num, err := strconv.ParseFloat(string(typedResult), 64); err == nil {
+			return num, true
+		}
+		return 0, false
+	default:
+		return 0, false
+	}
+}
+
+// Add takes a result and adds it to this float representation
+// Strings are concatenated with the float's string representation
+func (receiver FloatResult) Add(right Result) (Result, error) {
+	switch typedRight := right.(type) {
+	case FloatResult:
+		return receiver + typedRight, nil
+	case IntResult:
+		return receiver + FloatResult(typedRight), nil
+	case StringResult:
+		return StringResult(receiver.String() + string(typedRight)), nil
+	default:
+		return nil, newBinaryError("+", receiver, right)
+	}
+}
+
+// Subtract takes a result and subtracts it from this float representation
+func (receiver FloatResult) Subtract(right Result) (Result, error) {
+	switch typedRight := right.(type) {
+	case FloatResult:
+		return receiver - typedRight, nil
+	case IntResult:
+		return receiver - FloatResult(typedRight), nil
+	default:
+		return nil, newBinaryError("-", receiver, right)
+	}
+}
+
+// Multiply takes a result and multiplies it with this float representation
+func (receiver FloatResult) Multiply(right Result) (Result, error) {
+	switch typedRight := right.(type) {
+	case FloatResult:
+		return receiver * typedRight, nil
+	case IntResult:
+		return receiver * FloatResult(typedRight), nil
+	default:
+		return nil, newBinaryError("*", receiver, right)
+	}
+}
+
+// Divide takes a result and divides this float representation by it
+func (receiver FloatResult) Divide(right Result) (Result, error) {
+	switch typedRight := right.(type) {
+	case FloatResult:
+		return receiver / typedRight, nil
+	case IntResult:
+		return receiver / FloatResult(typedRight), nil
+	default:
+		return nil, newBinaryError("/", receiver, right)
+	}
+}
+
+// Mod takes a result and returns the remainder of dividing this float
+// representation by it
+func (receiver FloatResult) Mod(right Result) (Result, error) {
+	divisor, ok := 0.0, false
+	switch typedRight := right.(type) {
+	case FloatResult:
+		divisor, ok = float64(typedRight), true
+	case IntResult:
+		divisor, ok = float64(typedRight), true
+	}
+
+	if !ok {
+		return nil, newBinaryError("%", receiver, right)
+	} else if divisor == 0 {
+		return nil, newDivisionByZeroError("%", receiver)
+	}
+	return FloatResult(math.Mod(float64(receiver), divisor)), nil
+}
+
+// EqualTo checks if the provided result is logically equal to
+// the receiver
+func (receiver FloatResult) EqualTo(right Result) (Result, error) {
+	if rightFloat, ok := convertToFloat(right); ok {
+		return BoolResult(float64(receiver) == rightFloat), nil
+	}
+	return nil, newBinaryError("==", receiver, right)
+}
+
+// NotEqualTo checks if the provided result is logically not equal
+// to the receiver
+func (receiver FloatResult) NotEqualTo(right Result) (Result, error) {
+	if rightFloat, ok := convertToFloat(right); ok {
+		return BoolResult(float64(receiver) != rightFloat), nil
+	}
+	return nil, newBinaryError("!=", receiver, right)
+}
+
+// LessThan checks if the receiver is less than the provided result
+func (receiver FloatResult) LessThan(right Result) (Result, error) {
+	if rightFloat, ok := convertToFloat(right); ok {
+		return BoolResult(float64(receiver) < rightFloat), nil
+	}
+	return nil, newBinaryError("<", receiver, right)
+}
+
+// GreaterThan checks if the receiver is greater than the provided result
+func (receiver FloatResult) GreaterThan(right Result) (Result, error) {
+	if rightFloat, ok := convertToFloat(right); ok {
+		return BoolResult(float64(receiver) > rightFloat), nil
+	}
+	return nil, newBinaryError(">", receiver, right)
+}
+
+// LessThanEqual checks if the receiver is less than or equal to the
+// provided result
+func (receiver FloatResult) LessThanEqual(right Result) (Result, error) {
+	if rightFloat, ok := convertToFloat(right); ok {
+		return BoolResult(float64(receiver) <= rightFloat), nil
+	}
+	return nil, newBinaryError("<=", receiver, right)
+}
+
+// GreaterThanEqual checks if the receiver is greater than or equal to
+// the provided result
+func (receiver FloatResult) GreaterThanEqual(right Result) (Result, error) {
+	if rightFloat, ok := convertToFloat(right); ok {
+		return BoolResult(float64(receiver) >= rightFloat), nil
+	}
+	return nil, newBinaryError(">=", receiver, right)
+}
+
+// Negative switches the sign of this number
+func (receiver FloatResult) Negative() (Result, error) {
+	return -receiver, nil
+}
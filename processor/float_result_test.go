@@ -0,0 +1,131 @@
+package processor
+
+import (
+	"testing"
+)
+
+func TestFloatArithmetic(t *testing.T) {
+	var tests = []struct {
+		left     Result
+		operator string
+		right    Result
+		expected Result
+	}{
+		{FloatResult(1.5), "+", FloatResult(2.25), FloatResult(3.75)},
+		{FloatResult(1.5), "+", IntResult(2), FloatResult(3.5)},
+		{IntResult(2), "+", FloatResult(1.5), FloatResult(3.5)},
+		{FloatResult(1.5), "+", StringResult("x"), StringResult("1.5x")},
+		{StringResult("x"), "+", FloatResult(1.5), StringResult("x1.5")},
+		{FloatResult(1.5), "-", IntResult(2), FloatResult(-0.5)},
+		{IntResult(2), "-", FloatResult(0.5), FloatResult(1.5)},
+		{FloatResult(1.5), "*", IntResult(3), FloatResult(4.5)},
+		{IntResult(3), "*", FloatResult(1.5), FloatResult(4.5)},
+		{FloatResult(7), "/", IntResult(2), FloatResult(3.5)},
+		{IntResult(7), "/", FloatResult(2), FloatResult(3.5)},
+		{IntResult(7), "/", IntResult(2), IntResult(3)},
+		{IntResult(7), "%", IntResult(3), IntResult(1)},
+		{IntResult(-7), "%", IntResult(2), IntResult(-1)},
+		{FloatResult(7.5), "%", IntResult(2), FloatResult(1.5)},
+		{IntResult(7), "%", FloatResult(2.5), FloatResult(2)},
+		{FloatResult(-7.5), "%", FloatResult(2), FloatResult(-1.5)},
+	}
+
+	for i, test := range tests {
+		var actual Result
+		var err error
+
+		switch test.operator {
+		case "+", "-":
+			actual, err = processAddition(test.left, test.right, test.operator)
+		default:
+			actual, err = processMultiplication(test.left, test.right, test.operator)
+		}
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %v %s %v to be %#v, got %#v", i, test.left, test.operator, test.right, test.expected, actual)
+		}
+	}
+}
+
+func TestFloatComparisons(t *testing.T) {
+	var tests = []struct {
+		left     Result
+		operator string
+		right    Result
+		expected bool
+	}{
+		{FloatResult(1.5), "<", FloatResult(2.5), true},
+		{FloatResult(1.5), "<", IntResult(1), false},
+		{IntResult(1), "<", FloatResult(1.5), true},
+		{FloatResult(2), "==", IntResult(2), true},
+		{IntResult(2), "==", FloatResult(2), true},
+		{IntResult(2), "!=", FloatResult(2.5), true},
+		{FloatResult(2.5), "==", StringResult("2.5"), true},
+		{FloatResult(2.5), ">", IntResult(2), true},
+		{IntResult(3), ">", FloatResult(2.5), true},
+		{FloatResult(2.5), "<=", FloatResult(2.5), true},
+		{IntResult(2), ">=", FloatResult(2.5), false},
+	}
+
+	for i, test := range tests {
+		actual, err := processRel(test.left, test.right, test.operator)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != BoolResult(test.expected) {
+			t.Errorf("%d: expected %v %s %v to be %t, got %v", i, test.left, test.operator, test.right, test.expected, actual)
+		}
+	}
+}
+
+func TestFloatUnsupportedOperands(t *testing.T) {
+	var tests = []struct {
+		left     Result
+		operator string
+		right    Result
+	}{
+		{FloatResult(1.5), "-", StringResult("1")},
+		{FloatResult(1.5), "*", BoolResult(true)},
+		{FloatResult(1.5), "/", StringResult("2")},
+		{FloatResult(1.5), "%", BoolResult(true)},
+		{IntResult(1), "%", StringResult("2")},
+		{FloatResult(1.5), "<", BoolResult(true)},
+		{FloatResult(1.5), "==", StringResult("a")},
+	}
+
+	for i, test := range tests {
+		var err error
+		switch test.operator {
+		case "-":
+			_, err = processAddition(test.left, test.right, test.operator)
+		case "*", "/", "%":
+			_, err = processMultiplication(test.left, test.right, test.operator)
+		default:
+			_, err = processRel(test.left, test.right, test.operator)
+		}
+
+		if _, ok := err.(*RuntimeError); !ok {
+			t.Errorf("%d: expected a runtime error for %v %s %v, got %v", i, test.left, test.operator, test.right, err)
+		}
+	}
+}
+
+func TestFloatString(t *testing.T) {
+	var tests = []struct {
+		value    FloatResult
+		expected string
+	}{
+		{FloatResult(4.5), "4.5"},
+		{FloatResult(1e21), "1000000000000000000000"},
+		{FloatResult(2), "2"},
+		{FloatResult(-0.25), "-0.25"},
+	}
+
+	for i, test := range tests {
+		if actual := test.value.String(); actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// NewContextFromMap converts decoded front matter values into a Context
+// Nested maps become nested contexts and lists become contexts keyed
+// by their index so they can be looped over like other containers
+func NewContextFromMap(values map[string]interface{}) *Context {
+	context := &Context{}
+	for key, value := range values {
+		(*context)[key] = newContextNode(value)
+	}
+	return context
+}
+
+func newContextNode(value interface{}) *ContextNode {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		return &ContextNode{child: NewContextFromMap(typedValue)}
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(typedValue))
+		for key, v := range typedValue {
+			converted[fmt.Sprint(key)] = v
+		}
+		return &ContextNode{child: NewContextFromMap(converted)}
+	case []map[string]interface{}:
+		list := make([]interface{}, len(typedValue))
+		for i, v := range typedValue {
+			list[i] = v
+		}
+		return newContextNode(list)
+	case []interface{}:
+		child := &Context{}
+		for i, v := range typedValue {
+			(*child)[strconv.Itoa(i)] = newContextNode(v)
+		}
+		return &ContextNode{child: child}
+	default:
+		return &ContextNode{result: newScalarResult(typedValue)}
+	}
+}
+
+func newScalarResult(value interface{}) Result {
+	switch typedValue := value.(type) {
+	case nil:
+		return StringResult("")
+	case string:
+		return StringResult(typedValue)
+	case bool:
+		return BoolResult(typedValue)
+	case int:
+		return IntResult(typedValue)
+	case int64:
+		return IntResult(typedValue)
+	case uint64:
+		return IntResult(typedValue)
+	case float64:
+		return numberResult(typedValue)
+	case json.Number:
+		if i, err := typedValue.Int64(); err == nil {
+			return IntResult(i)
+		}
+		if f, err := typedValue.Float64(); err == nil {
+			return FloatResult(f)
+		}
+		return StringResult(typedValue.String())
+	case time.Time:
+		hour, min, sec := typedValue.Clock()
+		if hour == 0 && min == 0 && sec == 0 && typedValue.Nanosecond() == 0 {
+			return StringResult(typedValue.Format("2006-01-02"))
+		}
+		return StringResult(typedValue.Format(time.RFC3339))
+	default:
+		return StringResult(fmt.Sprint(typedValue))
+	}
+}
+
+// numberResult converts whole numbers to an IntResult and everything
+// else to a FloatResult
+func numberResult(value float64) Result {
+	if value == float64(int(value)) {
+		return IntResult(int(value))
+	}
+	return FloatResult(value)
+}
@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNewContextFromMapConvertsValues(t *testing.T) {
+	context := NewContextFromMap(map[string]interface{}{
+		"title":  "Hello",
+		"count":  3,
+		"json":   json.Number("12"),
+		"rating": 4.5,
+		"draft":  false,
+		"date":   time.Date(2021, 3, 8, 0, 0, 0, 0, time.UTC),
+		"author": map[string]interface{}{
+			"name": "Ada",
+		},
+		"tags": []interface{}{"a", "b"},
+		"links": []map[string]interface{}{
+			{"href": "/one"},
+		},
+	})
+
+	var tests = []struct {
+		key      string
+		expected Result
+	}{
+		{"title", StringResult("Hello")},
+		{"count", IntResult(3)},
+		{"json", IntResult(12)},
+		{"rating", FloatResult(4.5)},
+		{"draft", BoolResult(false)},
+		{"date", StringResult("2021-03-08")},
+		{"author.name", StringResult("Ada")},
+		{"tags.0", StringResult("a")},
+		{"tags.1", StringResult("b")},
+		{"links.0.href", StringResult("/one")},
+	}
+
+	for _, test := range tests {
+		node, ok := context.At(test.key)
+
+		if !ok {
+			t.Errorf("expected key %q to exist", test.key)
+		} else if node.result != test.expected {
+			t.Errorf("expected %q to be %v, got %v", test.key, test.expected, node.result)
+		}
+	}
+
+	tags, _ := context.At("tags")
+	if values := tags.child.Values(); len(values) != 2 {
+		t.Errorf("expected tags to be iterable with 2 values, got %d", len(values))
+	}
+}
+
+func TestContextKeysSortsIndicesNumerically(t *testing.T) {
+	context := &Context{}
+	for _, key := range []string{"b", "10", "2", "a", "1"} {
+		context.Insert([]string{key}, StringResult(key))
+	}
+
+	expected := []string{"1", "2", "10", "a", "b"}
+	for i, key := range context.Keys() {
+		if key != expected[i] {
+			t.Errorf("expected keys %v, got %v", expected, context.Keys())
+			break
+		}
+	}
+}
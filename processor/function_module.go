@@ -1,6 +1,9 @@
 package processor
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
 
 // FunctionModule represents a type that can take a
 // function name and result arguments and returns a result
@@ -19,11 +22,17 @@ func (receiver *BuiltinFunctionModule) registerFunc(funcName string, function fu
 
 // CallFunction calls the function with the given name and passes in
 // the provided arguments
+// Functions registered with the FunctionRegistry are called if there
+// is no builtin function with the name
 func (receiver *BuiltinFunctionModule) CallFunction(funcName string, funcArgs ...Result) (Result, error) {
 	if function, ok := (*receiver)[funcName]; ok {
 		return function(funcArgs...)
 	}
 
+	if function, ok := GetFunctionRegistry().lookup(funcName); ok {
+		return function(funcArgs...)
+	}
+
 	return nil, fmt.Errorf("function %s is not registered", funcName)
 }
 
@@ -45,13 +54,74 @@ func NewBuiltinFunctionModule(curPage, numPages, inputPath Result) *BuiltinFunct
 	)
 
 	module.registerFunc("template", TemplateRaw)
+	module.registerFunc("defined", DefinedRaw)
+	module.registerFunc("default", DefaultRaw)
+	module.registerFunc("safe", SafeRaw)
+
+	module.registerFunc("round", RoundRaw)
+	module.registerFunc("floor", FloorRaw)
+	module.registerFunc("ceil", CeilRaw)
+	module.registerFunc("fixed", FixedRaw)
+	module.registerFunc("formatNumber", FormatNumberRaw)
+	module.registerFunc("range", RangeRaw)
+
+	module.registerFunc("upper", UpperRaw)
+	module.registerFunc("lower", LowerRaw)
+	module.registerFunc("title", TitleRaw)
+	module.registerFunc("trim", TrimRaw)
+	module.registerFunc("replace", ReplaceRaw)
+	module.registerFunc("split", SplitRaw)
+	module.registerFunc("join", JoinRaw)
+	module.registerFunc("contains", ContainsRaw)
+	module.registerFunc("hasPrefix", HasPrefixRaw)
+	module.registerFunc("hasSuffix", HasSuffixRaw)
+	module.registerFunc("truncate", TruncateRaw)
+	module.registerFunc("slugify", SlugifyRaw)
+	module.registerFunc("pluralize", PluralizeRaw)
+	module.registerFunc("regexMatch", RegexMatchRaw)
+	module.registerFunc("regexReplace", RegexReplaceRaw)
+	module.registerFunc("wordCount", WordCountRaw)
+	module.registerFunc("readingTime", ReadingTimeRaw)
+
+	for funcName, function := range collectionFuncs {
+		module.registerFunc(funcName, function)
+	}
+	module.registerFunc("len", LenRaw)
 
 	return module
 }
 
+// collectionFuncs take a container as their first argument
+// NewNodeProcessor also lets them be passed a content path
+var collectionFuncs = map[string]func(...Result) (Result, error){
+	"sort":    SortRaw,
+	"where":   WhereRaw,
+	"first":   FirstRaw,
+	"last":    LastRaw,
+	"reverse": ReverseRaw,
+	"groupBy": GroupByRaw,
+	"uniq":    UniqRaw,
+}
+
+var builtinFuncNamesOnce sync.Once
+var builtinFuncNames map[string]bool
+
+// isBuiltinFunc reports whether name is taken by a builtin function,
+// including those NewNodeProcessor binds to the processor
+func isBuiltinFunc(name string) bool {
+	builtinFuncNamesOnce.Do(func() {
+		builtinFuncNames = map[string]bool{"include": true}
+		for funcName := range *NewBuiltinFunctionModule(nil, nil, nil) {
+			builtinFuncNames[funcName] = true
+		}
+	})
+
+	return builtinFuncNames[name]
+}
+
 func paginationClosure(f func(...Result) (Result, error), prepend ...Result) func(...Result) (Result, error) {
 	return func(args ...Result) (Result, error) {
-		prepend = append(prepend, args...)
-		return f(prepend...)
+		callArgs := append(append([]Result{}, prepend...), args...)
+		return f(callArgs...)
 	}
 }
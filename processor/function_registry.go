@@ -0,0 +1,415 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FunctionRegistry holds template functions registered from Go code
+// Every NodeProcessor can call them, including those for loop bodies,
+// partials and pagination templates
+type FunctionRegistry struct {
+	mutex sync.RWMutex
+	funcs map[string]func(...Result) (Result, error)
+}
+
+var registryOnce sync.Once
+var registry *FunctionRegistry
+
+func createRegistry() {
+	if registry == nil {
+		registry = newFunctionRegistry()
+	}
+}
+
+// GetFunctionRegistry returns the FunctionRegistry singleton
+func GetFunctionRegistry() *FunctionRegistry {
+	registryOnce.Do(createRegistry)
+	return registry
+}
+
+func newFunctionRegistry() *FunctionRegistry {
+	return &FunctionRegistry{funcs: make(map[string]func(...Result) (Result, error))}
+}
+
+// funcNameExp matches names like upper and namespaced names like str.upper
+var funcNameExp = regexp.MustCompile(`^_?[a-zA-Z]+[a-zA-Z0-9_]*(\._?[a-zA-Z]+[a-zA-Z0-9_]*)*$`)
+
+var resultType = reflect.TypeOf((*Result)(nil)).Elem()
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Register makes function callable from templates as name
+// Names can be namespaced with dots like str.upper
+//
+// function is either a func(...Result) (Result, error), which checks
+// its own arguments, or any Go func whose parameters and first result
+// are strings, bools, ints, floats, Results, interface{} or slices and
+// string keyed maps of those. It can return an error as its second
+// result. Template arguments are checked against the parameters when
+// the function is called
+func (receiver *FunctionRegistry) Register(name string, function interface{}) error {
+	if !funcNameExp.MatchString(name) {
+		return fmt.Errorf("invalid function name %q", name)
+	}
+
+	if isBuiltinFunc(name) {
+		return fmt.Errorf("function %q is a builtin", name)
+	}
+
+	wrapped, err := wrapFunc(name, function)
+	if err != nil {
+		return err
+	}
+
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	if _, ok := receiver.funcs[name]; ok {
+		return fmt.Errorf("function %q is already registered", name)
+	}
+
+	receiver.funcs[name] = wrapped
+	return nil
+}
+
+// RegisterNamespace registers each of functions as namespace.name
+// Nothing is registered if any of the functions can't be
+func (receiver *FunctionRegistry) RegisterNamespace(namespace string, functions map[string]interface{}) error {
+	wrapped := make(map[string]func(...Result) (Result, error), len(functions))
+	for name, function := range functions {
+		fullName := namespace + "." + name
+		if !funcNameExp.MatchString(fullName) {
+			return fmt.Errorf("invalid function name %q", fullName)
+		}
+
+		f, err := wrapFunc(fullName, function)
+		if err != nil {
+			return err
+		}
+		wrapped[fullName] = f
+	}
+
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	for name := range wrapped {
+		if _, ok := receiver.funcs[name]; ok {
+			return fmt.Errorf("function %q is already registered", name)
+		}
+	}
+
+	for name, f := range wrapped {
+		receiver.funcs[name] = f
+	}
+	return nil
+}
+
+func (receiver *FunctionRegistry) lookup(name string) (func(...Result) (Result, error), bool) {
+	receiver.mutex.RLock()
+	defer receiver.mutex.RUnlock()
+
+	function, ok := receiver.funcs[name]
+	return function, ok
+}
+
+// recoverCall turns a panic in the function called name into an error
+// in err, so it fails the file calling it instead of the whole build
+// It must be deferred
+func recoverCall(name string, err *error) {
+	if recovered := recover(); recovered != nil {
+		*err = fmt.Errorf("`%s` panicked: %v", name, recovered)
+	}
+}
+
+// wrapFunc turns function into a func taking and returning Results
+// which checks the number and types of its arguments
+func wrapFunc(name string, function interface{}) (func(...Result) (Result, error), error) {
+	if raw, ok := function.(func(...Result) (Result, error)); ok {
+		return func(args ...Result) (result Result, err error) {
+			defer recoverCall(name, &err)
+			return raw(args...)
+		}, nil
+	}
+
+	funcValue := reflect.ValueOf(function)
+	if funcValue.Kind() != reflect.Func || funcValue.IsNil() {
+		return nil, fmt.Errorf("function %q must be a func, got %T", name, function)
+	}
+
+	funcType := funcValue.Type()
+	for i := 0; i < funcType.NumIn(); i++ {
+		param := funcType.In(i)
+		if funcType.IsVariadic() && i == funcType.NumIn()-1 {
+			param = param.Elem()
+		}
+
+		if !isSupportedType(param) {
+			return nil, fmt.Errorf("function %q has unsupported parameter type %s", name, param)
+		}
+	}
+
+	if funcType.NumOut() < 1 || funcType.NumOut() > 2 {
+		return nil, fmt.Errorf("function %q must return a value and optionally an error", name)
+	} else if !isSupportedType(funcType.Out(0)) {
+		return nil, fmt.Errorf("function %q has unsupported result type %s", name, funcType.Out(0))
+	} else if funcType.NumOut() == 2 && funcType.Out(1) != errorType {
+		return nil, fmt.Errorf("function %q must return an error as its second result, got %s", name, funcType.Out(1))
+	}
+
+	return func(args ...Result) (result Result, err error) {
+		defer recoverCall(name, &err)
+
+		numParams := funcType.NumIn()
+		if funcType.IsVariadic() && len(args) < numParams-1 {
+			return nil, fmt.Errorf("`%s` expects at least %s, got %d", name, argCount(numParams-1), len(args))
+		} else if !funcType.IsVariadic() && len(args) != numParams {
+			return nil, fmt.Errorf("`%s` expects %s, got %d", name, argCount(numParams), len(args))
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			var param reflect.Type
+			if funcType.IsVariadic() && i >= numParams-1 {
+				param = funcType.In(numParams - 1).Elem()
+			} else {
+				param = funcType.In(i)
+			}
+
+			value, err := resultToGo(arg, param)
+			if errors.Is(err, errWrongType) {
+				return nil, fmt.Errorf("`%s` expects argument %d to be %s, got %s", name, i+1, goTypeName(param), typeName(arg))
+			} else if err != nil {
+				return nil, fmt.Errorf("`%s` argument %d: %s", name, i+1, err)
+			}
+			in[i] = value
+		}
+
+		out := funcValue.Call(in)
+		if len(out) == 2 && !out[1].IsNil() {
+			return nil, out[1].Interface().(error)
+		}
+
+		return goToResult(out[0]), nil
+	}, nil
+}
+
+func argCount(n int) string {
+	if n == 1 {
+		return "one argument"
+	}
+	return fmt.Sprintf("%d args", n)
+}
+
+// isSupportedType reports whether values of t can be converted to and
+// from Results
+func isSupportedType(t reflect.Type) bool {
+	if t.Implements(resultType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return t.NumMethod() == 0
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	case reflect.Slice:
+		return isSupportedType(t.Elem())
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && isSupportedType(t.Elem())
+	}
+
+	return false
+}
+
+// goTypeName describes the results that can be converted to t
+// e.g. "a string" or "a list of ints"
+func goTypeName(t reflect.Type) string {
+	noun := goTypeNoun(t)
+	if strings.IndexAny(noun, "aeiou") == 0 {
+		return "an " + noun
+	}
+	return "a " + noun
+}
+
+func goTypeNoun(t reflect.Type) string {
+	if t == resultType || (t.Kind() == reflect.Interface && t.NumMethod() == 0) {
+		return "value"
+	} else if t.Implements(resultType) {
+		return typeName(reflect.Zero(t).Interface().(Result))
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice:
+		return "list of " + goTypeNoun(t.Elem()) + "s"
+	case reflect.Map:
+		return "map of " + goTypeNoun(t.Elem()) + "s"
+	}
+
+	return "int"
+}
+
+// errWrongType is returned by resultToGo for results of a type that
+// can't be converted
+var errWrongType = errors.New("wrong type")
+
+// resultToGo converts result to a value of type t, it returns
+// errWrongType if result can't be converted and an error if it doesn't
+// fit in t
+func resultToGo(result Result, t reflect.Type) (reflect.Value, error) {
+	if t == resultType {
+		if result == nil {
+			return reflect.Zero(t), nil
+		}
+		return reflect.ValueOf(result), nil
+	} else if t.Implements(resultType) {
+		// concrete results like SafeResult have to match exactly
+		if result == nil || reflect.TypeOf(result) != t {
+			return reflect.Value{}, errWrongType
+		}
+		return reflect.ValueOf(result), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		if value := jsonValue(result); value != nil {
+			return reflect.ValueOf(value), nil
+		}
+		return reflect.Zero(t), nil
+	case reflect.String:
+		switch typedResult := result.(type) {
+		case StringResult:
+			return reflect.ValueOf(string(typedResult)).Convert(t), nil
+		case SafeResult:
+			return reflect.ValueOf(string(typedResult)).Convert(t), nil
+		}
+	case reflect.Bool:
+		if typedResult, ok := result.(BoolResult); ok {
+			return reflect.ValueOf(bool(typedResult)).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if typedResult, ok := result.(IntResult); ok {
+			value := reflect.New(t).Elem()
+			if value.OverflowInt(int64(typedResult)) {
+				return reflect.Value{}, fmt.Errorf("%d overflows %s", int64(typedResult), t)
+			}
+			value.SetInt(int64(typedResult))
+			return value, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch typedResult := result.(type) {
+		case FloatResult:
+			return reflect.ValueOf(float64(typedResult)).Convert(t), nil
+		case IntResult:
+			return reflect.ValueOf(float64(typedResult)).Convert(t), nil
+		}
+	case reflect.Slice:
+		if container, ok := result.(ContainerResult); ok {
+			keys := containerKeys(container)
+			slice := reflect.MakeSlice(t, len(keys), len(keys))
+			for i, key := range keys {
+				value, err := resultToGo(nodeResult((*container.context)[key]), t.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				slice.Index(i).Set(value)
+			}
+			return slice, nil
+		}
+	case reflect.Map:
+		if container, ok := result.(ContainerResult); ok {
+			keys := containerKeys(container)
+			m := reflect.MakeMapWithSize(t, len(keys))
+			for _, key := range keys {
+				value, err := resultToGo(nodeResult((*container.context)[key]), t.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				m.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), value)
+			}
+			return m, nil
+		}
+	}
+
+	return reflect.Value{}, errWrongType
+}
+
+func containerKeys(container ContainerResult) []string {
+	if container.context == nil {
+		return nil
+	}
+	return container.context.Keys()
+}
+
+// nodeResult returns the result held by node with nested contexts
+// wrapped in a ContainerResult
+func nodeResult(node *ContextNode) Result {
+	if node.HasContext() {
+		return ContainerResult{context: node.child}
+	}
+	return node.result
+}
+
+// goToResult converts a value returned by a registered function to a
+// Result, slices and maps become containers
+func goToResult(value reflect.Value) Result {
+	return nodeResult(goToContextNode(value))
+}
+
+func goToContextNode(value reflect.Value) *ContextNode {
+	if value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return &ContextNode{result: StringResult("")}
+		}
+		value = value.Elem()
+	}
+
+	if value.Type().Implements(resultType) {
+		if container, ok := value.Interface().(ContainerResult); ok {
+			if container.context == nil {
+				return &ContextNode{child: &Context{}}
+			}
+			return &ContextNode{child: container.context}
+		}
+		return &ContextNode{result: value.Interface().(Result)}
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return &ContextNode{result: StringResult(value.String())}
+	case reflect.Bool:
+		return &ContextNode{result: BoolResult(value.Bool())}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &ContextNode{result: IntResult(value.Int())}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &ContextNode{result: IntResult(value.Uint())}
+	case reflect.Float32, reflect.Float64:
+		return &ContextNode{result: FloatResult(value.Float())}
+	case reflect.Slice, reflect.Array:
+		child := &Context{}
+		for i := 0; i < value.Len(); i++ {
+			(*child)[strconv.Itoa(i)] = goToContextNode(value.Index(i))
+		}
+		return &ContextNode{child: child}
+	case reflect.Map:
+		child := &Context{}
+		iter := value.MapRange()
+		for iter.Next() {
+			(*child)[fmt.Sprint(iter.Key().Interface())] = goToContextNode(iter.Value())
+		}
+		return &ContextNode{child: child}
+	}
+
+	return &ContextNode{result: StringResult(fmt.Sprint(value.Interface()))}
+}
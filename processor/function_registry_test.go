@@ -0,0 +1,181 @@
+package processor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// useTestRegistry replaces the registry singleton with one holding
+// functions for the tests until t finishes
+func useTestRegistry(t *testing.T) {
+	previous := GetFunctionRegistry()
+	registry = newFunctionRegistry()
+	t.Cleanup(func() { registry = previous })
+
+	if err := registry.Register("shout", func(s string) string { return strings.ToUpper(s) + "!" }); err != nil {
+		t.Fatal(err)
+	}
+	err := registry.Register("sum", func(nums ...float64) float64 {
+		total := 0.0
+		for _, num := range nums {
+			total += num
+		}
+		return total
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterNamespace("test", map[string]interface{}{
+		"repeat": strings.Repeat,
+		"words":  strings.Fields,
+		"join":   func(parts []string, sep string) string { return strings.Join(parts, sep) },
+		"keys": func(m map[string]interface{}) int {
+			return len(m)
+		},
+		"fail": func(s string) (string, error) { return "", errors.New("failed on " + s) },
+		"raw": func(args ...Result) (Result, error) {
+			return IntResult(len(args)), nil
+		},
+		"byte":  func(n int8) int8 { return n },
+		"bytes": func(ns []int16) int { return len(ns) },
+		"rune":  func(n int32) int32 { return n },
+		"panic": func(s string) string { panic("bad " + s) },
+		"rawPanic": func(args ...Result) (Result, error) {
+			panic("bad raw")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegisteredFunctionsCanBeCalled(t *testing.T) {
+	useTestRegistry(t)
+
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{: shout("hi")}}`, "HI!"},
+		{`{{: "hi" | shout}}`, "HI!"},
+		{`{{: test.repeat("ab", 2)}}`, "abab"},
+		{`{{: "ab" | test.repeat(3)}}`, "ababab"},
+		{`{{: sum(1, 2.5, 3)}}`, "6.5"},
+		{`{{: sum()}}`, "0"},
+		{`{{for w in test.words("a b c")}}[{{: w}}]{{end}}`, "[a][b][c]"},
+		{`{{: test.join(["a", "b"], "-")}}`, "a-b"},
+		{`{{: test.keys({"a": 1, "b": [1, 2]})}}`, "2"},
+		{`{{: test.raw(1, "a", true)}}`, "3"},
+		{`{{for n in [1, 2]}}{{: shout("n" + n)}}{{end}}`, "N1!N2!"},
+		{`{{: test.byte(-128)}}`, "-128"},
+		{`{{: test.bytes([32767, -32768])}}`, "2"},
+		{`{{: test.rune(2147483647)}}`, "2147483647"},
+	}
+
+	for i, test := range tests {
+		got, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no errors, got %q", i, err)
+		} else if got != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, got)
+		}
+	}
+}
+
+func TestRegisteredFunctionsCheckTheirArgs(t *testing.T) {
+	useTestRegistry(t)
+
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{: shout()}}`, "`shout` expects one argument, got 0"},
+		{`{{: shout(1)}}`, "`shout` expects argument 1 to be a string, got int"},
+		{`{{: test.repeat("a", "b")}}`, "`test.repeat` expects argument 2 to be an int, got string"},
+		{`{{: sum(1, "2")}}`, "`sum` expects argument 2 to be a number, got string"},
+		{`{{: test.join([1], "-")}}`, "`test.join` expects argument 1 to be a list of strings, got container"},
+		{`{{: test.byte(128)}}`, "`test.byte` argument 1: 128 overflows int8"},
+		{`{{: test.bytes([1, 32768])}}`, "`test.bytes` argument 1: 32768 overflows int16"},
+		{`{{: test.rune(2147483648)}}`, "`test.rune` argument 1: 2147483648 overflows int32"},
+		{`{{: test.fail("x")}}`, "failed on x"},
+		{`{{: test.panic("x")}}`, "`test.panic` panicked: bad x"},
+		{`{{: test.rawPanic()}}`, "`test.rawPanic` panicked: bad raw"},
+		{`{{: test.missing("x")}}`, "function test.missing is not registered"},
+	}
+
+	for i, test := range tests {
+		_, err := processText(t, test.text)
+
+		if err == nil {
+			t.Errorf("%d: expected error %q, got nil", i, test.expected)
+		} else if !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%d: expected error %q, got %q", i, test.expected, err)
+		}
+	}
+}
+
+func TestRegisterRejectsInvalidFunctions(t *testing.T) {
+	var tests = []struct {
+		name     string
+		function interface{}
+		expected string
+	}{
+		{"str.", strings.ToUpper, `invalid function name "str."`},
+		{"1up", strings.ToUpper, `invalid function name "1up"`},
+		{"round", strings.ToUpper, `function "round" is a builtin`},
+		{"include", strings.ToUpper, `function "include" is a builtin`},
+		{"loud", "not a func", `function "loud" must be a func, got string`},
+		{"loud", func(c chan int) string { return "" }, `function "loud" has unsupported parameter type chan int`},
+		{"loud", func() {}, `function "loud" must return a value and optionally an error`},
+		{"loud", func() (string, int) { return "", 0 }, `function "loud" must return an error as its second result, got int`},
+		{"loud", func() *int { return nil }, `function "loud" has unsupported result type *int`},
+	}
+
+	for i, test := range tests {
+		err := newFunctionRegistry().Register(test.name, test.function)
+
+		if err == nil {
+			t.Errorf("%d: expected error %q, got nil", i, test.expected)
+		} else if err.Error() != test.expected {
+			t.Errorf("%d: expected error %q, got %q", i, test.expected, err)
+		}
+	}
+}
+
+func TestRegisterRejectsDuplicateNames(t *testing.T) {
+	registry := newFunctionRegistry()
+	if err := registry.Register("str.upper", strings.ToUpper); err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	if err := registry.Register("str.upper", strings.ToLower); err == nil {
+		t.Errorf("expected registering str.upper twice to fail")
+	}
+
+	err := registry.RegisterNamespace("str", map[string]interface{}{
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+	})
+	if err == nil {
+		t.Errorf("expected registering the str namespace to fail")
+	} else if _, ok := registry.lookup("str.lower"); ok {
+		t.Errorf("expected str.lower not to be registered when the namespace fails")
+	}
+}
+
+func TestRegisteredFunctionsCanBeCalledFromPaginatedTemplates(t *testing.T) {
+	useTestRegistry(t)
+
+	cacheTemplate(t, "registry/paginated.html", `{{: shout("page")}}`)
+
+	result, err := Paginate([]string{}, "registry/paginated.html", 1, 1)
+	if err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	if result.String() != "PAGE!" {
+		t.Errorf("expected %q, got %q", "PAGE!", result.String())
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"mettlach.codes/frizzy/config"
 	"mettlach.codes/frizzy/file"
@@ -15,43 +16,76 @@ import (
 
 // PaginateRaw converts its Result type arguments into
 // the actual types that Paginate expects
+// The content paginated is either a content path or a container
 func PaginateRaw(args ...Result) (Result, error) {
+	source, templatePath, curPage, numPerPage, err := paginateArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if container, ok := source.(ContainerResult); ok {
+		return paginateContainer(container, templatePath, curPage, numPerPage, nil)
+	}
+
+	contentPaths := file.GetContentPaths(string(source.(StringResult)))
+	return Paginate(contentPaths, templatePath, curPage, numPerPage)
+}
+
+// paginateRaw is PaginateRaw for functions called by receiver so the
+// content listed and the template used are recorded as dependencies
+func (receiver *NodeProcessor) paginateRaw(args ...Result) (Result, error) {
+	source, templatePath, curPage, numPerPage, err := paginateArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if container, ok := source.(ContainerResult); ok {
+		return paginateContainer(container, templatePath, curPage, numPerPage, receiver)
+	}
+
+	contentPaths := receiver.getPaths(string(source.(StringResult)))
+	return paginate(contentPaths, templatePath, curPage, numPerPage, receiver)
+}
+
+// paginateArgs returns the content path or container, template path,
+// current page and number per page passed to paginate
+func paginateArgs(args []Result) (Result, string, int, int, error) {
 	if len(args) < 4 {
-		return nil, fmt.Errorf("paginate expects 4 args, got %d", len(args))
+		return nil, "", 0, 0, fmt.Errorf("paginate expects 4 args, got %d", len(args))
 	}
 
-	var filePathString, templatePathString string
+	var templatePathString string
 	var curPageInt, numPerPageInt int
 
 	if curPage, ok := args[0].(IntResult); ok {
 		curPageInt = int(curPage)
 	} else {
-		return nil, fmt.Errorf("expected current page to be an int, got %T", args[0])
+		return nil, "", 0, 0, fmt.Errorf("expected current page to be an int, got %T", args[0])
 	}
 
-	// Path to content to be paginated
-	if filePath, ok := args[1].(StringResult); ok {
-		filePathString = string(filePath)
-	} else {
-		return nil, fmt.Errorf("expected file path to be an string, got %T", args[1])
+	// Path to content or container of values to be paginated
+	source := args[1]
+	switch source.(type) {
+	case StringResult, ContainerResult:
+	default:
+		return nil, "", 0, 0, fmt.Errorf("expected file path to be a string or container, got %T", args[1])
 	}
 
 	// Path to the template to use for each content file on the page
 	if templatePath, ok := args[2].(StringResult); ok {
 		templatePathString = string(templatePath)
 	} else {
-		return nil, fmt.Errorf("expected template path to be an string, got %T", args[2])
+		return nil, "", 0, 0, fmt.Errorf("expected template path to be an string, got %T", args[2])
 	}
 
 	// Number of content items per page
 	if numPerPage, ok := args[3].(IntResult); ok {
 		numPerPageInt = int(numPerPage)
 	} else {
-		return nil, fmt.Errorf("expected number per page to be an int, got %T", args[3])
+		return nil, "", 0, 0, fmt.Errorf("expected number per page to be an int, got %T", args[3])
 	}
 
-	contentPaths := file.GetContentPaths(filePathString)
-	return Paginate(contentPaths, templatePathString, curPageInt, numPerPageInt)
+	return source, templatePathString, curPageInt, numPerPageInt, nil
 }
 
 // PagesBeforeRaw converts its Result type arguments into
@@ -122,80 +156,372 @@ func PagesAfterRaw(args ...Result) (Result, error) {
 	return PagesAfter(curPageInt, numPagesInt, numAfterInt, inputPathStr)
 }
 
+// SafeRaw marks its argument as safe so it is written to html output
+// without being escaped
+func SafeRaw(args ...Result) (Result, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("`safe` expects one argument, got %d", len(args))
+	}
+
+	return SafeResult(args[0].String()), nil
+}
+
+// DefinedRaw reports whether its argument is a variable that is set
+// An undefined variable is passed to it as nil
+func DefinedRaw(args ...Result) (Result, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("`defined` expects one argument, got %d", len(args))
+	}
+
+	return BoolResult(args[0] != nil), nil
+}
+
+// DefaultRaw returns its first argument unless it is undefined or empty,
+// in which case it returns the second like the ?? operator
+func DefaultRaw(args ...Result) (Result, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("`default` expects 2 args, got %d", len(args))
+	}
+
+	if isEmpty(args[0]) {
+		return args[1], nil
+	}
+	return args[0], nil
+}
+
 func TemplateRaw(args ...Result) (Result, error) {
-	var (
-		ret Result
-		err error
-	)
+	templatePath, err := templateArgs(args)
+	if err != nil {
+		return nil, err
+	}
 
+	return Template(templatePath), nil
+}
+
+// templateRaw is TemplateRaw for functions called by receiver so the
+// template is recorded as a dependency
+func (receiver *NodeProcessor) templateRaw(args ...Result) (Result, error) {
+	templatePath, err := templateArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	receiver.recordTemplate(templatePath)
+	return Template(templatePath), nil
+}
+
+// templateArgs returns the template path passed to template
+func templateArgs(args []Result) (string, error) {
 	if len(args) != 1 {
-		err = fmt.Errorf("`template` expects one argument, got %d", len(args))
-	} else if templatePath, ok := args[0].(StringResult); !ok {
-		err = fmt.Errorf("invalid template argument %s", args[0])
+		return "", fmt.Errorf("`template` expects one argument, got %d", len(args))
+	}
+
+	templatePath, ok := args[0].(StringResult)
+	if !ok {
+		return "", fmt.Errorf("invalid template argument %s", args[0])
+	}
+
+	return string(templatePath), nil
+}
+
+// includeRaw converts its Result type arguments into the actual
+// types that Include expects
+// The partial is evaluated with the container passed as the second
+// argument or the caller's context if there isn't one
+func (receiver *NodeProcessor) includeRaw(args ...Result) (Result, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("`include` expects 1 or 2 args, got %d", len(args))
+	}
+
+	var partialPathString string
+	context := receiver.Context
+
+	if partialPath, ok := args[0].(StringResult); ok {
+		partialPathString = string(partialPath)
 	} else {
-		ret = Template(string(templatePath))
+		return nil, fmt.Errorf("expected partial path to be a string, got %T", args[0])
+	}
+
+	if len(args) == 2 {
+		if container, ok := args[1].(ContainerResult); ok {
+			context = container.context
+		} else {
+			return nil, fmt.Errorf("expected partial context to be a container, got %T", args[1])
+		}
 	}
 
-	return ret, err
+	receiver.recordTemplate(partialPathString)
+	return include(partialPathString, context, receiver.includeChain, receiver)
+}
+
+// Include evaluates the cached template at partialPath with a copy of context
+// and returns the rendered output
+// includeChain holds the partials already being included by the caller
+// and is used to detect partials that include each other
+func Include(partialPath string, context *Context, includeChain []string) (Result, error) {
+	return include(partialPath, context, includeChain, nil)
+}
+
+// include is Include for partials included by parent, which can be nil
+// The partial records its dependencies and warnings with parent
+func include(partialPath string, context *Context, includeChain []string, parent *NodeProcessor) (Result, error) {
+	for _, included := range includeChain {
+		if included == partialPath {
+			chain := strings.Join(append(includeChain, partialPath), " -> ")
+			return nil, fmt.Errorf("include: cycle detected %s", chain)
+		}
+	}
+
+	templateNodes := parser.GetTemplateCache().Get(partialPath)
+	if len(*templateNodes) == 0 {
+		return nil, fmt.Errorf("include: template %q not found", partialPath)
+	}
+
+	// the partial is included by many files so its assignments aren't
+	// exported
+	processor := NewNodeProcessor(partialPath, nil, nil, NewReadOnlyExportStore(partialPath), nil, 0, 0)
+	if context != nil {
+		// copy so assignments in the partial don't leak into the caller
+		processor.Context = processor.Context.Merge(context.Copy())
+	}
+	processor.includeChain = append(append([]string{}, includeChain...), partialPath)
+	processor.inheritFrom(parent)
+
+	output := ""
+	for _, node := range *templateNodes {
+		result, err := processor.processHeadNode(node)
+		if err != nil {
+			return nil, inTemplate(partialPath, err)
+		}
+		output += result.String()
+	}
+
+	parent.addWarnings(partialPath, processor.takeWarnings())
+	return processor.partialResult(output), nil
+}
+
+// inheritFrom gives receiver the dependency recorder and undefined
+// variable policy of parent, which can be nil
+// receiver is escaped like parent, starting wherever parent's output
+// has got to, and isn't escaped without a parent
+func (receiver *NodeProcessor) inheritFrom(parent *NodeProcessor) {
+	receiver.escaper = nil
+	if parent != nil {
+		receiver.Dependencies = parent.Dependencies
+		receiver.UndefinedVariables = parent.UndefinedVariables
+		if parent.escaper != nil {
+			receiver.escaper = parent.escaper.copy()
+		}
+	}
+}
+
+// partialResult returns the output of a partial processed by receiver
+// It is only a SafeResult if what the partial printed was escaped
+func (receiver *NodeProcessor) partialResult(output string) Result {
+	if receiver.escaper == nil {
+		return StringResult(output)
+	}
+	return SafeResult(output)
+}
+
+// addWarnings records warnings found in the template at templatePath
+// with receiver, which can be nil
+func (receiver *NodeProcessor) addWarnings(templatePath string, warnings []error) {
+	if receiver == nil {
+		return
+	}
+
+	for _, warning := range warnings {
+		*receiver.warnings = append(*receiver.warnings, inTemplate(templatePath, warning))
+	}
 }
 
 // Paginate creates a context with pagination data to be passed to the specified template
 func Paginate(contentPaths []string, templatePath string, curPage int, numPerPage int) (Result, error) {
+	return paginate(contentPaths, templatePath, curPage, numPerPage, nil)
+}
+
+// paginate is Paginate for pages paginated by parent, which can be nil
+// The template records its dependencies and warnings with parent
+func paginate(contentPaths []string, templatePath string, curPage int, numPerPage int, parent *NodeProcessor) (Result, error) {
 	paginationContext, err := buildPaginationContext(contentPaths, curPage, numPerPage)
 
 	if err != nil {
 		return nil, err
 	}
 
+	return renderPaginationTemplate(paginationContext, templatePath, getContentPathsOnPage(contentPaths, curPage, numPerPage), parent)
+}
+
+// paginateContainer is paginate for the values of container
+// The content the values came from is recorded by whatever built the
+// container
+func paginateContainer(container ContainerResult, templatePath string, curPage int, numPerPage int, parent *NodeProcessor) (Result, error) {
+	paginationContext, err := buildContainerPaginationContext(container, curPage, numPerPage)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return renderPaginationTemplate(paginationContext, templatePath, nil, parent)
+}
+
+// renderPaginationTemplate processes the template at templatePath with
+// paginationContext, recording it and contentPaths as dependencies of
+// parent
+func renderPaginationTemplate(paginationContext *Context, templatePath string, contentPaths []string, parent *NodeProcessor) (Result, error) {
 	templateCache := parser.GetTemplateCache()
 	templateNodes := templateCache.Get(templatePath)
 
+	// the processor sets curPage and numPages in its context so it needs
+	// the values from paginationContext
+	curPage := int((*paginationContext)["curPage"].result.(IntResult))
+	numPages := int((*paginationContext)["numPages"].result.(IntResult))
+
 	output := ""
-	processor := NewNodeProcessor(templatePath, paginationContext, nil, nil, nil, 0, 0)
+	processor := NewNodeProcessor(templatePath, paginationContext, nil, nil, nil, curPage, numPages)
+	processor.inheritFrom(parent)
+	if dependencies := processor.Dependencies; dependencies != nil {
+		dependencies.RecordTemplate(templatePath)
+		for _, contentPath := range contentPaths {
+			dependencies.RecordExport(contentPath)
+		}
+	}
+
 	for _, node := range *templateNodes {
-		result, _ := processor.processHeadNode(node)
+		result, err := processor.processHeadNode(node)
+		if err != nil {
+			return nil, inTemplate(templatePath, err)
+		}
 		output += result.String()
 	}
 
-	return StringResult(output), nil
+	parent.addWarnings(templatePath, processor.takeWarnings())
+	return processor.partialResult(output), nil
 }
 
 func buildPaginationContext(contentPaths []string, curPage int, numPerPage int) (*Context, error) {
-	var (
-		pageContext *Context
-		err         error
-	)
+	if err := checkPagination(curPage, numPerPage); err != nil {
+		return nil, err
+	}
+
+	exportStore := GetExportStore()
+
+	// get the context for each content file on this page
+	contextsOnPage := []*ContextNode{}
+	for _, contentPath := range getContentPathsOnPage(contentPaths, curPage, numPerPage) {
+		contextsOnPage = append(contextsOnPage, &ContextNode{child: exportStore.Get(contentPath)})
+	}
+
+	return newPageContext(curPage, CountPages(len(contentPaths), numPerPage), contextsOnPage), nil
+}
 
+// buildContainerPaginationContext is buildPaginationContext for the
+// values of container in key order
+func buildContainerPaginationContext(container ContainerResult, curPage int, numPerPage int) (*Context, error) {
+	if err := checkPagination(curPage, numPerPage); err != nil {
+		return nil, err
+	}
+
+	items, _ := collectionArg("paginate", container)
+	offset, last := getPageBounds(len(items), curPage, numPerPage)
+	return newPageContext(curPage, CountPages(len(items), numPerPage), items[offset:last]), nil
+}
+
+func checkPagination(curPage int, numPerPage int) error {
 	if numPerPage < 1 {
-		err = fmt.Errorf("expected number of items per page to be > 0, got %d", numPerPage)
+		return fmt.Errorf("expected number of items per page to be > 0, got %d", numPerPage)
 	} else if curPage < 1 {
-		err = fmt.Errorf("expected current page to be > 0, got %d", curPage)
-	} else {
-		numPages := int(math.Ceil(float64(len(contentPaths)) / float64(numPerPage)))
-		exportStore := GetExportStore()
-		// create a page context
-		pageContext = &Context{
-			"curPage":  &ContextNode{result: IntResult(curPage)},
-			"numPages": &ContextNode{result: IntResult(numPages)},
-		}
+		return fmt.Errorf("expected current page to be > 0, got %d", curPage)
+	}
+	return nil
+}
+
+// newPageContext returns the context a pagination template is
+// processed with, content lists the values on the page
+func newPageContext(curPage int, numPages int, contentOnPage []*ContextNode) *Context {
+	return &Context{
+		"curPage":  &ContextNode{result: IntResult(curPage)},
+		"numPages": &ContextNode{result: IntResult(numPages)},
+		"content":  &ContextNode{child: newList(contentOnPage).context},
+	}
+}
+
+// CountPages returns the number of pages numItems are split over with
+// numPerPage on each page
+func CountPages(numItems int, numPerPage int) int {
+	return int(math.Ceil(float64(numItems) / float64(numPerPage)))
+}
+
+// PageCount returns the number of pages the first paginate call in
+// nodes renders, it is false if there is no paginate call
+// The call's arguments are evaluated with receiver's context so they
+// can use front matter but not variables assigned in the file
+func (receiver *NodeProcessor) PageCount(nodes []parser.TreeNode) (int, bool, error) {
+	call := findPaginateCall(nodes)
+	if call == nil {
+		return 0, false, nil
+	}
+
+	args := call.GetArguments()
+	if len(args) != 3 {
+		return 0, false, positionError(call, fmt.Errorf("paginate expects 3 args, got %d", len(args)))
+	}
 
-		// get the paths of the content files that will be on this page
-		offset := minInt(len(contentPaths), (curPage-1)*numPerPage)
-		last := minInt(len(contentPaths), offset+numPerPage)
-		contentPathsOnPage := contentPaths[offset:last]
-
-		// get the context for each content file on this page
-		contextsOnPage := &Context{}
-		for i, contentPath := range contentPathsOnPage {
-			// key content like an array
-			key := fmt.Sprint(i)
-			(*contextsOnPage)[key] = &ContextNode{child: exportStore.Get(contentPath)}
+	source, err := receiver.processHeadNode(args[0])
+	if err != nil {
+		return 0, false, positionError(call, err)
+	}
+
+	numPerPage, err := receiver.processHeadNode(args[2])
+	if err != nil {
+		return 0, false, positionError(call, err)
+	}
+
+	perPage, ok := numPerPage.(IntResult)
+	if !ok || perPage < 1 {
+		return 0, false, positionError(call, fmt.Errorf("expected number of items per page to be > 0, got %s", numPerPage))
+	}
+
+	numItems := 0
+	switch typedSource := source.(type) {
+	case StringResult:
+		numItems = len(receiver.getPaths(string(typedSource)))
+	case ContainerResult:
+		numItems = len(containerKeys(typedSource))
+	default:
+		return 0, false, positionError(call, fmt.Errorf("expected file path to be a string or container, got %T", source))
+	}
+
+	return CountPages(numItems, int(perPage)), true, nil
+}
+
+// findPaginateCall returns the first call to paginate in nodes or nil
+func findPaginateCall(nodes []parser.TreeNode) *parser.FuncCallParseNode {
+	for _, node := range nodes {
+		if call, ok := node.(*parser.FuncCallParseNode); ok && call.GetFuncName() == "paginate" {
+			return call
 		}
 
-		// add content file contexts to pageContext
-		(*pageContext)["content"] = &ContextNode{child: contextsOnPage}
+		if call := findPaginateCall(node.GetChildren()); call != nil {
+			return call
+		}
 	}
-	return pageContext, err
+
+	return nil
+}
+
+// getContentPathsOnPage returns the slice of contentPaths shown on curPage
+func getContentPathsOnPage(contentPaths []string, curPage int, numPerPage int) []string {
+	offset, last := getPageBounds(len(contentPaths), curPage, numPerPage)
+	return contentPaths[offset:last]
+}
+
+// getPageBounds returns the range of the numItems items shown on curPage
+func getPageBounds(numItems int, curPage int, numPerPage int) (int, int) {
+	offset := minInt(numItems, (curPage-1)*numPerPage)
+	last := minInt(numItems, offset+numPerPage)
+	return offset, last
 }
 
 // PagesBefore builds a collection of contexts for the numBefore pages
@@ -239,7 +565,7 @@ func PagesAfter(curPage, numPages, numAfter int, inputPath string) (ContainerRes
 }
 
 // Template loads the content of the specified file
-// and returns it as a StringResult
+// and returns it as a SafeResult so it is output as is
 func Template(templatePath string) Result {
 	config := config.GetLoadedConfig()
 	fullPath := filepath.Join(config.GetTemplatePath(), templatePath)
@@ -250,7 +576,7 @@ func Template(templatePath string) Result {
 		if bytes, err := io.ReadAll(f); err != nil {
 			log.Printf("could not read template file %s\n", fullPath)
 		} else {
-			return StringResult(bytes)
+			return SafeResult(bytes)
 		}
 	}
 
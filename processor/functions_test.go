@@ -182,3 +182,21 @@ func TestPagesAfterReturnsCorrectContexts(t *testing.T) {
 		}
 	}
 }
+
+func TestDefinedAndDefaultCheckTheirArgs(t *testing.T) {
+	var tests = []struct {
+		function func(...Result) (Result, error)
+		args     []Result
+	}{
+		{DefinedRaw, []Result{}},
+		{DefinedRaw, []Result{IntResult(1), IntResult(2)}},
+		{DefaultRaw, []Result{nil}},
+		{DefaultRaw, []Result{nil, IntResult(1), IntResult(2)}},
+	}
+
+	for i, test := range tests {
+		if _, err := test.function(test.args...); err == nil {
+			t.Errorf("%d: expected an error for %d args", i, len(test.args))
+		}
+	}
+}
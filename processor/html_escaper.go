@@ -0,0 +1,553 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// htmlState is where in an html document the escaper is
+type htmlState int
+
+const (
+	htmlText htmlState = iota
+	// just after a '<'
+	htmlTagOpen
+	htmlTagName
+	// inside an end tag like </p>
+	htmlEndTag
+	// inside a start tag between attributes
+	htmlTag
+	htmlAttrName
+	htmlAfterAttrName
+	// after the '=' of an attribute, before its value
+	htmlBeforeValue
+	htmlAttrValue
+	htmlComment
+	// inside <!doctype ...> or <?...>
+	htmlDeclaration
+	htmlScript
+	// inside elements like <style> and <title> that can't hold tags
+	htmlRawText
+)
+
+// attrKind is how an attribute value is interpreted
+type attrKind int
+
+const (
+	attrPlain attrKind = iota
+	attrURL
+	attrJS
+)
+
+// urlPart is the part of a url the escaper is in
+type urlPart int
+
+const (
+	urlStart urlPart = iota
+	urlPath
+	urlQuery
+)
+
+// jsState is where in javascript code the escaper is
+type jsState int
+
+const (
+	jsCode jsState = iota
+	jsDoubleQuote
+	jsSingleQuote
+	jsTemplate
+	jsLineComment
+	jsBlockComment
+)
+
+// unsafeURL replaces urls with schemes that could run code
+const unsafeURL = "#unsafe-url"
+
+// urlAttrs are the attributes whose values are urls
+var urlAttrs = map[string]bool{
+	"action":     true,
+	"background": true,
+	"cite":       true,
+	"codebase":   true,
+	"data":       true,
+	"formaction": true,
+	"href":       true,
+	"icon":       true,
+	"longdesc":   true,
+	"manifest":   true,
+	"poster":     true,
+	"src":        true,
+	"usemap":     true,
+}
+
+// safeSchemes are the url schemes that can be printed into urls
+var safeSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+	"tel":    true,
+}
+
+// rawTextElements are the elements other than <script> whose content
+// is text until their end tag
+var rawTextElements = map[string]bool{
+	"style":    true,
+	"textarea": true,
+	"title":    true,
+}
+
+// htmlEscaper follows the html written to an output so results printed
+// into it can be escaped for where they end up
+// It understands enough html to tell text, attribute values, urls and
+// javascript apart, not to validate it
+type htmlEscaper struct {
+	state   htmlState
+	tagName string
+	// the element whose end tag ends htmlScript or htmlRawText
+	endTag   string
+	attrName string
+	// the quote around the attribute value, 0 if it is unquoted
+	quote   byte
+	kind    attrKind
+	url     urlPart
+	js      jsState
+	escaped bool
+	// the last few bytes written, lowercased
+	tail string
+	// markdown escapes every result as text, markdown sources aren't
+	// html until they are rendered
+	markdown bool
+}
+
+func newHTMLEscaper() *htmlEscaper {
+	return &htmlEscaper{}
+}
+
+func newMarkdownEscaper() *htmlEscaper {
+	return &htmlEscaper{markdown: true}
+}
+
+// copy returns an escaper in the same place as receiver
+func (receiver *htmlEscaper) copy() *htmlEscaper {
+	copied := *receiver
+	return &copied
+}
+
+// write moves receiver past text
+func (receiver *htmlEscaper) write(text string) {
+	if receiver.markdown {
+		return
+	}
+
+	for i := 0; i < len(text); i++ {
+		receiver.writeByte(text[i])
+	}
+}
+
+// escape returns result escaped for where receiver is
+// SafeResults are never escaped
+func (receiver *htmlEscaper) escape(result Result) string {
+	if safe, ok := result.(SafeResult); ok {
+		return string(safe)
+	}
+
+	if receiver.markdown {
+		return escapeMarkdownText(result.String())
+	}
+
+	switch receiver.state {
+	case htmlTagName, htmlTag, htmlAttrName, htmlAfterAttrName:
+		return escapeAttr(result.String(), true)
+	case htmlBeforeValue:
+		// a result right after the '=' starts an unquoted value
+		unquoted := receiver.copy()
+		unquoted.startValue(0)
+		return unquoted.escape(result)
+	case htmlAttrValue:
+		unquoted := receiver.quote == 0
+		switch receiver.kind {
+		case attrURL:
+			return escapeAttr(escapeURL(result.String(), receiver.url), unquoted)
+		case attrJS:
+			return escapeAttr(escapeJS(result, receiver.js), unquoted)
+		default:
+			return escapeAttr(result.String(), unquoted)
+		}
+	case htmlScript:
+		return escapeJS(result, receiver.js)
+	default:
+		return html.EscapeString(result.String())
+	}
+}
+
+func (receiver *htmlEscaper) writeByte(c byte) {
+	receiver.tail += string(lower(c))
+	if len(receiver.tail) > len("</textarea") {
+		receiver.tail = receiver.tail[1:]
+	}
+
+	switch receiver.state {
+	case htmlText:
+		if c == '<' {
+			receiver.state = htmlTagOpen
+		}
+	case htmlTagOpen:
+		switch {
+		case isLetter(c):
+			receiver.state = htmlTagName
+			receiver.tagName = string(lower(c))
+		case c == '/':
+			receiver.state = htmlEndTag
+		case c == '!' || c == '?':
+			receiver.state = htmlDeclaration
+		case c != '<':
+			receiver.state = htmlText
+		}
+	case htmlTagName:
+		switch {
+		case c == '>':
+			receiver.endStartTag()
+		case isSpace(c) || c == '/':
+			receiver.state = htmlTag
+		default:
+			receiver.tagName += string(lower(c))
+		}
+	case htmlEndTag:
+		if c == '>' {
+			receiver.state = htmlText
+		}
+	case htmlTag:
+		switch {
+		case c == '>':
+			receiver.endStartTag()
+		case !isSpace(c) && c != '/':
+			receiver.state = htmlAttrName
+			receiver.attrName = string(lower(c))
+		}
+	case htmlAttrName:
+		switch {
+		case c == '>':
+			receiver.endStartTag()
+		case c == '=':
+			receiver.state = htmlBeforeValue
+		case isSpace(c):
+			receiver.state = htmlAfterAttrName
+		case c == '/':
+			receiver.state = htmlTag
+		default:
+			receiver.attrName += string(lower(c))
+		}
+	case htmlAfterAttrName:
+		switch {
+		case c == '>':
+			receiver.endStartTag()
+		case c == '=':
+			receiver.state = htmlBeforeValue
+		case c == '/':
+			receiver.state = htmlTag
+		case !isSpace(c):
+			receiver.state = htmlAttrName
+			receiver.attrName = string(lower(c))
+		}
+	case htmlBeforeValue:
+		switch {
+		case c == '>':
+			receiver.endStartTag()
+		case c == '"' || c == '\'':
+			receiver.startValue(c)
+		case !isSpace(c):
+			receiver.startValue(0)
+			receiver.writeValueByte(c)
+		}
+	case htmlAttrValue:
+		switch {
+		case receiver.quote != 0 && c == receiver.quote:
+			receiver.state = htmlTag
+		case receiver.quote == 0 && isSpace(c):
+			receiver.state = htmlTag
+		case receiver.quote == 0 && c == '>':
+			receiver.endStartTag()
+		default:
+			receiver.writeValueByte(c)
+		}
+	case htmlComment:
+		if strings.HasSuffix(receiver.tail, "-->") {
+			receiver.state = htmlText
+		}
+	case htmlDeclaration:
+		if strings.HasSuffix(receiver.tail, "<!--") {
+			receiver.state = htmlComment
+			// so the dashes of <!-- don't also close the comment
+			receiver.tail = ""
+		} else if c == '>' {
+			receiver.state = htmlText
+		}
+	case htmlScript, htmlRawText:
+		if strings.HasSuffix(receiver.tail, "</"+receiver.endTag) {
+			receiver.state = htmlEndTag
+		} else if receiver.state == htmlScript {
+			receiver.writeJSByte(c)
+		}
+	}
+}
+
+// endStartTag moves past the '>' of a start tag into its content
+func (receiver *htmlEscaper) endStartTag() {
+	receiver.state = htmlText
+
+	if receiver.tagName == "script" {
+		receiver.state = htmlScript
+		receiver.js = jsCode
+		receiver.endTag = receiver.tagName
+	} else if rawTextElements[receiver.tagName] {
+		receiver.state = htmlRawText
+		receiver.endTag = receiver.tagName
+	}
+}
+
+// startValue moves to the start of the value of the current attribute
+// quote is 0 for unquoted values
+func (receiver *htmlEscaper) startValue(quote byte) {
+	receiver.state = htmlAttrValue
+	receiver.quote = quote
+	receiver.url = urlStart
+	receiver.js = jsCode
+	receiver.escaped = false
+
+	name := receiver.attrName
+	switch {
+	case strings.HasPrefix(name, "on"):
+		receiver.kind = attrJS
+	case urlAttrs[name] || strings.Contains(name, "url") || strings.Contains(name, "uri"):
+		receiver.kind = attrURL
+	default:
+		receiver.kind = attrPlain
+	}
+}
+
+func (receiver *htmlEscaper) writeValueByte(c byte) {
+	switch receiver.kind {
+	case attrURL:
+		if c == '?' || c == '#' {
+			receiver.url = urlQuery
+		} else if receiver.url == urlStart {
+			receiver.url = urlPath
+		}
+	case attrJS:
+		receiver.writeJSByte(c)
+	}
+}
+
+func (receiver *htmlEscaper) writeJSByte(c byte) {
+	previous := byte(0)
+	if len(receiver.tail) > 1 {
+		previous = receiver.tail[len(receiver.tail)-2]
+	}
+
+	switch receiver.js {
+	case jsCode:
+		switch {
+		case c == '"':
+			receiver.js = jsDoubleQuote
+		case c == '\'':
+			receiver.js = jsSingleQuote
+		case c == '`':
+			receiver.js = jsTemplate
+		case c == '/' && previous == '/':
+			receiver.js = jsLineComment
+		case c == '*' && previous == '/':
+			receiver.js = jsBlockComment
+		}
+	case jsDoubleQuote, jsSingleQuote, jsTemplate:
+		if receiver.escaped {
+			receiver.escaped = false
+		} else if c == '\\' {
+			receiver.escaped = true
+		} else if c == closingQuote(receiver.js) {
+			receiver.js = jsCode
+		}
+	case jsLineComment:
+		if c == '\n' {
+			receiver.js = jsCode
+		}
+	case jsBlockComment:
+		if c == '/' && previous == '*' {
+			receiver.js = jsCode
+		}
+	}
+}
+
+// closingQuote returns the quote that ends the javascript string state
+func closingQuote(state jsState) byte {
+	switch state {
+	case jsDoubleQuote:
+		return '"'
+	case jsSingleQuote:
+		return '\''
+	default:
+		return '`'
+	}
+}
+
+// escapeAttr escapes value for an attribute value, unquoted values also
+// have the characters that would end them escaped
+func escapeAttr(value string, unquoted bool) string {
+	escaped := html.EscapeString(value)
+	if !unquoted {
+		return escaped
+	}
+
+	builder := strings.Builder{}
+	for _, r := range escaped {
+		switch r {
+		case ' ', '\t', '\n', '\r', '\f', '=', '`':
+			fmt.Fprintf(&builder, "&#%d;", r)
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// escapeURL escapes value for part of a url
+// A url printed at the start of an attribute can't have a scheme that
+// runs code like javascript:
+func escapeURL(value string, part urlPart) string {
+	if part == urlQuery {
+		return url.QueryEscape(value)
+	}
+
+	if part == urlStart {
+		if colon := strings.IndexByte(value, ':'); colon != -1 && !strings.ContainsAny(value[:colon], "/?#") {
+			if !safeSchemes[strings.ToLower(value[:colon])] {
+				return unsafeURL
+			}
+		}
+	}
+
+	// percent encode everything that isn't allowed in a url
+	builder := strings.Builder{}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if isLetter(c) || isDigit(c) || strings.IndexByte("-._~:/?#[]@!$&'()*+,;=%", c) != -1 {
+			builder.WriteByte(c)
+		} else {
+			fmt.Fprintf(&builder, "%%%02X", c)
+		}
+	}
+	return builder.String()
+}
+
+// escapeJS escapes result for javascript, as the contents of a string
+// or comment or otherwise as a json value
+func escapeJS(result Result, state jsState) string {
+	if state != jsCode {
+		return escapeJSString(result.String())
+	}
+
+	encoded, err := json.Marshal(jsonValue(result))
+	if err != nil {
+		return "null"
+	}
+	return string(encoded)
+}
+
+// escapeJSString escapes value for the inside of a javascript string
+// Characters that could end the string or the script are written as
+// escape sequences
+func escapeJSString(value string) string {
+	builder := strings.Builder{}
+	for _, r := range value {
+		switch r {
+		case '\\':
+			builder.WriteString(`\\`)
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '"', '\'', '`', '<', '>', '&', '$', '/':
+			fmt.Fprintf(&builder, `\x%02x`, r)
+		case '\u2028', '\u2029':
+			fmt.Fprintf(&builder, `\u%04x`, r)
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// jsonValue returns result as a value encoding/json can marshal
+// Containers keyed like lists become arrays and the rest objects
+func jsonValue(result Result) interface{} {
+	switch typedResult := result.(type) {
+	case StringResult:
+		return string(typedResult)
+	case SafeResult:
+		return string(typedResult)
+	case IntResult:
+		return int(typedResult)
+	case FloatResult:
+		return float64(typedResult)
+	case BoolResult:
+		return bool(typedResult)
+	case ContainerResult:
+		return jsonContainer(typedResult.context)
+	case nil:
+		return nil
+	default:
+		return result.String()
+	}
+}
+
+func jsonContainer(context *Context) interface{} {
+	if context == nil {
+		return map[string]interface{}{}
+	}
+
+	keys := context.Keys()
+	values := make([]interface{}, len(keys))
+	isList := true
+	for i, key := range keys {
+		node := (*context)[key]
+		if node.HasResult() {
+			values[i] = jsonValue(node.result)
+		} else {
+			values[i] = jsonContainer(node.child)
+		}
+		isList = isList && key == strconv.Itoa(i)
+	}
+
+	if isList && len(keys) > 0 {
+		return values
+	}
+
+	object := make(map[string]interface{}, len(keys))
+	for i, key := range keys {
+		object[key] = values[i]
+	}
+	return object
+}
+
+func lower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func isLetter(c byte) bool {
+	c = lower(c)
+	return 'a' <= c && c <= 'z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
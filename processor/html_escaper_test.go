@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"testing"
+)
+
+func TestHTMLEscaperEscapesForContext(t *testing.T) {
+	var tests = []struct {
+		before   string
+		value    Result
+		expected string
+	}{
+		{"<p>", StringResult("<b>&</b>"), "&lt;b&gt;&amp;&lt;/b&gt;"},
+		{"<p>", SafeResult("<b>&</b>"), "<b>&</b>"},
+		{"<p>", IntResult(5), "5"},
+		{`<a title="`, StringResult(`say "hi"`), "say &#34;hi&#34;"},
+		{`<a title='`, StringResult("it's"), "it&#39;s"},
+		{"<a title=", StringResult("a b>"), "a&#32;b&gt;"},
+		{`<a href="`, StringResult("javascript:alert(1)"), "#unsafe-url"},
+		{`<a href="`, StringResult("https://example.com/a b"), "https://example.com/a%20b"},
+		{`<a href="/search?q=`, StringResult("a&b c"), "a%26b+c"},
+		{`<a href="`, StringResult("/posts/1"), "/posts/1"},
+		{`<button onclick="go(`, StringResult(`"x"`), "&#34;\\&#34;x\\&#34;&#34;"},
+		{"<script>var x = ", StringResult("</script>"), `"\u003c/script\u003e"`},
+		{"<script>var x = ", IntResult(3), "3"},
+		{"<script>var x = '", StringResult("'; alert(1)"), `\x27; alert(1)`},
+		{"<title>", StringResult("<b>"), "&lt;b&gt;"},
+		{"<!-- ", StringResult("<b>"), "&lt;b&gt;"},
+		{"<script>var a = '</script><p>", StringResult("<b>"), "&lt;b&gt;"},
+	}
+
+	for i, test := range tests {
+		escaper := newHTMLEscaper()
+		escaper.write(test.before)
+		escaped := escaper.escape(test.value)
+
+		if escaped != test.expected {
+			t.Errorf("%d: expected %s, got %s", i, test.expected, escaped)
+		}
+	}
+}
+
+func TestHTMLEscaperCopyDoesNotChangeOriginal(t *testing.T) {
+	escaper := newHTMLEscaper()
+	escaper.write("<p>")
+	copied := escaper.copy()
+	copied.write(`<a href="`)
+
+	if escaped := escaper.escape(StringResult("javascript:x")); escaped != "javascript:x" {
+		t.Errorf("expected original escaper to stay in text, got %s", escaped)
+	}
+	if escaped := copied.escape(StringResult("javascript:x")); escaped != unsafeURL {
+		t.Errorf("expected copied escaper to be in a url, got %s", escaped)
+	}
+}
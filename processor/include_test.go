@@ -0,0 +1,122 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+
+	"mettlach.codes/frizzy/config"
+)
+
+func TestIncludeProcessesPartialWithContext(t *testing.T) {
+	cacheTemplate(t, "partials/card.html", `<div>{{: title}}</div>{{title = "changed"}}`)
+	cacheTemplate(t, "partials/list.html", `<ul>{{: include("partials/card.html")}}</ul>`)
+
+	var tests = []struct {
+		page     string
+		expected string
+	}{
+		{`{{title = "caller"}}{{: include("partials/card.html")}}`, `<div>caller</div>`},
+		{`{{post.title = "post"}}{{: include("partials/card.html", post)}}`, `<div>post</div>`},
+		{`{{title = "nested"}}{{: include("partials/list.html")}}`, `<ul><div>nested</div></ul>`},
+		{`{{title = "kept"}}{{include("partials/card.html")}}{{: title}}`, `kept`},
+	}
+
+	for i, test := range tests {
+		got, err := processText(t, test.page)
+
+		if err != nil {
+			t.Errorf("%d: expected no errors, got %q", i, err)
+		} else if got != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, got)
+		}
+	}
+}
+
+func TestIncludeDoesNotExportPartialAssignments(t *testing.T) {
+	cacheTemplate(t, "partials/assign.html", `{{title = "partial"}}{{: title}}`)
+
+	if _, err := processText(t, `{{: include("partials/assign.html")}}`); err != nil {
+		t.Fatalf("expected no errors, got %q", err)
+	}
+
+	if exports := GetExportStore().Get("partials/assign.html"); exports != nil {
+		t.Errorf("expected the partial not to export anything, got %v", *exports)
+	}
+}
+
+func TestIncludeReturnsErrors(t *testing.T) {
+	cacheTemplate(t, "partials/cycle_a.html", `{{: include("partials/cycle_b.html")}}`)
+	cacheTemplate(t, "partials/cycle_b.html", `{{: include("partials/cycle_a.html")}}`)
+
+	var tests = []struct {
+		page     string
+		expected string
+	}{
+		{`{{: include("partials/missing.html")}}`, `include: template "partials/missing.html" not found`},
+		{`{{: include("partials/cycle_a.html")}}`, "include: cycle detected partials/cycle_a.html -> partials/cycle_b.html -> partials/cycle_a.html"},
+		{`{{: include(1)}}`, "expected partial path to be a string"},
+		{`{{: include("partials/cycle_a.html", "a")}}`, "expected partial context to be a container"},
+		{`{{: include()}}`, "`include` expects 1 or 2 args, got 0"},
+	}
+
+	for i, test := range tests {
+		_, err := processText(t, test.page)
+
+		if err == nil {
+			t.Errorf("%d: expected error %q, got nil", i, test.expected)
+		} else if !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%d: expected error %q, got %q", i, test.expected, err)
+		}
+	}
+}
+
+// namespacedExportStore is a TestExportStore for the file at namespace
+type namespacedExportStore struct {
+	TestExportStore
+	namespace string
+}
+
+func (receiver *namespacedExportStore) GetNamespace() string { return receiver.namespace }
+
+func TestWarningsAreAttributedToTheirTemplate(t *testing.T) {
+	cacheTemplate(t, "partials/warn.html", `<p>{{: partialMissing}}</p>`)
+	cacheTemplate(t, "warn/base.html", `{{: layoutMissing}}{{block "main"}}{{end}}`)
+
+	var tests = []struct {
+		page     string
+		expected []string
+	}{
+		{
+			`{{: include("partials/warn.html")}}`,
+			[]string{`partials/warn.html:1:8: warning: undefined variable "partialMissing"`},
+		},
+		{
+			`{{extends "warn/base.html"}}{{block "main"}}{{: pageMissing}}{{end}}`,
+			[]string{
+				`warn/base.html:1:5: warning: undefined variable "layoutMissing"`,
+				`pages/page.html:1:49: warning: undefined variable "pageMissing"`,
+			},
+		},
+	}
+
+	for i, test := range tests {
+		exportStore := &namespacedExportStore{namespace: "pages/page.html"}
+		processor := NewNodeProcessor("", &Context{}, nil, exportStore, nil, 0, 0)
+		processor.UndefinedVariables = config.UndefinedWarn
+
+		for _, node := range parseText(t, test.page) {
+			if _, err := processor.processHeadNode(node); err != nil {
+				t.Errorf("%d: expected no errors, got %q", i, err)
+			}
+		}
+
+		actual := []string{}
+		for _, warning := range processor.takeWarnings() {
+			actual = append(actual, warning.Error())
+		}
+
+		if strings.Join(actual, "|") != strings.Join(test.expected, "|") {
+			t.Errorf("%d: expected warnings %q, got %q", i, test.expected, actual)
+		}
+	}
+}
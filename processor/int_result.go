@@ -1,9 +1,6 @@
 package processor
 
-import (
-	"fmt"
-	"strconv"
-)
+import "strconv"
 
 // IntResult represents a result containing an integer value
 type IntResult int
@@ -24,10 +21,12 @@ func (receiver IntResult) Add(right Result) (Result, error) {
 	switch typedRight := right.(type) {
 	case IntResult:
 		return IntResult(receiver + typedRight), nil
+	case FloatResult:
+		return FloatResult(receiver) + typedRight, nil
 	case StringResult:
 		return StringResult(strconv.Itoa(int(receiver)) + string(typedRight)), nil
 	default:
-		return nil, fmt.Errorf("Cannot add a %T to a %T", receiver, right)
+		return nil, newBinaryError("+", receiver, right)
 	}
 }
 
@@ -38,8 +37,10 @@ func (receiver IntResult) Subtract(right Result) (Result, error) {
 	switch typedRight := right.(type) {
 	case IntResult:
 		return IntResult(receiver - typedRight), nil
+	case FloatResult:
+		return FloatResult(receiver) - typedRight, nil
 	default:
-		return nil, fmt.Errorf("Cannt subtract %T and %T", receiver, right)
+		return nil, newBinaryError("-", receiver, right)
 	}
 }
 
@@ -49,20 +50,44 @@ func (receiver IntResult) Multiply(right Result) (Result, error) {
 	switch typedRight := right.(type) {
 	case IntResult:
 		return IntResult(receiver * typedRight), nil
+	case FloatResult:
+		return FloatResult(receiver) * typedRight, nil
 	default:
-		return nil, fmt.Errorf(("Cannot multiply a %T and a %T"), receiver, right)
+		return nil, newBinaryError("*", receiver, right)
 	}
 }
 
 // Divide takes a result and divides it with this integer representation
 // Returns a Result type or an error if right cannot be divided with receiver
-// Floats are currently not supported so all division is integer divison
+// Dividing two ints is integer division, dividing by a float is not
 func (receiver IntResult) Divide(right Result) (Result, error) {
 	switch typedRight := right.(type) {
 	case IntResult:
+		if typedRight == 0 {
+			return nil, newDivisionByZeroError("/", receiver)
+		}
 		return IntResult(receiver / typedRight), nil
+	case FloatResult:
+		return FloatResult(receiver) / typedRight, nil
+	default:
+		return nil, newBinaryError("/", receiver, right)
+	}
+}
+
+// Mod takes a result and returns the remainder of dividing this integer
+// representation by it
+// The remainder has the same sign as receiver, so -7 % 2 is -1
+func (receiver IntResult) Mod(right Result) (Result, error) {
+	switch typedRight := right.(type) {
+	case IntResult:
+		if typedRight == 0 {
+			return nil, newDivisionByZeroError("%", receiver)
+		}
+		return IntResult(receiver % typedRight), nil
+	case FloatResult:
+		return FloatResult(receiver).Mod(typedRight)
 	default:
-		return nil, fmt.Errorf("Cannot divide a %T and a %T", receiver, right)
+		return nil, newBinaryError("%", receiver, right)
 	}
 }
 
@@ -83,55 +108,73 @@ func convertToInt(right Result) (int, bool) {
 // EqualTo checks if the provided result is logically equal to
 // the receiver
 func (receiver IntResult) EqualTo(right Result) (Result, error) {
+	if _, ok := right.(FloatResult); ok {
+		return FloatResult(receiver).EqualTo(right)
+	}
 	if rightInt, ok := convertToInt(right); ok {
 		return BoolResult(int(receiver) == rightInt), nil
 	}
-	return nil, fmt.Errorf("Cannot determine %T == %T", receiver, right)
+	return nil, newBinaryError("==", receiver, right)
 }
 
 // NotEqualTo checks if the provided result is logically not equal
 // to the receiver
 func (receiver IntResult) NotEqualTo(right Result) (Result, error) {
+	if _, ok := right.(FloatResult); ok {
+		return FloatResult(receiver).NotEqualTo(right)
+	}
 	if rightInt, ok := convertToInt(right); ok {
 		return BoolResult(int(receiver) != rightInt), nil
 	}
-	return nil, fmt.Errorf("Cannot determine %T != %T", receiver, right)
+	return nil, newBinaryError("!=", receiver, right)
 }
 
 // LessThan checks if the provided result is logically less than
 // the receiver
 func (receiver IntResult) LessThan(right Result) (Result, error) {
+	if _, ok := right.(FloatResult); ok {
+		return FloatResult(receiver).LessThan(right)
+	}
 	if rightInt, ok := convertToInt(right); ok {
 		return BoolResult(int(receiver) < rightInt), nil
 	}
-	return nil, fmt.Errorf("Cannot determine %T < %T", receiver, right)
+	return nil, newBinaryError("<", receiver, right)
 }
 
 // GreaterThan checks if the provided result is logically greater than
 // the receiver
 func (receiver IntResult) GreaterThan(right Result) (Result, error) {
+	if _, ok := right.(FloatResult); ok {
+		return FloatResult(receiver).GreaterThan(right)
+	}
 	if rightInt, ok := convertToInt(right); ok {
 		return BoolResult(int(receiver) > rightInt), nil
 	}
-	return nil, fmt.Errorf("Cannot determine %T > %T", receiver, right)
+	return nil, newBinaryError(">", receiver, right)
 }
 
 // LessThanEqual checks if the provided result is logically less than or equal to
 // the receiver
 func (receiver IntResult) LessThanEqual(right Result) (Result, error) {
+	if _, ok := right.(FloatResult); ok {
+		return FloatResult(receiver).LessThanEqual(right)
+	}
 	if rightInt, ok := convertToInt(right); ok {
 		return BoolResult(int(receiver) <= rightInt), nil
 	}
-	return nil, fmt.Errorf("Cannot determine %T <= %T", receiver, right)
+	return nil, newBinaryError("<=", receiver, right)
 }
 
 // GreaterThanEqual checks if the provided result is logically greater than or equal to
 // the receiver
 func (receiver IntResult) GreaterThanEqual(right Result) (Result, error) {
+	if _, ok := right.(FloatResult); ok {
+		return FloatResult(receiver).GreaterThanEqual(right)
+	}
 	if rightInt, ok := convertToInt(right); ok {
 		return BoolResult(int(receiver) >= rightInt), nil
 	}
-	return nil, fmt.Errorf("Cannot determine %T >= %T", receiver, right)
+	return nil, newBinaryError(">=", receiver, right)
 }
 
 // Negative switches the sign of this number
@@ -130,3 +130,25 @@ func TestGreaterThanOrEqualTo(t *testing.T) {
 		}
 	}
 }
+
+func TestDivisionByZero(t *testing.T) {
+	var tests = []struct {
+		left     Result
+		operator string
+		right    Result
+	}{
+		{IntResult(7), "/", IntResult(0)},
+		{IntResult(7), "%", IntResult(0)},
+		{IntResult(7), "%", FloatResult(0)},
+		{FloatResult(7.5), "%", IntResult(0)},
+		{FloatResult(7.5), "%", FloatResult(0)},
+	}
+
+	for i, test := range tests {
+		_, err := processMultiplication(test.left, test.right, test.operator)
+
+		if _, ok := err.(*DivisionByZeroError); !ok {
+			t.Errorf("%d: expected a division by zero error for %v %s %v, got %v", i, test.left, test.operator, test.right, err)
+		}
+	}
+}
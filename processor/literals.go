@@ -0,0 +1,101 @@
+package processor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"mettlach.codes/frizzy/parser"
+)
+
+// processListLiteral evaluates each element of node into a container
+// keyed by the element's index
+func (receiver *NodeProcessor) processListLiteral(node *parser.ListLiteralParseNode) (Result, error) {
+	context := &Context{}
+
+	for i, element := range node.GetElements() {
+		result, err := receiver.processHeadNode(element)
+		if err != nil {
+			return nil, err
+		}
+
+		context.Insert([]string{strconv.Itoa(i)}, result)
+	}
+
+	return ContainerResult{context}, nil
+}
+
+// processMapLiteral evaluates each entry of node into a container
+// Later entries overwrite earlier ones with the same key
+func (receiver *NodeProcessor) processMapLiteral(node *parser.MapLiteralParseNode) (Result, error) {
+	context := &Context{}
+
+	for _, entry := range node.GetEntries() {
+		result, err := receiver.processHeadNode(entry.GetValue())
+		if err != nil {
+			return nil, err
+		}
+
+		context.Insert([]string{entry.GetKey()}, result)
+	}
+
+	return ContainerResult{context}, nil
+}
+
+// getVarNameKeys returns the context keys of node evaluating any index
+// expressions e.g. posts[i + 1].title with i = 0 is ["posts", "1", "title"]
+func (receiver *NodeProcessor) getVarNameKeys(node *parser.VarNameParseNode) ([]string, error) {
+	return receiver.getKeys(node.GetKeys())
+}
+
+// getKeys returns the context keys named by the idents in keyNodes
+// and given by evaluating the other nodes
+func (receiver *NodeProcessor) getKeys(keyNodes []parser.TreeNode) ([]string, error) {
+	keys := make([]string, 0, len(keyNodes))
+
+	for _, keyNode := range keyNodes {
+		if ident, ok := keyNode.(*parser.IdentParseNode); ok {
+			keys = append(keys, ident.Value)
+			continue
+		}
+
+		index, err := receiver.processHeadNode(keyNode)
+		if err != nil {
+			return nil, err
+		}
+
+		switch typedIndex := index.(type) {
+		case IntResult, StringResult:
+			keys = append(keys, typedIndex.String())
+		default:
+			return nil, fmt.Errorf("cannot index with %s, index must be an int or a string", typeName(index))
+		}
+	}
+
+	return keys, nil
+}
+
+// processIndex looks up the keys of node in the container its target
+// evaluates to
+func (receiver *NodeProcessor) processIndex(node *parser.IndexParseNode) (Result, error) {
+	target, err := receiver.processHeadNode(node.GetTarget())
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := receiver.getKeys(node.GetKeys())
+	if err != nil {
+		return nil, err
+	}
+
+	container, ok := target.(ContainerResult)
+	if !ok {
+		return nil, fmt.Errorf("cannot index %s, only lists and maps can be indexed", typeName(target))
+	}
+
+	contextNode, ok := container.context.AtNested(keys)
+	if !ok {
+		return receiver.undefined(node, fmt.Errorf("undefined key %q", strings.Join(keys, ".")))
+	}
+	return nodeResult(contextNode), nil
+}
@@ -11,10 +11,44 @@ import (
 	"mettlach.codes/frizzy/file"
 )
 
+// markdownPlaceholders stand in for the characters html escapes in
+// values printed into markdown, they are unicode noncharacters so the
+// renderer leaves them alone wherever they are and they can't clash
+// with the source
+var markdownPlaceholders = strings.NewReplacer(
+	"&", "\ufdd0",
+	"<", "\ufdd1",
+	">", "\ufdd2",
+	`"`, "\ufdd3",
+	"'", "\ufdd4",
+)
+
+// markdownEntities replaces markdownPlaceholders once the markdown is html
+var markdownEntities = strings.NewReplacer(
+	"\ufdd0", "&amp;",
+	"\ufdd1", "&lt;",
+	"\ufdd2", "&gt;",
+	"\ufdd3", "&#34;",
+	"\ufdd4", "&#39;",
+)
+
+// escapeMarkdownText escapes text printed into markdown so it is
+// escaped exactly once in the html, in prose as well as in code
+func escapeMarkdownText(text string) string {
+	return markdownPlaceholders.Replace(text)
+}
+
+// isMarkdown reports whether the file at inputPath is rendered from markdown
+func isMarkdown(inputPath string) bool {
+	return filepath.Ext(inputPath) == ".md"
+}
+
 // Call turns a processed markdown result into a processed html result
+// The html is a SafeResult since values printed in the markdown were
+// escaped by the processor, markdown itself passes html through as is
 // If the input is not markdown, it is passed through
 func PostProcessMarkdown(inputPath string, resultChan <-chan Result) <-chan Result {
-	if filepath.Ext(inputPath) == ".md" {
+	if isMarkdown(inputPath) {
 		postProcessChan := make(chan Result)
 		go func() {
 			defer close(postProcessChan)
@@ -22,7 +56,7 @@ func PostProcessMarkdown(inputPath string, resultChan <-chan Result) <-chan Resu
 				parser := parser.NewWithExtensions(parser.FencedCode)
 				inputBytes := []byte(result.String())
 				mdBytes := string(markdown.ToHTML(inputBytes, parser, nil))
-				postProcessChan <- StringResult(mdBytes)
+				postProcessChan <- SafeResult(markdownEntities.Replace(mdBytes))
 			}
 		}()
 
@@ -40,6 +74,12 @@ func getFullOutputPath(inputPath string) string {
 	return filepath.Join(outputPath, relativeInputPath)
 }
 
+// isHTMLOutput reports whether the file at inputPath is written as html
+// A processor without a file isn't written anywhere
+func isHTMLOutput(inputPath string) bool {
+	return inputPath != "" && filepath.Ext(GetMarkdownOutputPath(inputPath, 0)) == ".html"
+}
+
 // GetMarkdownOutputPath returns the html output path given
 // the input path of a file and optional current page number
 func GetMarkdownOutputPath(inputPath string, curPage int) string {
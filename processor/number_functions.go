@@ -0,0 +1,197 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RoundRaw rounds its first argument to the nearest int or, when given
+// a number of decimal places, to the nearest float with that many places
+func RoundRaw(args ...Result) (Result, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, fmt.Errorf("`round` expects 1 or 2 args, got %d", len(args))
+	}
+
+	num, err := numberArg("round", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 1 {
+		return IntResult(math.Round(num)), nil
+	}
+
+	places, err := placesArg("round", args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(num, 'f', places, 64), 64)
+	return FloatResult(rounded), nil
+}
+
+// FloorRaw returns the largest int less than or equal to its argument
+func FloorRaw(args ...Result) (Result, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("`floor` expects one argument, got %d", len(args))
+	}
+
+	num, err := numberArg("floor", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return IntResult(math.Floor(num)), nil
+}
+
+// CeilRaw returns the smallest int greater than or equal to its argument
+func CeilRaw(args ...Result) (Result, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("`ceil` expects one argument, got %d", len(args))
+	}
+
+	num, err := numberArg("ceil", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return IntResult(math.Ceil(num)), nil
+}
+
+// FixedRaw formats a number with exactly the given number of decimal
+// places e.g. fixed(4.5, 2) is "4.50"
+func FixedRaw(args ...Result) (Result, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("`fixed` expects 2 args, got %d", len(args))
+	}
+
+	num, err := numberArg("fixed", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	places, err := placesArg("fixed", args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return StringResult(strconv.FormatFloat(num, 'f', places, 64)), nil
+}
+
+// FormatNumberRaw formats a number with commas between each group of
+// thousands and, when given, a fixed number of decimal places
+// e.g. formatNumber(1234.5, 2) is "1,234.50"
+func FormatNumberRaw(args ...Result) (Result, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, fmt.Errorf("`formatNumber` expects 1 or 2 args, got %d", len(args))
+	}
+
+	num, err := numberArg("formatNumber", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	places := -1
+	if len(args) == 2 {
+		if places, err = placesArg("formatNumber", args[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	return StringResult(groupThousands(strconv.FormatFloat(num, 'f', places, 64))), nil
+}
+
+// maxRangeLength is the most numbers range will return so a mistyped
+// bound can't stall a build
+const maxRangeLength = 100000
+
+// RangeRaw returns a list of ints from start up to but not including
+// end, counting by step
+// range(end) starts at 0 and range(start, end) counts by 1, a negative
+// step counts down
+func RangeRaw(args ...Result) (Result, error) {
+	if len(args) < 1 || len(args) > 3 {
+		return nil, fmt.Errorf("`range` expects 1 to 3 args, got %d", len(args))
+	}
+
+	bounds := []int{0, 0, 1}
+	if len(args) == 1 {
+		args = []Result{IntResult(0), args[0]}
+	}
+
+	for i, arg := range args {
+		bound, ok := arg.(IntResult)
+		if !ok {
+			return nil, fmt.Errorf("`range` expects int args, got %s", typeName(arg))
+		}
+		bounds[i] = int(bound)
+	}
+
+	start, end, step := bounds[0], bounds[1], bounds[2]
+	if step == 0 {
+		return nil, fmt.Errorf("`range` step cannot be 0")
+	}
+
+	length := 0
+	if step > 0 && end > start {
+		length = (end - start + step - 1) / step
+	} else if step < 0 && end < start {
+		length = (start - end - step - 1) / -step
+	}
+
+	if length > maxRangeLength {
+		return nil, fmt.Errorf("`range` would return %d numbers, the most it can return is %d", length, maxRangeLength)
+	}
+
+	context := &Context{}
+	for i := 0; i < length; i++ {
+		context.Insert([]string{strconv.Itoa(i)}, IntResult(start+i*step))
+	}
+
+	return ContainerResult{context}, nil
+}
+
+// groupThousands adds commas between each group of three digits before
+// the decimal point of formatted
+func groupThousands(formatted string) string {
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign, formatted = "-", formatted[1:]
+	}
+
+	whole, fraction := formatted, ""
+	if point := strings.Index(formatted, "."); point != -1 {
+		whole, fraction = formatted[:point], formatted[point:]
+	}
+
+	grouped := whole[:len(whole)%3]
+	for i := len(whole) % 3; i < len(whole); i += 3 {
+		if grouped != "" {
+			grouped += ","
+		}
+		grouped += whole[i : i+3]
+	}
+
+	return sign + grouped + fraction
+}
+
+// numberArg returns arg as a float64 if it is a number or a string
+// containing one
+func numberArg(funcName string, arg Result) (float64, error) {
+	if num, ok := convertToFloat(arg); ok {
+		return num, nil
+	}
+
+	return 0, fmt.Errorf("`%s` expects a number, got %s", funcName, typeName(arg))
+}
+
+// placesArg returns arg as a number of decimal places
+func placesArg(funcName string, arg Result) (int, error) {
+	if places, ok := arg.(IntResult); ok && places >= 0 {
+		return int(places), nil
+	}
+
+	return 0, fmt.Errorf("`%s` expects decimal places to be an int of at least 0, got %s", funcName, arg)
+}
@@ -0,0 +1,104 @@
+package processor
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestNumberFunctions(t *testing.T) {
+	var tests = []struct {
+		function func(...Result) (Result, error)
+		args     []Result
+		expected Result
+	}{
+		{RoundRaw, []Result{FloatResult(2.5)}, IntResult(3)},
+		{RoundRaw, []Result{FloatResult(-2.4)}, IntResult(-2)},
+		{RoundRaw, []Result{IntResult(7)}, IntResult(7)},
+		{RoundRaw, []Result{FloatResult(3.14159), IntResult(2)}, FloatResult(3.14)},
+		{RoundRaw, []Result{StringResult("2.675"), IntResult(1)}, FloatResult(2.7)},
+		{FloorRaw, []Result{FloatResult(2.9)}, IntResult(2)},
+		{FloorRaw, []Result{FloatResult(-2.1)}, IntResult(-3)},
+		{CeilRaw, []Result{FloatResult(2.1)}, IntResult(3)},
+		{CeilRaw, []Result{IntResult(4)}, IntResult(4)},
+		{FixedRaw, []Result{FloatResult(4.5), IntResult(2)}, StringResult("4.50")},
+		{FixedRaw, []Result{IntResult(3), IntResult(1)}, StringResult("3.0")},
+		{FixedRaw, []Result{FloatResult(0.125), IntResult(0)}, StringResult("0")},
+		{FormatNumberRaw, []Result{IntResult(1234567)}, StringResult("1,234,567")},
+		{FormatNumberRaw, []Result{FloatResult(1234.5), IntResult(2)}, StringResult("1,234.50")},
+		{FormatNumberRaw, []Result{FloatResult(-9876543.21)}, StringResult("-9,876,543.21")},
+		{FormatNumberRaw, []Result{IntResult(999)}, StringResult("999")},
+		{FormatNumberRaw, []Result{IntResult(100000)}, StringResult("100,000")},
+	}
+
+	for i, test := range tests {
+		actual, err := test.function(test.args...)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %#v, got %#v", i, test.expected, actual)
+		}
+	}
+}
+
+func TestNumberFunctionsRejectInvalidArgs(t *testing.T) {
+	var tests = []struct {
+		function func(...Result) (Result, error)
+		args     []Result
+	}{
+		{RoundRaw, []Result{}},
+		{RoundRaw, []Result{StringResult("abc")}},
+		{RoundRaw, []Result{FloatResult(1.5), IntResult(-1)}},
+		{FloorRaw, []Result{BoolResult(true)}},
+		{CeilRaw, []Result{IntResult(1), IntResult(2)}},
+		{FixedRaw, []Result{FloatResult(1.5)}},
+		{FixedRaw, []Result{FloatResult(1.5), FloatResult(2)}},
+		{FormatNumberRaw, []Result{ContainerResult{&Context{}}}},
+		{RangeRaw, []Result{}},
+		{RangeRaw, []Result{FloatResult(2.5)}},
+		{RangeRaw, []Result{IntResult(0), IntResult(5), IntResult(0)}},
+		{RangeRaw, []Result{IntResult(0), IntResult(1000000)}},
+		{RangeRaw, []Result{IntResult(0), IntResult(1), IntResult(1), IntResult(1)}},
+	}
+
+	for i, test := range tests {
+		if _, err := test.function(test.args...); err == nil {
+			t.Errorf("%d: expected an error for %v", i, test.args)
+		}
+	}
+}
+
+func TestRangeRaw(t *testing.T) {
+	var tests = []struct {
+		args     []Result
+		expected []Result
+	}{
+		{[]Result{IntResult(3)}, []Result{IntResult(0), IntResult(1), IntResult(2)}},
+		{[]Result{IntResult(0)}, []Result{}},
+		{[]Result{IntResult(-2)}, []Result{}},
+		{[]Result{IntResult(2), IntResult(5)}, []Result{IntResult(2), IntResult(3), IntResult(4)}},
+		{[]Result{IntResult(0), IntResult(10), IntResult(4)}, []Result{IntResult(0), IntResult(4), IntResult(8)}},
+		{[]Result{IntResult(3), IntResult(0), IntResult(-1)}, []Result{IntResult(3), IntResult(2), IntResult(1)}},
+		{[]Result{IntResult(0), IntResult(3), IntResult(-1)}, []Result{}},
+	}
+
+	for i, test := range tests {
+		actual, err := RangeRaw(test.args...)
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+			continue
+		}
+
+		context := actual.(ContainerResult).context
+		if len(*context) != len(test.expected) {
+			t.Errorf("%d: expected %d numbers, got %d", i, len(test.expected), len(*context))
+			continue
+		}
+
+		for j, expected := range test.expected {
+			if node, _ := context.At(strconv.Itoa(j)); node == nil || node.result != expected {
+				t.Errorf("%d: expected %#v at %d, got %#v", i, expected, j, node)
+			}
+		}
+	}
+}
@@ -4,48 +4,55 @@ import "fmt"
 
 func processAddition(left, right Result, operator string) (Result, error) {
 	if operator == "+" {
-		leftOp := left.(AddableResult)
-		return leftOp.Add(right)
+		if leftOp, ok := left.(AddableResult); ok {
+			return leftOp.Add(right)
+		}
+		return nil, newBinaryError(operator, left, right)
 	} else if operator == "-" {
-		leftOp := left.(SubtractableResult)
-		return leftOp.Subtract(right)
+		if leftOp, ok := left.(SubtractableResult); ok {
+			return leftOp.Subtract(right)
+		}
+		return nil, newBinaryError(operator, left, right)
 	}
 
 	return nil, fmt.Errorf("invalid addition operator %q", operator)
 }
 
 func processMultiplication(left, right Result, operator string) (Result, error) {
-	leftOp := left.(MultipliableResult)
+	leftOp, ok := left.(MultipliableResult)
+	if !ok {
+		return nil, newBinaryError(operator, left, right)
+	}
+
 	if operator == "*" {
 		return leftOp.Multiply(right)
 	} else if operator == "/" {
 		return leftOp.Divide(right)
+	} else if operator == "%" {
+		return leftOp.Mod(right)
 	}
 
 	return nil, fmt.Errorf("invalid multiplication operator %q", operator)
 }
 
-func processLogic(left, right Result, operator string) (Result, error) {
-	leftOp := left.(LogicalResult)
-	if operator == "&&" {
-		return leftOp.LogicalAnd(right)
-	} else if operator == "||" {
-		return leftOp.LogicalOr(right)
-	}
-
-	return nil, fmt.Errorf("invalid logcal operator %q", operator)
-}
-
 // TODO: Figure out a better way to look up the appropriate functions
 func processRel(left, right Result, operator string) (Result, error) {
-	if operator == "==" {
-		leftOp := left.(EqualityResult)
-		return leftOp.EqualTo(right)
-	} else if operator == "!=" {
-		leftOp := left.(EqualityResult)
+	if operator == "==" || operator == "!=" {
+		leftOp, ok := left.(EqualityResult)
+		if !ok {
+			return nil, newBinaryError(operator, left, right)
+		}
+
+		if operator == "==" {
+			return leftOp.EqualTo(right)
+		}
 		return leftOp.NotEqualTo(right)
 	} else {
-		leftOp := left.(RelResult)
+		leftOp, ok := left.(RelResult)
+		if !ok {
+			return nil, newBinaryError(operator, left, right)
+		}
+
 		if operator == "<" {
 			return leftOp.LessThan(right)
 		} else if operator == ">" {
@@ -62,11 +69,12 @@ func processRel(left, right Result, operator string) (Result, error) {
 
 func processUnary(right Result, operator string) (Result, error) {
 	if operator == "!" {
-		rightOp := right.(NotResult)
-		return rightOp.Not()
+		return BoolResult(!isTruthy(right)), nil
 	} else if operator == "-" {
-		rightOp := right.(NegativeResult)
-		return rightOp.Negative()
+		if rightOp, ok := right.(NegativeResult); ok {
+			return rightOp.Negative()
+		}
+		return nil, newUnaryError(operator, right)
 	}
 
 	return nil, fmt.Errorf("invalid unary operator %q", operator)
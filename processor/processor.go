@@ -2,10 +2,14 @@ package processor
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"path/filepath"
+	"strconv"
 	"strings"
 
+	"mettlach.codes/frizzy/config"
+	"mettlach.codes/frizzy/diagnostic"
 	"mettlach.codes/frizzy/file"
 	"mettlach.codes/frizzy/parser"
 )
@@ -20,6 +24,25 @@ type NodeProcessor struct {
 	FunctionModule FunctionModule
 	CurPage        int
 	NumPages       int
+	// Dependencies is told about the templates, content and exports
+	// read while processing, it can be nil
+	Dependencies DependencyRecorder
+	// UndefinedVariables decides what reading a variable that isn't set
+	// does, the zero value renders it silently
+	UndefinedVariables config.UndefinedPolicy
+
+	// named block bodies from templates extending the one being
+	// processed, keyed by block name
+	blockOverrides map[string]parser.TreeNode
+	// paths of the layouts extended so far, used to detect cycles
+	extendsChain []string
+	// paths of the partials being included, used to detect cycles
+	includeChain []string
+	// warnings found while processing, shared with loop bodies
+	warnings *[]error
+	// escaper escapes printed results for files written as html, it is
+	// nil otherwise
+	escaper *htmlEscaper
 }
 
 func NewNodeProcessor(
@@ -38,12 +61,21 @@ func NewNodeProcessor(
 		FunctionModule: funcModule,
 		CurPage:        curPage,
 		NumPages:       numPages,
+
+		UndefinedVariables: config.GetLoadedConfig().UndefinedVariables,
+		warnings:           &[]error{},
 	}
 
 	if context == nil {
 		processor.Context = &Context{}
 	}
 
+	if isMarkdown(filepath) {
+		processor.escaper = newMarkdownEscaper()
+	} else if isHTMLOutput(filepath) {
+		processor.escaper = newHTMLEscaper()
+	}
+
 	processor.Context.Insert([]string{"curPage"}, IntResult(curPage))
 	processor.Context.Insert([]string{"numPages"}, IntResult(numPages))
 
@@ -56,11 +88,20 @@ func NewNodeProcessor(
 	}
 
 	if funcModule == nil {
-		processor.FunctionModule = NewBuiltinFunctionModule(
+		module := NewBuiltinFunctionModule(
 			IntResult(curPage),
 			IntResult(numPages),
 			StringResult(filepath),
 		)
+		module.registerFunc("include", processor.includeRaw)
+		module.registerFunc("template", processor.templateRaw)
+		module.registerFunc("paginate",
+			paginationClosure(processor.paginateRaw, IntResult(curPage)),
+		)
+		for funcName, function := range collectionFuncs {
+			module.registerFunc(funcName, processor.contentClosure(function))
+		}
+		processor.FunctionModule = module
 	}
 
 	return processor
@@ -78,6 +119,14 @@ func (receiver *NodeProcessor) Process(nodeChan <-chan parser.TreeNode, ctx cont
 
 		for node := range nodeChan {
 			result, err := receiver.processHeadNode(node)
+			for _, warning := range receiver.takeWarnings() {
+				select {
+				case errChan <- warning:
+				case <-ctx.Done():
+					return
+				}
+			}
+
 			if err != nil {
 				errChan <- err
 				return
@@ -94,6 +143,83 @@ func (receiver *NodeProcessor) Process(nodeChan <-chan parser.TreeNode, ctx cont
 	return resultChan, errChan
 }
 
+// Collect reads each node from nodeChan and evaluates only the
+// assignments, including those in the if branches and loop iterations
+// that would be rendered, so the file's exports are available before
+// any file is rendered
+// Assignments in loops over content are only evaluated when rendering
+func (receiver *NodeProcessor) Collect(nodeChan <-chan parser.TreeNode, ctx context.Context) <-chan error {
+	errChan := make(chan error, 1)
+	// undefined variables are reported when the file is rendered
+	receiver.UndefinedVariables = config.UndefinedSilent
+
+	go func() {
+		defer close(errChan)
+
+		for node := range nodeChan {
+			if err := receiver.collectAssignments(node); err != nil {
+				errChan <- err
+				// drain so the parser isn't blocked
+				for range nodeChan {
+				}
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+		}
+	}()
+
+	return errChan
+}
+
+func (receiver *NodeProcessor) collectAssignments(head parser.TreeNode) error {
+	switch typedNode := head.(type) {
+	case *parser.BlockParseNode:
+		content, ok := typedNode.GetContent().(*parser.NonTerminalParseNode)
+		if ok && content.IsAssignment() {
+			_, err := receiver.processHeadNode(content)
+			return err
+		}
+	case *parser.IfStatementParseNode:
+		// a condition that fails is reported when the file is rendered
+		if body, ok, err := receiver.chooseIfBranch(typedNode); err == nil && ok {
+			return receiver.collectAssignments(body)
+		}
+	case *parser.ForLoopParseNode:
+		// the exports of the content looped over aren't all collected
+		// yet, so only loops over lists and maps are collected
+		inputResult, err := receiver.processHeadNode(typedNode.GetLoopInput())
+		if _, isContentPath := inputResult.(StringResult); err != nil || isContentPath {
+			return nil
+		}
+
+		inputs := receiver.getLoopInputs(inputResult)
+		if elseBody, ok := typedNode.GetElseBody(); ok && len(inputs) == 0 {
+			return receiver.collectAssignments(elseBody)
+		}
+
+		return receiver.forEachLoopInput(getLoopIdents(typedNode), inputs, func(loopProcessor *NodeProcessor) error {
+			return loopProcessor.collectAssignments(typedNode.GetLoopBody())
+		})
+	case *parser.NamedBlockParseNode:
+		if body, ok := typedNode.GetBody(); ok {
+			return receiver.collectAssignments(body)
+		}
+	default:
+		for _, child := range head.GetChildren() {
+			if err := receiver.collectAssignments(child); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (receiver *NodeProcessor) processHeadNode(head parser.TreeNode) (Result, error) {
 	var processResult Result
 	var processError error
@@ -111,15 +237,23 @@ func (receiver *NodeProcessor) processHeadNode(head parser.TreeNode) (Result, er
 				processError = fmt.Errorf("invalid assignment: %s", err)
 			}
 		} else if typedNode.IsAddition() {
-			processResult, processError = processAddition(receiver.getBinaryOperatorAndOperands(children))
+			processResult, processError = receiver.processBinaryOperation(children, processAddition)
 		} else if typedNode.IsMultiplication() {
-			processResult, processError = processMultiplication(receiver.getBinaryOperatorAndOperands(children))
+			processResult, processError = receiver.processBinaryOperation(children, processMultiplication)
 		} else if typedNode.IsRelation() {
-			processResult, processError = processRel(receiver.getBinaryOperatorAndOperands(children))
+			processResult, processError = receiver.processBinaryOperation(children, processRel)
 		} else if typedNode.IsLogic() {
-			processResult, processError = processLogic(receiver.getBinaryOperatorAndOperands(children))
+			processResult, processError = receiver.processLogic(children)
+		} else if typedNode.IsConditional() {
+			processResult, processError = receiver.processConditional(children)
+		} else if typedNode.IsCoalesce() {
+			processResult, processError = receiver.processCoalesce(children)
 		} else if typedNode.IsUnary() {
-			processResult, processError = processUnary(receiver.getUnaryOperatorAndOperand(children))
+			if right, operator, err := receiver.getUnaryOperatorAndOperand(children); err == nil {
+				processResult, processError = processUnary(right, operator)
+			} else {
+				processError = err
+			}
 		} else {
 			// TODO: This smells bad
 			if len(children) == 1 {
@@ -140,6 +274,12 @@ func (receiver *NodeProcessor) processHeadNode(head parser.TreeNode) (Result, er
 		}
 	case *parser.ContentParseNode:
 		children := typedNode.GetChildren()
+
+		if hasExtends(children) {
+			processResult, processError = receiver.processExtendedContent(children)
+			break
+		}
+
 		resultText := make([]string, 0, len(children))
 
 		for _, child := range children {
@@ -158,82 +298,44 @@ func (receiver *NodeProcessor) processHeadNode(head parser.TreeNode) (Result, er
 		if err != nil {
 			processError = err
 		} else {
-			inputContexts := receiver.getLoopInputContexts(&inputResult)
-			loopBody := typedNode.GetLoopBody()
-			loopIdent := typedNode.GetLoopIdent().(*parser.IdentParseNode)
+			inputs := receiver.getLoopInputs(inputResult)
 
-			processResult = receiver.generateLoopBody(loopBody, loopIdent, inputContexts)
+			if elseBody, ok := typedNode.GetElseBody(); ok && len(inputs) == 0 {
+				processResult, processError = receiver.processHeadNode(elseBody)
+			} else {
+				processResult, processError = receiver.generateLoopBody(typedNode.GetLoopBody(), getLoopIdents(typedNode), inputs)
+			}
 		}
 	case *parser.IfStatementParseNode:
-		ifResult, err := receiver.processHeadNode(typedNode.GetIfConditional())
+		body, ok, err := receiver.chooseIfBranch(typedNode)
 
 		if err != nil {
 			processError = err
-			break
-		}
-
-		ifCondition := ifResult.(BoolResult)
-		// check if first
-		if bool(ifCondition) {
-			ifBody, err := receiver.processHeadNode(typedNode.GetIfBody())
-			if err != nil {
-				processError = err
-			} else {
-				processResult = StringResult(ifBody.String())
-			}
-			break
-		}
-
-		// check any else_ifs
-		elseIfConditions := typedNode.GetElseIfConditionals()
-		for i, elseCondition := range elseIfConditions {
-			elseIfResult, err := receiver.processHeadNode(elseCondition)
-			if err != nil {
-				processError = err
-				break
-			}
-
-			condition := elseIfResult.(BoolResult)
-			if bool(condition) {
-				if elseIfBody, ok := typedNode.GetElseIfBody(i); ok {
-					body, err := receiver.processHeadNode(elseIfBody)
-
-					if err != nil {
-						processError = err
-					} else {
-						processResult = StringResult(body.String())
-					}
-					break
-				}
-			}
-		}
-
-		if processError != nil || processResult != nil {
-			break
-		}
-
-		// finally try for the else
-		if elseBody, ok := typedNode.GetElseBody(); ok {
-			body, err := receiver.processHeadNode(elseBody)
-
-			if err != nil {
-				processError = err
-			} else {
-				processResult = StringResult(body.String())
-			}
-			break
+		} else if !ok {
+			// nothing is true
+			processResult = StringResult("")
+		} else if bodyResult, err := receiver.processHeadNode(body); err != nil {
+			processError = err
+		} else {
+			processResult = StringResult(bodyResult.String())
 		}
-
-		// nothing is true
-		processResult = StringResult("")
 	case *parser.FuncCallParseNode:
 		funcName := typedNode.GetFuncName()
+		if index, ok := typedNode.GetNameIndex(); ok {
+			processError = positionError(index, fmt.Errorf("cannot index function %q", funcName))
+			break
+		}
 
 		processedArgs := []Result{}
 		args := typedNode.GetArguments()
 
-		for _, arg := range args {
-			argResult, err := receiver.processHeadNode(arg)
+		for i, arg := range args {
+			processArg := receiver.processHeadNode
+			if i == 0 && optionalArgFuncs[funcName] {
+				processArg = receiver.processOptional
+			}
+
+			argResult, err := processArg(arg)
 
 			if err != nil {
 				processError = err
@@ -251,26 +353,53 @@ func (receiver *NodeProcessor) processHeadNode(head parser.TreeNode) (Result, er
 			}
 		}
 
+	case *parser.ExtendsParseNode:
+		processResult, processError = receiver.processExtendedContent([]parser.TreeNode{typedNode})
+	case *parser.NamedBlockParseNode:
+		body, overridden := receiver.blockOverrides[typedNode.GetName()]
+		firstWarning := len(*receiver.warnings)
+		ok := overridden
+		if !ok {
+			body, ok = typedNode.GetBody()
+		}
+
+		processResult = StringResult("")
+		if ok {
+			processResult, processError = receiver.processHeadNode(body)
+		}
+
+		if overridden {
+			// overrides come from the file being processed, not the layout
+			namespace := receiver.ExportStore.GetNamespace()
+			receiver.attributeWarnings(firstWarning, func(err error) error {
+				return inFile(namespace, err)
+			})
+			if processError != nil {
+				processError = inFile(namespace, processError)
+			}
+		}
 	case *parser.StringParseNode:
+		if typedNode.Passthrough {
+			receiver.writeOutput(typedNode.Value)
+		}
 		processResult = StringResult(typedNode.Value)
 	case *parser.NumParseNode:
 		processResult = IntResult(typedNode.Value)
+	case *parser.FloatParseNode:
+		processResult = FloatResult(typedNode.Value)
 	case *parser.BoolParseNode:
 		processResult = BoolResult(typedNode.Value)
+	case *parser.ListLiteralParseNode:
+		processResult, processError = receiver.processListLiteral(typedNode)
+	case *parser.MapLiteralParseNode:
+		processResult, processError = receiver.processMapLiteral(typedNode)
+	case *parser.IndexParseNode:
+		processResult, processError = receiver.processIndex(typedNode)
 	case *parser.VarNameParseNode:
-		keys := typedNode.GetVarNameParts()
-		if node, ok := receiver.lookupInContext(keys); ok {
-
-			// current is the last context node so we can
-			// return its result or its further nested context
-			if node.HasResult() {
-				processResult = node.result
-			} else {
-				processResult = ContainerResult{node.child}
-			}
-		} else {
-			log.Printf("context lookup failed: %q", strings.Join(keys, "."))
-			processResult = StringResult("")
+		var keys []string
+		processResult, keys, processError = receiver.lookupVarName(typedNode)
+		if processError == nil && processResult == nil {
+			processResult, processError = receiver.undefinedVariable(typedNode, keys)
 		}
 	case *parser.BlockParseNode:
 		content := typedNode.GetContent()
@@ -281,7 +410,7 @@ func (receiver *NodeProcessor) processHeadNode(head parser.TreeNode) (Result, er
 		} else {
 			result := ""
 			if typedNode.IsPrintable() {
-				result = parsed.String()
+				result = receiver.print(parsed)
 			}
 
 			processResult = StringResult(result)
@@ -290,13 +419,72 @@ func (receiver *NodeProcessor) processHeadNode(head parser.TreeNode) (Result, er
 		processResult = StringResult("")
 	}
 
+	if processError != nil {
+		processError = positionError(head, processError)
+	}
+
 	return processResult, processError
 }
 
+// positionError returns err as a diagnostic at the span of node
+// Errors that already have a position are returned unchanged so the
+// innermost node that failed is reported
+func positionError(node parser.TreeNode, err error) error {
+	var positioned *diagnostic.Diagnostic
+	if errors.As(err, &positioned) {
+		return err
+	}
+
+	span := node.GetSpan()
+	if span.Start.Line == 0 {
+		return err
+	}
+
+	positioned = diagnostic.Wrap(span.Start.Line, span.Start.Col, err)
+	if span.End.Line == span.Start.Line {
+		positioned.EndColumn = span.End.Col
+	}
+
+	return positioned
+}
+
+// warningAt returns err as a warning diagnostic at the span of node
+func warningAt(node parser.TreeNode, err error) error {
+	span := node.GetSpan()
+	warning := diagnostic.Warn(span.Start.Line, span.Start.Col, err)
+	if span.End.Line == span.Start.Line {
+		warning.EndColumn = span.End.Col
+	}
+
+	return warning
+}
+
+// inTemplate attributes a positioned err that has no filename to the
+// template at templatePath
+func inTemplate(templatePath string, err error) error {
+	return inFile(filepath.Join(config.GetLoadedConfig().GetTemplatePath(), templatePath), err)
+}
+
+// inFile attributes a positioned err that has no filename to filename
+func inFile(filename string, err error) error {
+	var positioned *diagnostic.Diagnostic
+	if errors.As(err, &positioned) && positioned.Filename == "" {
+		copied := *positioned
+		copied.Filename = filename
+		return &copied
+	}
+
+	return err
+}
+
 // Returns the left side of the assignment as a string and the right as a processed Result
 func (receiver *NodeProcessor) getAssignmentKeysAndValue(ops []parser.TreeNode) ([]string, Result, error) {
 	if left, ok := ops[0].(*parser.VarNameParseNode); ok {
-		nameParts := left.GetVarNameParts()
+		nameParts, err := receiver.getVarNameKeys(left)
+		if err != nil {
+			return nil, nil, err
+		}
+
 		right, err := receiver.processHeadNode(ops[len(ops)-1])
 
 		return nameParts, right, err
@@ -305,25 +493,141 @@ func (receiver *NodeProcessor) getAssignmentKeysAndValue(ops []parser.TreeNode)
 	return nil, nil, fmt.Errorf("invalid assignment to %T", ops[0])
 }
 
+// chooseIfBranch evaluates the conditions of node in order and returns
+// the body of the first one that is true or the else body, ok is false
+// if there is no body to process
+func (receiver *NodeProcessor) chooseIfBranch(node *parser.IfStatementParseNode) (body parser.TreeNode, ok bool, err error) {
+	ifResult, err := receiver.processHeadNode(node.GetIfConditional())
+	if err != nil {
+		return nil, false, err
+	}
+
+	if isTruthy(ifResult) {
+		return node.GetIfBody(), true, nil
+	}
+
+	for i, elseCondition := range node.GetElseIfConditionals() {
+		elseIfResult, err := receiver.processHeadNode(elseCondition)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if isTruthy(elseIfResult) {
+			if elseIfBody, ok := node.GetElseIfBody(i); ok {
+				return elseIfBody, true, nil
+			}
+		}
+	}
+
+	body, ok = node.GetElseBody()
+	return body, ok, nil
+}
+
+// processBinaryOperation evaluates the operands in ops and applies
+// operation to them
+func (receiver *NodeProcessor) processBinaryOperation(
+	ops []parser.TreeNode,
+	operation func(left, right Result, operator string) (Result, error),
+) (Result, error) {
+	left, right, operator, err := receiver.getBinaryOperatorAndOperands(ops)
+	if err != nil {
+		return nil, err
+	}
+
+	return operation(left, right, operator)
+}
+
+// processConditional evaluates only the branch chosen by the condition
+// e.g. given a ? b : c, ops = []parser.TreeNode{a, '?', b, ':', c}
+func (receiver *NodeProcessor) processConditional(ops []parser.TreeNode) (Result, error) {
+	condition, err := receiver.processHeadNode(ops[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if isTruthy(condition) {
+		return receiver.processHeadNode(ops[2])
+	}
+	return receiver.processHeadNode(ops[4])
+}
+
+// processLogic evaluates the right operand only if the left one doesn't
+// decide the result e.g. given a && b, ops = []parser.TreeNode{a, "&&", b}
+func (receiver *NodeProcessor) processLogic(ops []parser.TreeNode) (Result, error) {
+	left, err := receiver.processHeadNode(ops[0])
+	if err != nil {
+		return nil, err
+	}
+
+	operatorResult, err := receiver.processHeadNode(ops[1])
+	if err != nil {
+		return nil, err
+	}
+
+	operator := operatorResult.String()
+	if operator != "&&" && operator != "||" {
+		return nil, fmt.Errorf("invalid logical operator %q", operator)
+	}
+
+	// false && b and true || b are decided by the left operand
+	if isTruthy(left) == (operator == "||") {
+		return BoolResult(isTruthy(left)), nil
+	}
+
+	right, err := receiver.processHeadNode(ops[len(ops)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	return BoolResult(isTruthy(right)), nil
+}
+
+// processCoalesce evaluates the right operand only if the left one is
+// empty or undefined e.g. given a ?? b, ops = []parser.TreeNode{a, "??", b}
+func (receiver *NodeProcessor) processCoalesce(ops []parser.TreeNode) (Result, error) {
+	left, err := receiver.processOptional(ops[0])
+	if err != nil || !isEmpty(left) {
+		return left, err
+	}
+
+	return receiver.processHeadNode(ops[2])
+}
+
 // Returns the operator and operands of the binary operation represented in ops
 // e.g. given 5 + 4, ops = []parser.TreeNode{5, '+', 4}
-func (receiver *NodeProcessor) getBinaryOperatorAndOperands(ops []parser.TreeNode) (Result, Result, string) {
-	left, _ := receiver.processHeadNode(ops[0])
-	operatorResult, _ := receiver.processHeadNode(ops[1])
-	operator := operatorResult.(StringResult)
-	right, _ := receiver.processHeadNode(ops[len(ops)-1])
+func (receiver *NodeProcessor) getBinaryOperatorAndOperands(ops []parser.TreeNode) (Result, Result, string, error) {
+	left, err := receiver.processHeadNode(ops[0])
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	operatorResult, err := receiver.processHeadNode(ops[1])
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	right, err := receiver.processHeadNode(ops[len(ops)-1])
+	if err != nil {
+		return nil, nil, "", err
+	}
 
-	return left, right, string(operator)
+	return left, right, operatorResult.String(), nil
 }
 
 // Returns the operator and operand of the unary operation in ops
 // e.g. given !false, ops = []parser.TreeNode{"!", false}
-func (receiver *NodeProcessor) getUnaryOperatorAndOperand(ops []parser.TreeNode) (Result, string) {
-	operatorResult, _ := receiver.processHeadNode(ops[0])
-	operator := operatorResult.(StringResult)
-	right, _ := receiver.processHeadNode(ops[len(ops)-1])
+func (receiver *NodeProcessor) getUnaryOperatorAndOperand(ops []parser.TreeNode) (Result, string, error) {
+	operatorResult, err := receiver.processHeadNode(ops[0])
+	if err != nil {
+		return nil, "", err
+	}
+
+	right, err := receiver.processHeadNode(ops[len(ops)-1])
+	if err != nil {
+		return nil, "", err
+	}
 
-	return right, string(operator)
+	return right, operatorResult.String(), nil
 }
 
 func (receiver *NodeProcessor) doExport(keys []string, value Result) {
@@ -333,6 +637,7 @@ func (receiver *NodeProcessor) doExport(keys []string, value Result) {
 }
 
 func (receiver *NodeProcessor) doGetContext(filePath string) *Context {
+	receiver.recordExport(filePath)
 	if receiver.ExportStore != nil {
 		return receiver.ExportStore.GetFileContext(filePath)
 	}
@@ -344,22 +649,94 @@ func (receiver *NodeProcessor) callFunction(funcName string, args []Result) (Res
 	return receiver.FunctionModule.CallFunction(funcName, args...)
 }
 
-func (receiver *NodeProcessor) generateLoopBody(body parser.TreeNode, loopIdent *parser.IdentParseNode, contexts []*Context) StringResult {
+// generateLoopBody processes body once for each input with the value
+// bound to the first of loopIdents and the key bound to the second
+// if there is one
+func (receiver *NodeProcessor) generateLoopBody(body parser.TreeNode, loopIdents []*parser.IdentParseNode, inputs []loopInput) (StringResult, error) {
 	bodyText := ""
 
+	err := receiver.forEachLoopInput(loopIdents, inputs, func(loopProcessor *NodeProcessor) error {
+		bodyResult, err := loopProcessor.processHeadNode(body)
+		if err != nil {
+			return err
+		}
+
+		bodyText += bodyResult.String()
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return StringResult(bodyText), nil
+}
+
+// forEachLoopInput calls do with a processor for each input, with the
+// value bound to the first of loopIdents and the key bound to the
+// second if there is one, and stops at the first error
+func (receiver *NodeProcessor) forEachLoopInput(loopIdents []*parser.IdentParseNode, inputs []loopInput, do func(loopProcessor *NodeProcessor) error) error {
 	context := receiver.Context
 	merged := &Context{}
 	namespace := receiver.ExportStore.GetNamespace()
+	parent, _ := context.At("loop")
+	valueIdent := loopIdents[0].Value
+
+	for i, input := range inputs {
+		(*merged)["loop"] = &ContextNode{child: newLoopContext(i, len(inputs), parent)}
+
+		if len(loopIdents) > 1 {
+			(*merged)[loopIdents[1].Value] = &ContextNode{result: input.key}
+		}
+
+		if input.value.HasResult() {
+			// scalars like the elements of [1, 2, 3] are bound directly
+			(*merged)[valueIdent] = &ContextNode{result: input.value.result}
+		} else {
+			(*merged)[valueIdent] = &ContextNode{child: context.Merge(input.value.child)}
+		}
 
-	for _, inputContext := range contexts {
-		(*merged)[loopIdent.Value] = &ContextNode{child: context.Merge(inputContext)}
 		loopProcessor := NewNodeProcessor(namespace, merged, nil, nil, nil, 0, 0)
-		bodyResult, _ := loopProcessor.processHeadNode(body)
+		loopProcessor.blockOverrides = receiver.blockOverrides
+		loopProcessor.includeChain = receiver.includeChain
+		loopProcessor.Dependencies = receiver.Dependencies
+		loopProcessor.UndefinedVariables = receiver.UndefinedVariables
+		loopProcessor.warnings = receiver.warnings
+		loopProcessor.escaper = receiver.escaper
+		if err := do(loopProcessor); err != nil {
+			return err
+		}
+	}
 
-		bodyText += bodyResult.String()
+	return nil
+}
+
+// getLoopIdents returns the value ident of node followed by its key
+// ident if it has one
+func getLoopIdents(node *parser.ForLoopParseNode) []*parser.IdentParseNode {
+	loopIdents := []*parser.IdentParseNode{node.GetLoopIdent().(*parser.IdentParseNode)}
+	if keyIdent, ok := node.GetLoopKeyIdent(); ok {
+		loopIdents = append(loopIdents, keyIdent.(*parser.IdentParseNode))
+	}
+	return loopIdents
+}
+
+// newLoopContext returns the context bound to loop on iteration index
+// of a loop over length inputs
+// parent is the loop context of the enclosing loop or nil
+func newLoopContext(index, length int, parent *ContextNode) *Context {
+	loop := &Context{
+		"index":  &ContextNode{result: IntResult(index)},
+		"index1": &ContextNode{result: IntResult(index + 1)},
+		"first":  &ContextNode{result: BoolResult(index == 0)},
+		"last":   &ContextNode{result: BoolResult(index == length-1)},
+		"length": &ContextNode{result: IntResult(length)},
 	}
 
-	return StringResult(bodyText)
+	if parent != nil && parent.HasContext() {
+		(*loop)["parent"] = &ContextNode{child: parent.child}
+	}
+
+	return loop
 }
 
 // getPaths is responsible for looking up the path of each file in a loop
@@ -368,6 +745,7 @@ func (receiver *NodeProcessor) generateLoopBody(body parser.TreeNode, loopIdent
 // getPaths reads paths using the provided PathReader or defaulting to
 // file.GetContentPaths if PathReader is nil
 func (receiver *NodeProcessor) getPaths(subpath string) []string {
+	receiver.recordContentPath(subpath)
 	pathReader := receiver.PathReader
 	if pathReader == nil {
 		pathReader = file.GetContentPaths
@@ -375,6 +753,109 @@ func (receiver *NodeProcessor) getPaths(subpath string) []string {
 	return pathReader(subpath)
 }
 
+// lookupVarName returns the result of the variable named by node and
+// its keys, the result is nil if the variable isn't set
+func (receiver *NodeProcessor) lookupVarName(node *parser.VarNameParseNode) (Result, []string, error) {
+	keys, err := receiver.getVarNameKeys(node)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contextNode, ok := receiver.lookupInContext(keys)
+	if !ok {
+		return nil, keys, nil
+	}
+
+	// the last context node holds either a result or further nested context
+	if contextNode.HasResult() {
+		return contextNode.result, keys, nil
+	}
+	return ContainerResult{contextNode.child}, keys, nil
+}
+
+// undefinedVariable returns the result of reading the variable named by
+// keys, which isn't set, according to receiver's UndefinedVariables
+func (receiver *NodeProcessor) undefinedVariable(node parser.TreeNode, keys []string) (Result, error) {
+	return receiver.undefined(node, fmt.Errorf("undefined variable %q", strings.Join(keys, ".")))
+}
+
+// undefined returns the result of reading a value that isn't set, err
+// describes the value, according to receiver's UndefinedVariables
+func (receiver *NodeProcessor) undefined(node parser.TreeNode, err error) (Result, error) {
+	switch receiver.UndefinedVariables {
+	case config.UndefinedError:
+		return nil, err
+	case config.UndefinedWarn:
+		receiver.warn(node, err)
+	}
+
+	return StringResult(""), nil
+}
+
+// optionalArgFuncs are the functions whose first argument may be an
+// undefined variable, which is passed to them as nil
+var optionalArgFuncs = map[string]bool{
+	"defined": true,
+	"default": true,
+}
+
+// processOptional processes node like processHeadNode except that a
+// variable that isn't set gives nil instead of following receiver's
+// UndefinedVariables
+func (receiver *NodeProcessor) processOptional(node parser.TreeNode) (Result, error) {
+	varName, ok := node.(*parser.VarNameParseNode)
+	if !ok {
+		return receiver.processHeadNode(node)
+	}
+
+	result, _, err := receiver.lookupVarName(varName)
+	if err != nil {
+		return nil, positionError(node, err)
+	}
+	return result, nil
+}
+
+// print returns result as it is written to the output, escaped if
+// receiver is processing html
+func (receiver *NodeProcessor) print(result Result) string {
+	if receiver.escaper == nil {
+		return result.String()
+	}
+
+	output := receiver.escaper.escape(result)
+	receiver.escaper.write(output)
+	return output
+}
+
+// writeOutput tells receiver's escaper about template text written to
+// the output
+func (receiver *NodeProcessor) writeOutput(text string) {
+	if receiver.escaper != nil {
+		receiver.escaper.write(text)
+	}
+}
+
+// warn records err as a warning at node
+func (receiver *NodeProcessor) warn(node parser.TreeNode, err error) {
+	*receiver.warnings = append(*receiver.warnings, warningAt(node, err))
+}
+
+// takeWarnings returns the warnings recorded so far and forgets them
+func (receiver *NodeProcessor) takeWarnings() []error {
+	warnings := *receiver.warnings
+	*receiver.warnings = nil
+	return warnings
+}
+
+// attributeWarnings passes each warning recorded since the first to
+// attribute, which gives it a file
+func (receiver *NodeProcessor) attributeWarnings(first int, attribute func(error) error) {
+	warnings := *receiver.warnings
+	for i := first; i < len(warnings); i++ {
+		warnings[i] = attribute(warnings[i])
+	}
+}
+
 func (receiver *NodeProcessor) lookupInContext(keys []string) (*ContextNode, bool) {
 	return receiver.Context.AtNested(keys)
 }
@@ -383,32 +864,47 @@ func (receiver *NodeProcessor) insertInContext(keys []string, value Result) {
 	receiver.Context.Insert(keys, value)
 }
 
-// returns the contexts of each file in contextPath dir
-func (receiver *NodeProcessor) getLoopContentContexts(contextPath string) []*Context {
-	// typedInput is a path to content
-	contentPaths := receiver.getPaths(contextPath)
-	ret := make([]*Context, len(contentPaths))
-
-	// iterate through dir in content dir
-	for i, path := range contentPaths {
-		ret[i] = receiver.doGetContext(path)
-	}
-
-	// return array of export store context for each file
-	return ret
+// loopInput is the key and value bound on one iteration of a for loop
+type loopInput struct {
+	key   Result
+	value *ContextNode
 }
 
-// getLoopInputContexts returns an array of contexts that should
-// be sent on each iteration of a for loop
-func (receiver *NodeProcessor) getLoopInputContexts(input *Result) []*Context {
-	switch typedInput := (*input).(type) {
+// getLoopInputs returns the key and value that should be bound on
+// each iteration of a for loop
+// Content paths are keyed by the path of each file and containers by
+// their keys, with list indices as ints
+func (receiver *NodeProcessor) getLoopInputs(input Result) []loopInput {
+	switch typedInput := input.(type) {
 	case StringResult:
 		// return a context for each file in the path
-		return receiver.getLoopContentContexts(string(typedInput))
+		contentPaths := receiver.getPaths(string(typedInput))
+		inputs := make([]loopInput, len(contentPaths))
+		for i, contentPath := range contentPaths {
+			inputs[i] = loopInput{
+				key:   StringResult(contentPath),
+				value: &ContextNode{child: receiver.doGetContext(contentPath)},
+			}
+		}
+		return inputs
 	case ContainerResult:
-		// return a context
-		return typedInput.context.Values()
+		// return each value in key order
+		keys := typedInput.context.Keys()
+		inputs := make([]loopInput, len(keys))
+		for i, key := range keys {
+			inputs[i] = loopInput{key: keyResult(key), value: (*typedInput.context)[key]}
+		}
+		return inputs
 	default:
 		return nil
 	}
 }
+
+// keyResult returns key as an IntResult if it is a list index and a
+// StringResult otherwise
+func keyResult(key string) Result {
+	if index, err := strconv.Atoi(key); err == nil {
+		return IntResult(index)
+	}
+	return StringResult(key)
+}
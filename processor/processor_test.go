@@ -2,13 +2,18 @@ package processor
 
 import (
 	goContext "context"
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 
+	"mettlach.codes/frizzy/config"
+	"mettlach.codes/frizzy/diagnostic"
 	"mettlach.codes/frizzy/lexer"
 	"mettlach.codes/frizzy/parser"
 )
@@ -1352,3 +1357,1024 @@ func createBufferedNodeChan() <-chan parser.TreeNode {
 	}
 	return bufChan
 }
+
+func TestProcessErrorsReportSpan(t *testing.T) {
+	cacheTemplate(t, "spans/broken.html", "ok\n{{: nope()}}")
+
+	var tests = []struct {
+		text     string
+		expected diagnostic.Diagnostic
+	}{
+		{"a\n  {{: nope()}}", diagnostic.Diagnostic{Line: 2, Column: 7, EndColumn: 13, Message: "function nope is not registered"}},
+		{"{{: missing(1 + 2)}}", diagnostic.Diagnostic{Line: 1, Column: 5, EndColumn: 19, Message: "function missing is not registered"}},
+		{`{{: include("spans/broken.html")}}`, diagnostic.Diagnostic{
+			Filename:  filepath.Join(config.GetLoadedConfig().GetTemplatePath(), "spans/broken.html"),
+			Line:      2,
+			Column:    5,
+			EndColumn: 11,
+			Message:   "function nope is not registered",
+		}},
+	}
+
+	for i, test := range tests {
+		_, err := processText(t, test.text)
+
+		var actual *diagnostic.Diagnostic
+		if !errors.As(err, &actual) {
+			t.Errorf("%d: expected a diagnostic, got %v", i, err)
+		} else if positioned := (diagnostic.Diagnostic{
+			Filename:  actual.Filename,
+			Line:      actual.Line,
+			Column:    actual.Column,
+			EndColumn: actual.EndColumn,
+			Severity:  actual.Severity,
+			Message:   actual.Message,
+		}); positioned != test.expected {
+			t.Errorf("%d: expected %+v, got %+v", i, test.expected, positioned)
+		}
+	}
+}
+
+// typeError marks an operation expected to fail with a RuntimeError
+const typeError = "<type error>"
+
+// operands are one of each result type, c is a container
+var operands = []string{"2", `"3"`, "true", "c"}
+
+func TestBinaryOperatorsOnEveryType(t *testing.T) {
+	var tests = []struct {
+		operator string
+		// expected[left][right] indexed like operands
+		expected [4][4]string
+	}{
+		{"+", [4][4]string{
+			{"4", "23", typeError, typeError},
+			{"32", "33", typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+		}},
+		{"-", [4][4]string{
+			{"0", typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+		}},
+		{"*", [4][4]string{
+			{"4", typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+		}},
+		{"/", [4][4]string{
+			{"1", typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+		}},
+		{"%", [4][4]string{
+			{"0", typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+		}},
+		{"==", [4][4]string{
+			{"true", "false", typeError, typeError},
+			{"false", "true", typeError, typeError},
+			{"true", typeError, "true", typeError},
+			{typeError, typeError, typeError, typeError},
+		}},
+		{"!=", [4][4]string{
+			{"false", "true", typeError, typeError},
+			{"true", "false", typeError, typeError},
+			{"false", typeError, "false", typeError},
+			{typeError, typeError, typeError, typeError},
+		}},
+		{"<", [4][4]string{
+			{"false", "true", typeError, typeError},
+			{"false", "false", typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+		}},
+		{">", [4][4]string{
+			{"false", "false", typeError, typeError},
+			{"true", "false", typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+		}},
+		{"<=", [4][4]string{
+			{"true", "true", typeError, typeError},
+			{"false", "true", typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+		}},
+		{">=", [4][4]string{
+			{"true", "false", typeError, typeError},
+			{"true", "true", typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+			{typeError, typeError, typeError, typeError},
+		}},
+		{"&&", [4][4]string{
+			{"true", "true", "true", "true"},
+			{"true", "true", "true", "true"},
+			{"true", "true", "true", "true"},
+			{"true", "true", "true", "true"},
+		}},
+		{"||", [4][4]string{
+			{"true", "true", "true", "true"},
+			{"true", "true", "true", "true"},
+			{"true", "true", "true", "true"},
+			{"true", "true", "true", "true"},
+		}},
+	}
+
+	for _, test := range tests {
+		for left, row := range test.expected {
+			for right, expected := range row {
+				expression := fmt.Sprintf("%s %s %s", operands[left], test.operator, operands[right])
+				checkOperation(t, expression, expected)
+			}
+		}
+	}
+}
+
+func TestUnaryOperatorsOnEveryType(t *testing.T) {
+	var tests = []struct {
+		operator string
+		expected [4]string
+	}{
+		{"!", [4]string{"false", "false", "false", "false"}},
+		{"-", [4]string{"-2", typeError, typeError, typeError}},
+	}
+
+	for _, test := range tests {
+		for i, expected := range test.expected {
+			checkOperation(t, test.operator+operands[i], expected)
+		}
+	}
+}
+
+func TestLogicalOperatorsUseTruthiness(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{: "a" && 1}}`, "true"},
+		{`{{: "" && true}}`, "false"},
+		{`{{: 0 || "x"}}`, "true"},
+		{`{{: 0 || ""}}`, "false"},
+		{`{{: missing || 0.5}}`, "true"},
+		{`{{: !""}}`, "true"},
+		{`{{: !"abc"}}`, "false"},
+		{`{{: !0}}`, "true"},
+		{`{{: !missing}}`, "true"},
+		{`{{if "a" && 2}}yes{{end}}`, "yes"},
+		{`{{if !title}}untitled{{end}}`, "untitled"},
+		// the right operand isn't evaluated when the left one decides
+		{`{{: false && (true + 1)}}`, "false"},
+		{`{{: "" && missing.key}}`, "false"},
+		{`{{: 1 || (true + 1)}}`, "true"},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected %q, got error %q", i, test.expected, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+// checkOperation processes expression with c set to a container and
+// checks it outputs expected or fails with a RuntimeError
+func checkOperation(t *testing.T, expression, expected string) {
+	t.Helper()
+
+	actual, err := processText(t, "{{c.d = 1}}{{: "+expression+"}}")
+
+	if expected == typeError {
+		var runtimeError *RuntimeError
+		if !errors.As(err, &runtimeError) {
+			t.Errorf("%s: expected a runtime error, got %q, %v", expression, actual, err)
+		}
+	} else if err != nil {
+		t.Errorf("%s: expected %q, got error %q", expression, expected, err)
+	} else if actual != expected {
+		t.Errorf("%s: expected %q, got %q", expression, expected, actual)
+	}
+}
+
+func TestRuntimeErrorMessages(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{"{{: true + 1}}", `1:5: error: cannot apply "+" to bool and int`},
+		{`{{: -"a"}}`, `1:5: error: cannot apply "-" to string`},
+		{"{{c.d = 1}}\n{{: 1 < c}}", `2:5: error: cannot apply "<" to int and container`},
+		{"{{: 7 / 0}}", `1:5: error: division by zero in 7 / 0`},
+		{"{{x = 0}}\n{{: 7.5 % x}}", `2:5: error: division by zero in 7.5 % 0`},
+	}
+
+	for i, test := range tests {
+		_, err := processText(t, test.text)
+
+		if err == nil {
+			t.Errorf("%d: expected error %q, got nil", i, test.expected)
+		} else if err.Error() != test.expected {
+			t.Errorf("%d: expected error %q, got %q", i, test.expected, err)
+		}
+	}
+}
+
+func TestOperandErrorsAreReturned(t *testing.T) {
+	var tests = []string{
+		"{{: (true + 1) == 2}}",
+		"{{: 2 == (true + 1)}}",
+		"{{: !(true + 1)}}",
+		"{{if (true + 1) == 2}}a{{end}}",
+	}
+
+	for i, text := range tests {
+		if _, err := processText(t, text); err == nil {
+			t.Errorf("%d: expected the error in %q to be returned", i, text)
+		}
+	}
+}
+
+func TestIfConditionTruthiness(t *testing.T) {
+	var tests = []struct {
+		condition string
+		expected  string
+	}{
+		{"true", "yes"},
+		{"false", "no"},
+		{"1", "yes"},
+		{"-1", "yes"},
+		{"0", "no"},
+		{`"a"`, "yes"},
+		{`"false"`, "yes"},
+		{`""`, "no"},
+		{"full", "yes"},
+		{"missing", "no"},
+	}
+
+	for i, test := range tests {
+		text := "{{full.a = 1}}{{if " + test.condition + "}}yes{{else}}no{{end}}"
+		actual, err := processText(t, text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error for %s, got %q", i, test.condition, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q for %s, got %q", i, test.expected, test.condition, actual)
+		}
+	}
+}
+
+func TestElseIfConditionTruthiness(t *testing.T) {
+	actual, err := processText(t, `{{if 0}}a{{else_if ""}}b{{else_if "c"}}c{{end}}`)
+
+	if err != nil {
+		t.Errorf("expected no error, got %q", err)
+	} else if actual != "c" {
+		t.Errorf("expected %q, got %q", "c", actual)
+	}
+}
+
+func TestLoopBodyErrorsAreReturned(t *testing.T) {
+	_, err := processText(t, "{{posts.a.title = 1}}{{for post in posts}}{{: post.title + true}}{{end}}")
+
+	var runtimeError *RuntimeError
+	if !errors.As(err, &runtimeError) {
+		t.Errorf("expected a runtime error from the loop body, got %v", err)
+	}
+}
+
+func TestProcessFloats(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{"{{: 4.5}}", "4.5"},
+		{"{{: 1.5 + 2}}", "3.5"},
+		{"{{: 7 / 2}}", "3"},
+		{"{{: 7 / 2.0}}", "3.5"},
+		{"{{: -0.25 * 2}}", "-0.5"},
+		{"{{: 2.5 > 2}}", "true"},
+		{`{{: "$" + 9.99}}`, "$9.99"},
+		{"{{price = 19.5}}{{: fixed(price * 2, 2)}}", "39.00"},
+		{"{{: formatNumber(1234567.891, 2)}}", "1,234,567.89"},
+		{"{{if 0.0}}yes{{else}}no{{end}}", "no"},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestListAndMapLiterals(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{items = ["a", "b", 3]}}{{: items[0]}}{{: items[2] + 1}}`, "a4"},
+		{`{{i = 1}}{{items = ["x", "y"]}}{{: items[i]}}{{: items[i - 1]}}`, "yx"},
+		{`{{m = {"k": 1, "n": {"deep": [true]}}}}{{: m["k"]}} {{: m.n.deep[0]}} {{: m["n"]["deep"][0]}}`, "1 true true"},
+		{`{{m = {"a": 1}}}{{m["b"] = 2}}{{: m.a + m.b}}`, "3"},
+		{`{{m = {"a": 1, "a": 2}}}{{: m.a}}`, "2"},
+		{`{{for x in ["a", "b"]}}<{{: x}}>{{end}}`, "<a><b>"},
+		{`{{for p in [{"t": "one"}, {"t": "two"}]}}{{: p.t}},{{end}}`, "one,two,"},
+		{`{{for n in {"b": 2, "a": 1}}}{{: n}}{{end}}`, "12"},
+		{`{{empty = []}}{{for x in empty}}x{{end}}done`, "done"},
+		{`{{if []}}full{{else}}empty{{end}}`, "empty"},
+		{`{{if {"a": 1}}}full{{else}}empty{{end}}`, "full"},
+		{`{{items = ["a"]}}{{: items[5]}}`, ""},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestLiteralsCanBePassedToFunctions(t *testing.T) {
+	cacheTemplate(t, "literals/card.html", `<b>{{: title}}</b>`)
+
+	actual, err := processText(t, `{{: include("literals/card.html", {"title": "Hi"})}}`)
+	if err != nil {
+		t.Errorf("expected no error, got %q", err)
+	} else if actual != "<b>Hi</b>" {
+		t.Errorf("expected %q, got %q", "<b>Hi</b>", actual)
+	}
+}
+
+func TestLiteralsAreExported(t *testing.T) {
+	exportStore := NewExportFileStore("literals/exported.html")
+	processor := NewNodeProcessor("", &Context{}, nil, exportStore, nil, 0, 0)
+
+	for _, node := range parseText(t, `{{tags = ["go", "ssg"]}}`) {
+		if _, err := processor.processHeadNode(node); err != nil {
+			t.Fatalf("expected no error, got %q", err)
+		}
+	}
+
+	node, ok := exportStore.GetContext().At("tags.1")
+	if !ok || node.result != StringResult("ssg") {
+		t.Errorf("expected tags.1 to be exported as ssg, got %v", node)
+	}
+}
+
+func TestExpressionsCanBeIndexed(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{: [1, 2, 3][1]}}`, "2"},
+		{`{{: {"a": 1}["a"]}}`, "1"},
+		{`{{: split("a,b", ",")[1]}}`, "b"},
+		{`{{: {"a": {"b": ["x", "y"]}}["a"].b[1]}}`, "y"},
+		{`{{: [[1, 2], [3]][0][1] + 1}}`, "3"},
+		{`{{: ("a,b" | split(","))[0]}}`, "a"},
+		{`{{for x in [["a", "b"], ["c"]][0]}}{{: x}}{{end}}`, "ab"},
+		{`{{: [1][5]}}`, ""},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestLoopInputsCanBePiped(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{for w in "a b" | split(" ")}}[{{: w}}]{{end}}`, "[a][b]"},
+		{`{{xs = [3, 1, 2]}}{{for x in xs | sort | reverse}}{{: x}}{{end}}`, "321"},
+		{`{{for x in [1, 2] | last(1)}}{{: x}}{{end}}`, "2"},
+		{`{{for w in ("a b" | split(" "))}}[{{: w}}]{{end}}`, "[a][b]"},
+		{`{{for x in (false ? [1] : [])}}{{: x}}{{else}}none{{end}}`, "none"},
+		{`{{for i, w in "a b" | split(" ")}}{{: i}}{{: w}}{{end}}`, "0a1b"},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestInvalidIndexReturnsError(t *testing.T) {
+	var tests = []string{
+		`{{items = [1]}}{{: items[true]}}`,
+		`{{items = [1]}}{{: items[[0]]}}`,
+		`{{items = [1]}}{{: items[true + 1]}}`,
+		`{{: upper("a")[0]}}`,
+		`{{: [1][true]}}`,
+	}
+
+	for i, text := range tests {
+		if _, err := processText(t, text); err == nil {
+			t.Errorf("%d: expected an error for %s", i, text)
+		}
+	}
+}
+
+func TestLoopVariable(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{for x in ["a", "b", "c"]}}{{: loop.index}}{{: loop.index1}}{{: x}} {{end}}`, "01a 12b 23c "},
+		{`{{for x in ["a", "b", "c"]}}{{if loop.first}}F{{end}}{{if loop.last}}L{{end}}{{: loop.length}} {{end}}`, "F3 3 L3 "},
+		{`{{for x in ["a"]}}{{if loop.first && loop.last}}only{{end}}{{end}}`, "only"},
+		{`{{for p in [{"t": 1}, {"t": 2}]}}{{: p.t}}{{if !loop.last}}, {{end}}{{end}}`, "1, 2"},
+		{`{{for x in [1, 2]}}{{for y in ["a", "b"]}}{{: loop.parent.index}}{{: loop.index}} {{end}}{{end}}`, "00 01 10 11 "},
+		{`{{for x in [1]}}{{if loop.parent}}nested{{else}}top{{end}}{{end}}`, "top"},
+		{`{{for loop in ["mine"]}}{{: loop}}{{end}}`, "mine"},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestKeyValueLoops(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{for i, x in ["a", "b"]}}{{: i}}={{: x}} {{end}}`, "0=a 1=b "},
+		{`{{for i, x in ["a", "b"]}}{{: i + 1}}{{end}}`, "12"},
+		{`{{for k, v in {"b": 2, "a": 1}}}{{: k}}={{: v}} {{end}}`, "a=1 b=2 "},
+		{`{{for k, p in {"x": {"t": 1}}}}{{: k}}{{: p.t}}{{end}}`, "x1"},
+		{`{{site.title = "Home"}}{{site.year = 2024}}{{for k, v in site}}{{: k}}:{{: v}} {{end}}`, "title:Home year:2024 "},
+		{`{{for k, v in {}}}item{{else}}empty{{end}}`, "empty"},
+		{`{{for i, x in [1]}}{{: loop.index}}{{: i}}{{end}}`, "00"},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestRangeLoops(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{for i in range(3)}}{{: i}}{{end}}`, "012"},
+		{`{{for i in range(1, 4)}}{{: i}}{{end}}`, "123"},
+		{`{{for i in range(1, 10, 3)}}{{: i}},{{end}}`, "1,4,7,"},
+		{`{{for i in range(5, 0, -2)}}{{: i}},{{end}}`, "5,3,1,"},
+		{`{{for i in range(5, 0)}}{{: i}}{{else}}none{{end}}`, "none"},
+		{`{{for i, n in range(10, 12)}}{{: i}}{{: n}} {{end}}`, "010 111 "},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestPipes(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{: 4.567 | round}}`, "5"},
+		{`{{: 4.567 | round(2)}}`, "4.57"},
+		{`{{: 1234.5 | round | formatNumber}}`, "1,235"},
+		{`{{: 1 + 2 | fixed(2)}}`, "3.00"},
+		{`{{x = 3.14159 | fixed(1)}}{{: x}}`, "3.1"},
+		{`{{: fixed(1.5 | round, 2)}}`, "2.00"},
+		{`{{if 0.4 | round}}yes{{else}}no{{end}}`, "no"},
+		{`{{for i in range(3)}}{{: i * 1.5 | fixed(1)}} {{end}}`, "0.0 1.5 3.0 "},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestRegisteredFunctionsWorkAsFilters(t *testing.T) {
+	module := NewBuiltinFunctionModule(IntResult(0), IntResult(0), StringResult(""))
+	module.registerFunc("shout", func(args ...Result) (Result, error) {
+		return StringResult(strings.ToUpper(args[0].String()) + "!"), nil
+	})
+
+	processor := NewNodeProcessor("", &Context{}, nil, &TestExportStore{}, module, 0, 0)
+	head := parseText(t, `{{: "hi" | shout | shout}}`)[0]
+
+	result, err := processor.processHeadNode(head)
+	if err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	if result.String() != "HI!!" {
+		t.Errorf("expected %q, got %q", "HI!!", result.String())
+	}
+}
+
+func TestUnknownFilterReturnsError(t *testing.T) {
+	if _, err := processText(t, `{{: "hi" | nope}}`); err == nil {
+		t.Errorf("expected an error for an unregistered filter")
+	}
+}
+
+func TestCommentsAndRawBlocks(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{"a{{# note #}}b", "ab"},
+		{"{{x = 2}}{{# {{: x + 1}} #}}{{: x}}", "2"},
+		{"{{if true}}{{# why\nthis is here #}}yes{{end}}", "yes"},
+		{"{{raw}}{{: x}}{{endraw}}", "{{: x}}"},
+		{"{{raw}}{{for x in y}}\n{{x}}\n{{end}}{{endraw}}{{: 1 + 1}}", "{{for x in y}}\n{{x}}\n{{end}}2"},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestTrimMarkers(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{"<ul>\n  {{- for x in [1, 2] -}}\n    <li>{{: x}}</li>\n  {{- end -}}\n</ul>", "<ul><li>1</li><li>2</li></ul>"},
+		{"a b   {{- if true -}}   c d  {{- else -}}  e {{- end}}  f", "a bc d  f"},
+		{"a  \n\n  {{-: 1 -}}  \n\n  b", "a1b"},
+		{"{{x = 5}}{{: x -}}   z", "5z"},
+		{"{{if true -}}\n\n{{- end}}!", "!"},
+		{"x{{-1}}y", "xy"},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{: "say \"hi\"\n\ttab \\ \u{1F600}"}}`, "say \"hi\"\n\ttab \\ 😀"},
+		{`{{: 'it\'s' + " " + 'say "hi"'}}`, `it's say "hi"`},
+		{`{{x = 'single'}}{{: x == "single"}}`, "true"},
+		{"{{: `first\nsecond`}}", "first\nsecond"},
+		{"{{x = `a\n\nb` + \"c\"}}{{: x}}\n{{: 1 + 2}}", "a\n\nbc\n3"},
+		{"{{: `\\n is not escaped`}}", `\n is not escaped`},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestModulo(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{: 7 % 3}}`, "1"},
+		{`{{: 7 % 4 * 2}}`, "6"},
+		{`{{: 1 + 7 % 4}}`, "4"},
+		{`{{: 7.5 % 2}}`, "1.5"},
+		{`{{for i in range(4)}}{{: i % 2 == 0 ? "even" : "odd"}} {{end}}`, "even odd even odd "},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestFuncCallsInExpressions(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{: round(1.4) + round(1.6)}}`, "3"},
+		{`{{x = floor(2.5) * 2}}{{: x}}`, "4"},
+		{`{{if round(0.6)}}yes{{end}}`, "yes"},
+		{`{{l = [round(1.5), ceil(0.1)]}}{{: l[1]}}`, "1"},
+		{`{{: -round(1.5) | fixed(1)}}`, "-2.0"},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestIndexedFunctionNamesReturnError(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{: upper[0]("abc")}}`, `1:11: error: cannot index function "upper"`},
+		{`{{: upper["zz"].q("abc")}}`, `1:11: error: cannot index function "upper.q"`},
+		{`{{: "abc" | upper[0]}}`, `1:19: error: cannot index function "upper"`},
+	}
+
+	for i, test := range tests {
+		_, err := processText(t, test.text)
+
+		if err == nil {
+			t.Errorf("%d: expected error %q, got nil", i, test.expected)
+		} else if err.Error() != test.expected {
+			t.Errorf("%d: expected error %q, got %q", i, test.expected, err)
+		}
+	}
+}
+
+func TestConditionalExpressions(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{: true ? "a" : "b"}}`, "a"},
+		{`{{: 0 ? "a" : "b"}}`, "b"},
+		{`{{: 1 > 2 ? "a" : 2 > 1 ? "b" : "c"}}`, "b"},
+		{`{{: false || true ? 1 : 2}}`, "1"},
+		{`{{: true ? "a" : "b" + "c"}}`, "a"},
+		{`{{: true ? 1.25 : 2 | fixed(1)}}`, "1.2"},
+		{`{{x = true ? 1 : 2}}{{: x}}`, "1"},
+		{`{{m = {"a": false ? 1 : 2}}}{{: m.a}}`, "2"},
+		{`{{l = [true ? 1 : 2, 3]}}{{: l[0]}}`, "1"},
+		{`{{: missing ?? "default"}}`, "default"},
+		{`{{: "" ?? [] ?? "third"}}`, "third"},
+		{`{{: 0 ?? 5}} {{: false ?? true}}`, "0 false"},
+		{`{{: missing ?? "a" ? "yes" : "no"}}`, "yes"},
+		{`{{: missing ?? 1 + 1}}`, "2"},
+		// the branch that isn't taken is never evaluated
+		{`{{: true ? "ok" : (true + 1)}}`, "ok"},
+		{`{{: false ? (true + 1) : "ok"}}`, "ok"},
+		{`{{: "ok" ?? (true + 1)}}`, "ok"},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestConditionalExpressionsReturnErrorsFromTheTakenBranch(t *testing.T) {
+	var tests = []string{
+		`{{: true ? (true + 1) : "ok"}}`,
+		`{{: (true + 1) ? "a" : "b"}}`,
+		`{{: "" ?? (true + 1)}}`,
+	}
+
+	for i, text := range tests {
+		_, err := processText(t, text)
+
+		var runtimeError *RuntimeError
+		if !errors.As(err, &runtimeError) {
+			t.Errorf("%d: expected a RuntimeError, got %v", i, err)
+		}
+	}
+}
+
+func TestForElseRendersWhenInputIsEmpty(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{for x in []}}item{{else}}empty{{end}}`, "empty"},
+		{`{{for x in {}}}item{{else}}empty{{end}}`, "empty"},
+		{`{{for x in [1, 2]}}item{{else}}empty{{end}}`, "itemitem"},
+		{`{{for x in missing}}item{{else}}empty{{end}}`, "empty"},
+		{`{{none = []}}{{for x in none}}item{{else}}{{: loop.length}}{{end}}`, ""},
+		{`{{for x in []}}item{{end}}`, ""},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestForElseRendersWhenContentPathIsEmpty(t *testing.T) {
+	text := `{{for post in "posts"}}{{: post.title}}{{else}}no posts{{end}}`
+	head := parseText(t, text)[0]
+
+	resultChan := runProcessWithGetPathFunc(head, &TestExportStore{}, getTestPathReader(0))
+	if actual := (<-resultChan).String(); actual != "no posts" {
+		t.Errorf("expected %q, got %q", "no posts", actual)
+	}
+}
+
+// processTextWithPolicy processes text with undefined variables handled
+// by policy and returns its output and the warnings it found
+func processTextWithPolicy(t *testing.T, text string, policy config.UndefinedPolicy) (string, []error, error) {
+	processor := NewNodeProcessor("", &Context{}, nil, &TestExportStore{}, nil, 0, 0)
+	processor.UndefinedVariables = policy
+
+	output := ""
+	for _, node := range parseText(t, text) {
+		result, err := processor.processHeadNode(node)
+		if err != nil {
+			return "", processor.takeWarnings(), err
+		}
+		output += result.String()
+	}
+
+	return output, processor.takeWarnings(), nil
+}
+
+func TestUndefinedVariablePolicies(t *testing.T) {
+	var tests = []struct {
+		text     string
+		policy   config.UndefinedPolicy
+		expected string
+		warnings []string
+		err      string
+	}{
+		{`a{{: post.titel}}b`, config.UndefinedSilent, "ab", nil, ""},
+		{`a{{: post.titel}}b`, "", "ab", nil, ""},
+		{`a{{: post.titel}}b`, config.UndefinedWarn, "ab", []string{`1:6: warning: undefined variable "post.titel"`}, ""},
+		{"{{post.title = 1}}\n{{: post.titel}}", config.UndefinedError, "", nil, `2:5: error: undefined variable "post.titel"`},
+		{`{{for i in [1, 2]}}{{: missing}}{{end}}`, config.UndefinedWarn, "", []string{
+			`1:24: warning: undefined variable "missing"`,
+			`1:24: warning: undefined variable "missing"`,
+		}, ""},
+		{`{{if missing}}a{{else}}b{{end}}`, config.UndefinedError, "", nil, `1:6: error: undefined variable "missing"`},
+		{`{{x = 1}}{{: x}}`, config.UndefinedError, "1", nil, ""},
+	}
+
+	for i, test := range tests {
+		actual, warnings, err := processTextWithPolicy(t, test.text, test.policy)
+
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%d: expected error %q, got %v", i, test.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+
+		if len(warnings) != len(test.warnings) {
+			t.Errorf("%d: expected %d warnings, got %v", i, len(test.warnings), warnings)
+			continue
+		}
+		for j, warning := range warnings {
+			if warning.Error() != test.warnings[j] || diagnostic.IsError(warning) {
+				t.Errorf("%d: expected warning %q, got %q", i, test.warnings[j], warning)
+			}
+		}
+	}
+}
+
+func TestDefinedAndDefaultAllowUndefinedVariables(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{: defined(missing)}}`, "false"},
+		{`{{: defined(post.title)}}`, "false"},
+		{`{{post.title = ""}}{{: defined(post.title)}}`, "true"},
+		{`{{: defined("literal")}}`, "true"},
+		{`{{if defined(missing)}}a{{else}}b{{end}}`, "b"},
+		{`{{: default(missing, "fallback")}}`, "fallback"},
+		{`{{: default("", "fallback")}}`, "fallback"},
+		{`{{: default(0, 5)}}`, "0"},
+		{`{{x = "set"}}{{: default(x, "fallback")}}`, "set"},
+		{`{{: missing | default("piped")}}`, "piped"},
+		{`{{: post.subtitle ?? "coalesced"}}`, "coalesced"},
+	}
+
+	for i, test := range tests {
+		actual, warnings, err := processTextWithPolicy(t, test.text, config.UndefinedError)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		} else if len(warnings) != 0 {
+			t.Errorf("%d: expected no warnings, got %v", i, warnings)
+		}
+	}
+}
+
+func TestDefinedAndDefaultOnlyAllowUndefinedArguments(t *testing.T) {
+	var tests = []string{
+		`{{: defined(missing + 1)}}`,
+		`{{: default(missing, other)}}`,
+		`{{: default(missing.key ?? other, 1)}}`,
+	}
+
+	for i, text := range tests {
+		if _, _, err := processTextWithPolicy(t, text, config.UndefinedError); err == nil {
+			t.Errorf("%d: expected an undefined variable error for %q", i, text)
+		}
+	}
+}
+
+func TestProcessSendsWarningsWithoutStopping(t *testing.T) {
+	processor := NewNodeProcessor("", &Context{}, nil, &TestExportStore{}, nil, 0, 0)
+	processor.UndefinedVariables = config.UndefinedWarn
+
+	nodeChan := getNodeChan(parseText(t, "{{: a}}\n{{: b}}\ndone"))
+	resultChan, errChan := processor.Process(nodeChan, goContext.Background())
+
+	warnings := []string{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for err := range errChan {
+			warnings = append(warnings, err.Error())
+		}
+	}()
+
+	output := ""
+	for result := range resultChan {
+		output += result.String()
+	}
+	<-done
+
+	if output != "\n\ndone" {
+		t.Errorf("expected the whole file to be processed, got %q", output)
+	}
+
+	expected := []string{
+		`1:5: warning: undefined variable "a"`,
+		`2:5: warning: undefined variable "b"`,
+	}
+	if strings.Join(warnings, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected warnings %q, got %q", expected, warnings)
+	}
+}
+
+func TestHTMLOutputEscapesPrintedValues(t *testing.T) {
+	cacheTemplate(t, "partials/escaped.html", `<b>{{: label}}</b>`)
+	cacheTemplate(t, "partials/escaped.txt", `<b>{{: label}}</b>`)
+	cacheTemplate(t, "partials/paginated.txt", `{{for item in content}}<b>{{: item}}</b>{{end}}`)
+
+	var tests = []struct {
+		path     string
+		text     string
+		expected string
+	}{
+		{"page.html", `{{label = "<i>"}}<p>{{: label}}</p>`, "<p>&lt;i&gt;</p>"},
+		{"page.html", `{{label = "<i>"}}<p>{{: safe(label)}}</p>`, "<p><i></p>"},
+		{"page.html", `{{label = "a" + safe("<i>")}}<p>{{: label}}</p>`, "<p>a&lt;i&gt;</p>"},
+		{"page.html", `{{label = "<i>"}}<p>{{: include("partials/escaped.html")}}</p>`, "<p><b>&lt;i&gt;</b></p>"},
+		{"page.html", `{{url = "javascript:x"}}<a href="{{: url}}">{{: url}}</a>`, `<a href="#unsafe-url">javascript:x</a>`},
+		{"page.html", `<ul>{{for n in [1, 2]}}<li title="{{: "<" + n}}">{{end}}</ul>`, `<ul><li title="&lt;1"><li title="&lt;2"></ul>`},
+		{"page.html", `{{label = "<i>"}}<p>{{: include("partials/escaped.txt")}}</p>`, "<p><b>&lt;i&gt;</b></p>"},
+		{"page.html", `{{: paginate(["<i>"], "partials/paginated.txt", 1)}}`, "<b>&lt;i&gt;</b>"},
+		{"page.txt", `{{label = "<i>"}}{{: label}}`, "&lt;i&gt;"},
+		{"", `{{label = "<i>"}}{{: label}}`, "<i>"},
+		{"", `{{label = "<i>"}}{{: include("partials/escaped.html")}}`, "<b><i></b>"},
+	}
+
+	for i, test := range tests {
+		processor := NewNodeProcessor(test.path, &Context{}, nil, &TestExportStore{}, nil, 1, 0)
+
+		output := ""
+		for _, node := range parseText(t, test.text) {
+			result, err := processor.processHeadNode(node)
+			if err != nil {
+				t.Fatalf("%d: expected no errors, got %q", i, err)
+			}
+			output += result.String()
+		}
+
+		if output != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, output)
+		}
+	}
+}
+
+func TestMarkdownEscapesPrintedValuesAsText(t *testing.T) {
+	cacheTemplate(t, "partials/label.html", `<b>{{: label}}</b>`)
+
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{label = "<i>"}}{{: label}}`, "<p>&lt;i&gt;</p>\n"},
+		{`Use the <script> tag {{: "Hello"}}`, "<p>Use the <script> tag Hello</p>\n"},
+		{`Use the <script> tag {{: "\"Hi\""}}`, "<p>Use the <script> tag &#34;Hi&#34;</p>\n"},
+		{"`{{: \"a < b && c\"}}`", "<p><code>a &lt; b &amp;&amp; c</code></p>\n"},
+		{"```\n{{: \"<i>\"}}\n```\n", "<pre><code>&lt;i&gt;\n</code></pre>\n"},
+		{"`&lt;` {{: \"&lt;\"}}", "<p><code>&amp;lt;</code> &amp;lt;</p>\n"},
+		{`<div title="{{: "'"}}">{{: "<b>"}}</div>`, "<p><div title=\"&#39;\">&lt;b&gt;</div></p>\n"},
+		{`{{: safe("<i>kept</i>")}}`, "<p><i>kept</i></p>\n"},
+		{`{{label = "<i>"}}{{: include("partials/label.html")}}`, "<p><b>&lt;i&gt;</b></p>\n"},
+	}
+
+	for i, test := range tests {
+		processor := NewNodeProcessor("page.md", &Context{}, nil, &TestExportStore{}, nil, 1, 0)
+
+		output := ""
+		for _, node := range parseText(t, test.text) {
+			result, err := processor.processHeadNode(node)
+			if err != nil {
+				t.Fatalf("%d: expected no errors, got %q", i, err)
+			}
+			output += result.String()
+		}
+
+		resultChan := make(chan Result, 1)
+		resultChan <- StringResult(output)
+		close(resultChan)
+
+		if rendered := (<-PostProcessMarkdown("page.md", resultChan)).String(); rendered != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, rendered)
+		}
+	}
+}
@@ -20,6 +20,7 @@ type SubtractableResult interface {
 type MultipliableResult interface {
 	Multiply(right Result) (Result, error)
 	Divide(right Result) (Result, error)
+	Mod(right Result) (Result, error)
 }
 
 type EqualityResult interface {
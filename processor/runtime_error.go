@@ -0,0 +1,106 @@
+package processor
+
+import "fmt"
+
+// RuntimeError is returned when an operator is applied to results
+// of types it doesn't support
+// Left is nil for unary operators
+type RuntimeError struct {
+	Operator string
+	Left     Result
+	Right    Result
+}
+
+func newBinaryError(operator string, left, right Result) *RuntimeError {
+	return &RuntimeError{Operator: operator, Left: left, Right: right}
+}
+
+func newUnaryError(operator string, right Result) *RuntimeError {
+	return &RuntimeError{Operator: operator, Right: right}
+}
+
+func (receiver *RuntimeError) Error() string {
+	if receiver.Left == nil {
+		return fmt.Sprintf("cannot apply %q to %s", receiver.Operator, typeName(receiver.Right))
+	}
+
+	return fmt.Sprintf("cannot apply %q to %s and %s",
+		receiver.Operator, typeName(receiver.Left), typeName(receiver.Right))
+}
+
+// DivisionByZeroError is returned when an int is divided by 0 or any
+// number is taken modulo 0
+type DivisionByZeroError struct {
+	Operator string
+	Left     Result
+}
+
+func newDivisionByZeroError(operator string, left Result) *DivisionByZeroError {
+	return &DivisionByZeroError{Operator: operator, Left: left}
+}
+
+func (receiver *DivisionByZeroError) Error() string {
+	return fmt.Sprintf("division by zero in %s %s 0", receiver.Left, receiver.Operator)
+}
+
+// typeName is the name of the type of result used in errors
+func typeName(result Result) string {
+	switch result.(type) {
+	case BoolResult:
+		return "bool"
+	case IntResult:
+		return "int"
+	case FloatResult:
+		return "float"
+	case StringResult:
+		return "string"
+	case SafeResult:
+		return "safe string"
+	case ContainerResult:
+		return "container"
+	case nil:
+		return "nothing"
+	default:
+		return fmt.Sprintf("%T", result)
+	}
+}
+
+// isTruthy reports whether result counts as true in a condition
+// false, 0, empty strings, empty containers and missing results are
+// false, everything else is true
+func isTruthy(result Result) bool {
+	switch typedResult := result.(type) {
+	case BoolResult:
+		return bool(typedResult)
+	case IntResult:
+		return typedResult != 0
+	case FloatResult:
+		return typedResult != 0
+	case StringResult:
+		return typedResult != ""
+	case SafeResult:
+		return typedResult != ""
+	case ContainerResult:
+		return typedResult.context != nil && len(*typedResult.context) > 0
+	default:
+		return false
+	}
+}
+
+// isEmpty reports whether result is missing, an empty string or an
+// empty container, the results ?? replaces
+// Unlike isTruthy, false and 0 are not empty
+func isEmpty(result Result) bool {
+	switch typedResult := result.(type) {
+	case StringResult:
+		return typedResult == ""
+	case SafeResult:
+		return typedResult == ""
+	case ContainerResult:
+		return typedResult.context == nil || len(*typedResult.context) == 0
+	case nil:
+		return true
+	default:
+		return false
+	}
+}
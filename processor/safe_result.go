@@ -0,0 +1,66 @@
+package processor
+
+// SafeResult is a string that is written to html output as is instead
+// of being escaped
+// Combining it with anything other than another SafeResult gives a
+// plain StringResult which is escaped again
+type SafeResult string
+
+// GetResult returns this result value
+func (receiver SafeResult) GetResult() interface{} {
+	return receiver
+}
+
+func (receiver SafeResult) String() string {
+	return string(receiver)
+}
+
+// Add concatenates right to this string, the result is only safe if
+// right is too
+func (receiver SafeResult) Add(right Result) (Result, error) {
+	if typedRight, ok := right.(SafeResult); ok {
+		return receiver + typedRight, nil
+	}
+
+	result, err := StringResult(receiver).Add(right)
+	if err != nil {
+		return nil, newBinaryError("+", receiver, right)
+	}
+	return result, nil
+}
+
+// EqualTo checks if the provided result is logically equal to
+// the receiver
+func (receiver SafeResult) EqualTo(right Result) (Result, error) {
+	return StringResult(receiver).EqualTo(right)
+}
+
+// NotEqualTo checks if the provided result is logically not equal
+// to the receiver
+func (receiver SafeResult) NotEqualTo(right Result) (Result, error) {
+	return StringResult(receiver).NotEqualTo(right)
+}
+
+// LessThan checks if the provided result is logically less than
+// the receiver
+func (receiver SafeResult) LessThan(right Result) (Result, error) {
+	return StringResult(receiver).LessThan(right)
+}
+
+// GreaterThan checks if the provided result is logically greater than
+// the receiver
+func (receiver SafeResult) GreaterThan(right Result) (Result, error) {
+	return StringResult(receiver).GreaterThan(right)
+}
+
+// LessThanEqual checks if the provided result is logically less than or
+// equal to the receiver
+func (receiver SafeResult) LessThanEqual(right Result) (Result, error) {
+	return StringResult(receiver).LessThanEqual(right)
+}
+
+// GreaterThanEqual checks if the provided result is logically greater
+// than or equal to the receiver
+func (receiver SafeResult) GreaterThanEqual(right Result) (Result, error) {
+	return StringResult(receiver).GreaterThanEqual(right)
+}
@@ -0,0 +1,28 @@
+package processor
+
+import (
+	"testing"
+)
+
+func TestSafeResultAddKeepsSafetyOnlyForSafeOperands(t *testing.T) {
+	var tests = []struct {
+		left     Result
+		right    Result
+		expected Result
+	}{
+		{SafeResult("<a>"), SafeResult("<b>"), SafeResult("<a><b>")},
+		{SafeResult("<a>"), StringResult("<b>"), StringResult("<a><b>")},
+		{SafeResult("<a>"), IntResult(1), StringResult("<a>1")},
+		{StringResult("<a>"), SafeResult("<b>"), StringResult("<a><b>")},
+	}
+
+	for i, test := range tests {
+		result, err := test.left.(AddableResult).Add(test.right)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if result != test.expected {
+			t.Errorf("%d: expected %#v, got %#v", i, test.expected, result)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// UpperRaw returns its argument in upper case
+func UpperRaw(args ...Result) (Result, error) {
+	return mapString("upper", strings.ToUpper, args)
+}
+
+// LowerRaw returns its argument in lower case
+func LowerRaw(args ...Result) (Result, error) {
+	return mapString("lower", strings.ToLower, args)
+}
+
+// TitleRaw upper cases the first letter of each word of its argument
+func TitleRaw(args ...Result) (Result, error) {
+	return mapString("title", titleCase, args)
+}
+
+// TrimRaw removes leading and trailing whitespace or, when given a
+// second argument, any of the characters in it
+func TrimRaw(args ...Result) (Result, error) {
+	if err := expectArgs("trim", args, 1, 2); err != nil {
+		return nil, err
+	}
+
+	strs, err := stringArgs("trim", args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(strs) == 1 {
+		return StringResult(strings.TrimSpace(strs[0])), nil
+	}
+	return StringResult(strings.Trim(strs[0], strs[1])), nil
+}
+
+// ReplaceRaw replaces every occurrence of its second argument in its
+// first with its third
+func ReplaceRaw(args ...Result) (Result, error) {
+	if err := expectArgs("replace", args, 3, 3); err != nil {
+		return nil, err
+	}
+
+	strs, err := stringArgs("replace", args)
+	if err != nil {
+		return nil, err
+	}
+
+	return StringResult(strings.ReplaceAll(strs[0], strs[1], strs[2])), nil
+}
+
+// SplitRaw returns a list of the parts of its first argument between
+// each occurrence of its second
+func SplitRaw(args ...Result) (Result, error) {
+	if err := expectArgs("split", args, 2, 2); err != nil {
+		return nil, err
+	}
+
+	strs, err := stringArgs("split", args)
+	if err != nil {
+		return nil, err
+	}
+
+	context := &Context{}
+	for i, part := range strings.Split(strs[0], strs[1]) {
+		context.Insert([]string{strconv.Itoa(i)}, StringResult(part))
+	}
+
+	return ContainerResult{context}, nil
+}
+
+// JoinRaw joins the values of a container into a string with its
+// second argument between each one
+func JoinRaw(args ...Result) (Result, error) {
+	if err := expectArgs("join", args, 2, 2); err != nil {
+		return nil, err
+	}
+
+	container, ok := args[0].(ContainerResult)
+	if !ok {
+		return nil, fmt.Errorf("`join` expects a container, got %s", typeName(args[0]))
+	}
+
+	sep, err := stringArg("join", args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	parts := []string{}
+	for _, key := range containerKeys(container) {
+		part, ok := convertToString(nodeResult((*container.context)[key]))
+		if !ok {
+			return nil, fmt.Errorf("`join` expects the values to be strings or numbers, got %s at %q",
+				typeName(nodeResult((*container.context)[key])), key)
+		}
+		parts = append(parts, part)
+	}
+
+	return StringResult(strings.Join(parts, sep)), nil
+}
+
+// ContainsRaw reports whether its second argument is in its first
+func ContainsRaw(args ...Result) (Result, error) {
+	return testStrings("contains", strings.Contains, args)
+}
+
+// HasPrefixRaw reports whether its first argument starts with its second
+func HasPrefixRaw(args ...Result) (Result, error) {
+	return testStrings("hasPrefix", strings.HasPrefix, args)
+}
+
+// HasSuffixRaw reports whether its first argument ends with its second
+func HasSuffixRaw(args ...Result) (Result, error) {
+	return testStrings("hasSuffix", strings.HasSuffix, args)
+}
+
+// TruncateRaw shortens its first argument to at most the given number
+// of characters, cutting at the end of a word if there is one, and adds
+// an ellipsis, "…" unless a third argument is given
+// e.g. truncate("a long title", 8) is "a long…"
+func TruncateRaw(args ...Result) (Result, error) {
+	if err := expectArgs("truncate", args, 2, 3); err != nil {
+		return nil, err
+	}
+
+	str, err := stringArg("truncate", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	length, ok := args[1].(IntResult)
+	if !ok || length < 0 {
+		return nil, fmt.Errorf("`truncate` expects length to be an int of at least 0, got %s", args[1])
+	}
+
+	ellipsis := "…"
+	if len(args) == 3 {
+		if ellipsis, err = stringArg("truncate", args[2]); err != nil {
+			return nil, err
+		}
+	}
+
+	return StringResult(truncateWords(str, int(length), ellipsis)), nil
+}
+
+// SlugifyRaw turns its argument into a lower case string of letters,
+// digits and dashes for use in URLs e.g. "Hello, World!" is "hello-world"
+func SlugifyRaw(args ...Result) (Result, error) {
+	return mapString("slugify", slugify, args)
+}
+
+// PluralizeRaw returns the singular word given as its second argument
+// when its first argument is 1 and the plural otherwise
+// The plural is the third argument or the singular with an s added
+// e.g. pluralize(3, "post") is "posts"
+func PluralizeRaw(args ...Result) (Result, error) {
+	if err := expectArgs("pluralize", args, 2, 3); err != nil {
+		return nil, err
+	}
+
+	count, err := numberArg("pluralize", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	words, err := stringArgs("pluralize", args[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	if count == 1 {
+		return StringResult(words[0]), nil
+	} else if len(words) == 2 {
+		return StringResult(words[1]), nil
+	}
+	return StringResult(words[0] + "s"), nil
+}
+
+// RegexMatchRaw reports whether the regular expression given as its
+// second argument matches any of its first
+func RegexMatchRaw(args ...Result) (Result, error) {
+	if err := expectArgs("regexMatch", args, 2, 2); err != nil {
+		return nil, err
+	}
+
+	strs, err := stringArgs("regexMatch", args)
+	if err != nil {
+		return nil, err
+	}
+
+	exp, err := compileRegex("regexMatch", strs[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return BoolResult(exp.MatchString(strs[0])), nil
+}
+
+// RegexReplaceRaw replaces the matches of the regular expression given
+// as its second argument with its third, which can refer to groups
+// like $1
+func RegexReplaceRaw(args ...Result) (Result, error) {
+	if err := expectArgs("regexReplace", args, 3, 3); err != nil {
+		return nil, err
+	}
+
+	strs, err := stringArgs("regexReplace", args)
+	if err != nil {
+		return nil, err
+	}
+
+	exp, err := compileRegex("regexReplace", strs[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return StringResult(exp.ReplaceAllString(strs[0], strs[2])), nil
+}
+
+// WordCountRaw returns the number of whitespace separated words in its
+// argument
+func WordCountRaw(args ...Result) (Result, error) {
+	if err := expectArgs("wordCount", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	str, err := stringArg("wordCount", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return IntResult(len(strings.Fields(str))), nil
+}
+
+// defaultWordsPerMinute is the reading speed readingTime uses when it
+// isn't given one
+const defaultWordsPerMinute = 200
+
+// ReadingTimeRaw returns the minutes it takes to read its argument,
+// rounded up, at 200 words per minute or the speed given as a second
+// argument
+func ReadingTimeRaw(args ...Result) (Result, error) {
+	if err := expectArgs("readingTime", args, 1, 2); err != nil {
+		return nil, err
+	}
+
+	str, err := stringArg("readingTime", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	wordsPerMinute := IntResult(defaultWordsPerMinute)
+	if len(args) == 2 {
+		var ok bool
+		if wordsPerMinute, ok = args[1].(IntResult); !ok || wordsPerMinute < 1 {
+			return nil, fmt.Errorf("`readingTime` expects words per minute to be an int of at least 1, got %s", args[1])
+		}
+	}
+
+	words := len(strings.Fields(str))
+	return IntResult(math.Ceil(float64(words) / float64(wordsPerMinute))), nil
+}
+
+// expectArgs returns an error if args doesn't have between min and max
+// arguments
+func expectArgs(funcName string, args []Result, min, max int) error {
+	if len(args) >= min && len(args) <= max {
+		return nil
+	}
+
+	switch {
+	case min == max && min == 1:
+		return fmt.Errorf("`%s` expects one argument, got %d", funcName, len(args))
+	case min == max:
+		return fmt.Errorf("`%s` expects %d args, got %d", funcName, min, len(args))
+	case max == min+1:
+		return fmt.Errorf("`%s` expects %d or %d args, got %d", funcName, min, max, len(args))
+	default:
+		return fmt.Errorf("`%s` expects %d to %d args, got %d", funcName, min, max, len(args))
+	}
+}
+
+// stringArg returns arg as a string if it is a string or a number
+func stringArg(funcName string, arg Result) (string, error) {
+	if str, ok := convertToString(arg); ok {
+		return str, nil
+	}
+
+	return "", fmt.Errorf("`%s` expects a string, got %s", funcName, typeName(arg))
+}
+
+func stringArgs(funcName string, args []Result) ([]string, error) {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		str, err := stringArg(funcName, arg)
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = str
+	}
+
+	return strs, nil
+}
+
+// mapString calls f with the only argument in args
+func mapString(funcName string, f func(string) string, args []Result) (Result, error) {
+	if err := expectArgs(funcName, args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	str, err := stringArg(funcName, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return StringResult(f(str)), nil
+}
+
+// testStrings calls f with the two arguments in args
+func testStrings(funcName string, f func(string, string) bool, args []Result) (Result, error) {
+	if err := expectArgs(funcName, args, 2, 2); err != nil {
+		return nil, err
+	}
+
+	strs, err := stringArgs(funcName, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return BoolResult(f(strs[0], strs[1])), nil
+}
+
+func compileRegex(funcName, pattern string) (*regexp.Regexp, error) {
+	exp, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("`%s` expects a valid regular expression: %s", funcName, err)
+	}
+
+	return exp, nil
+}
+
+func titleCase(str string) string {
+	runes := []rune(str)
+	for i, r := range runes {
+		if i == 0 || unicode.IsSpace(runes[i-1]) {
+			runes[i] = unicode.ToUpper(r)
+		}
+	}
+
+	return string(runes)
+}
+
+// truncateWords cuts str to at most length characters and adds
+// ellipsis, the cut is moved back to the end of a word if there is one
+// before it
+func truncateWords(str string, length int, ellipsis string) string {
+	if utf8.RuneCountInString(str) <= length {
+		return str
+	}
+
+	runes := []rune(str)
+	cut := length
+	for cut > 0 && !unicode.IsSpace(runes[cut]) && !unicode.IsSpace(runes[cut-1]) {
+		cut--
+	}
+
+	// a single word longer than length is cut in the middle
+	if cut == 0 {
+		cut = length
+	}
+
+	return strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + ellipsis
+}
+
+func slugify(str string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(str) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			dash = false
+		} else if r != '\'' {
+			dash = true
+		}
+	}
+
+	return slug.String()
+}
@@ -0,0 +1,165 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestStringFunctions(t *testing.T) {
+	var tests = []struct {
+		function func(...Result) (Result, error)
+		args     []Result
+		expected Result
+	}{
+		{UpperRaw, []Result{StringResult("Hello")}, StringResult("HELLO")},
+		{UpperRaw, []Result{SafeResult("<b>hi</b>")}, StringResult("<B>HI</B>")},
+		{LowerRaw, []Result{StringResult("HeLLo")}, StringResult("hello")},
+		{TitleRaw, []Result{StringResult("the quick  fox")}, StringResult("The Quick  Fox")},
+		{TitleRaw, []Result{StringResult("éclair au café")}, StringResult("Éclair Au Café")},
+		{TrimRaw, []Result{StringResult("  hi \n")}, StringResult("hi")},
+		{TrimRaw, []Result{StringResult("--hi-"), StringResult("-")}, StringResult("hi")},
+		{ReplaceRaw, []Result{StringResult("a-b-c"), StringResult("-"), StringResult("+")}, StringResult("a+b+c")},
+		{JoinRaw, []Result{listResult(StringResult("a"), IntResult(1)), StringResult(", ")}, StringResult("a, 1")},
+		{JoinRaw, []Result{listResult(), StringResult(", ")}, StringResult("")},
+		{ContainsRaw, []Result{StringResult("frizzy"), StringResult("izz")}, BoolResult(true)},
+		{ContainsRaw, []Result{StringResult("frizzy"), StringResult("fuzz")}, BoolResult(false)},
+		{HasPrefixRaw, []Result{StringResult("/posts/a"), StringResult("/posts/")}, BoolResult(true)},
+		{HasSuffixRaw, []Result{StringResult("a.md"), StringResult(".html")}, BoolResult(false)},
+		{TruncateRaw, []Result{StringResult("a long title"), IntResult(8)}, StringResult("a long…")},
+		{TruncateRaw, []Result{StringResult("a long title"), IntResult(6)}, StringResult("a long…")},
+		{TruncateRaw, []Result{StringResult("short"), IntResult(10)}, StringResult("short")},
+		{TruncateRaw, []Result{StringResult("Hello, world"), IntResult(9), StringResult("...")}, StringResult("Hello...")},
+		{TruncateRaw, []Result{StringResult("supercalifragilistic"), IntResult(5)}, StringResult("super…")},
+		{TruncateRaw, []Result{StringResult("héllo wörld"), IntResult(8)}, StringResult("héllo…")},
+		{SlugifyRaw, []Result{StringResult("Hello, World!")}, StringResult("hello-world")},
+		{SlugifyRaw, []Result{StringResult("  Don't Panic -- 42  ")}, StringResult("dont-panic-42")},
+		{SlugifyRaw, []Result{StringResult("Crème brûlée")}, StringResult("crème-brûlée")},
+		{PluralizeRaw, []Result{IntResult(1), StringResult("post")}, StringResult("post")},
+		{PluralizeRaw, []Result{IntResult(0), StringResult("post")}, StringResult("posts")},
+		{PluralizeRaw, []Result{IntResult(2), StringResult("child"), StringResult("children")}, StringResult("children")},
+		{PluralizeRaw, []Result{FloatResult(1.5), StringResult("hour")}, StringResult("hours")},
+		{RegexMatchRaw, []Result{StringResult("2021-03-04"), StringResult(`^\d{4}-\d{2}`)}, BoolResult(true)},
+		{RegexMatchRaw, []Result{StringResult("draft"), StringResult(`^\d`)}, BoolResult(false)},
+		{RegexReplaceRaw, []Result{StringResult("2021-03-04"), StringResult(`(\d+)-(\d+)-(\d+)`), StringResult("$3/$2/$1")}, StringResult("04/03/2021")},
+		{WordCountRaw, []Result{StringResult(" one two\nthree ")}, IntResult(3)},
+		{WordCountRaw, []Result{StringResult("")}, IntResult(0)},
+		{ReadingTimeRaw, []Result{StringResult("one two three")}, IntResult(1)},
+		{ReadingTimeRaw, []Result{StringResult("")}, IntResult(0)},
+		{ReadingTimeRaw, []Result{StringResult("one two three"), IntResult(2)}, IntResult(2)},
+	}
+
+	for i, test := range tests {
+		actual, err := test.function(test.args...)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %#v, got %#v", i, test.expected, actual)
+		}
+	}
+}
+
+func TestSplitRaw(t *testing.T) {
+	var tests = []struct {
+		str      string
+		sep      string
+		expected []string
+	}{
+		{"a,b,c", ",", []string{"a", "b", "c"}},
+		{"a", ",", []string{"a"}},
+		{"a, b", ", ", []string{"a", "b"}},
+		{"", ",", []string{""}},
+	}
+
+	for i, test := range tests {
+		result, err := SplitRaw(StringResult(test.str), StringResult(test.sep))
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+			continue
+		}
+
+		context := result.(ContainerResult).context
+		keys := context.Keys()
+		if len(keys) != len(test.expected) {
+			t.Errorf("%d: expected %d parts, got %d", i, len(test.expected), len(keys))
+			continue
+		}
+
+		for j, key := range keys {
+			if node := (*context)[key]; node.result != StringResult(test.expected[j]) {
+				t.Errorf("%d: expected part %d to be %q, got %q", i, j, test.expected[j], node.result)
+			}
+		}
+	}
+}
+
+func TestStringFunctionsRejectInvalidArgs(t *testing.T) {
+	var tests = []struct {
+		function func(...Result) (Result, error)
+		args     []Result
+		expected string
+	}{
+		{UpperRaw, []Result{}, "`upper` expects one argument, got 0"},
+		{LowerRaw, []Result{BoolResult(true)}, "`lower` expects a string, got bool"},
+		{TrimRaw, []Result{StringResult("a"), StringResult("b"), StringResult("c")}, "`trim` expects 1 or 2 args, got 3"},
+		{ReplaceRaw, []Result{StringResult("a"), StringResult("b")}, "`replace` expects 3 args, got 2"},
+		{SplitRaw, []Result{listResult(), StringResult(",")}, "`split` expects a string, got container"},
+		{JoinRaw, []Result{StringResult("a"), StringResult(",")}, "`join` expects a container, got string"},
+		{JoinRaw, []Result{listResult(listResult()), StringResult(",")}, "`join` expects the values to be strings or numbers, got container at \"0\""},
+		{ContainsRaw, []Result{StringResult("a")}, "`contains` expects 2 args, got 1"},
+		{TruncateRaw, []Result{StringResult("a"), StringResult("b")}, "`truncate` expects length to be an int of at least 0, got b"},
+		{TruncateRaw, []Result{StringResult("a"), IntResult(-1)}, "`truncate` expects length to be an int of at least 0, got -1"},
+		{PluralizeRaw, []Result{StringResult("many"), StringResult("post")}, "`pluralize` expects a number, got string"},
+		{RegexMatchRaw, []Result{StringResult("a"), StringResult("(")}, "`regexMatch` expects a valid regular expression: error parsing regexp: missing closing ): `(`"},
+		{RegexReplaceRaw, []Result{StringResult("a"), StringResult("a")}, "`regexReplace` expects 3 args, got 2"},
+		{WordCountRaw, []Result{ContainerResult{&Context{}}}, "`wordCount` expects a string, got container"},
+		{ReadingTimeRaw, []Result{StringResult("a"), IntResult(0)}, "`readingTime` expects words per minute to be an int of at least 1, got 0"},
+	}
+
+	for i, test := range tests {
+		_, err := test.function(test.args...)
+
+		if err == nil {
+			t.Errorf("%d: expected error %q, got nil", i, test.expected)
+		} else if err.Error() != test.expected {
+			t.Errorf("%d: expected error %q, got %q", i, test.expected, err)
+		}
+	}
+}
+
+func TestStringFunctionsInTemplates(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{: "my first post" | title}}`, "My First Post"},
+		{`{{: "a,b" | split(",") | join(" & ")}}`, "a & b"},
+		{`{{for tag in split("go,ssg", ",")}}<{{: tag | upper}}>{{end}}`, "<GO><SSG>"},
+		{`{{n = 3}}{{: n}} {{: pluralize(n, "minute")}}`, "3 minutes"},
+		{`{{if "/posts/a" | hasPrefix("/posts")}}post{{end}}`, "post"},
+		{`{{: "Hello World" | slugify}}`, "hello-world"},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+// listResult returns a container holding values as a list
+func listResult(values ...Result) ContainerResult {
+	context := &Context{}
+	for i, value := range values {
+		if container, ok := value.(ContainerResult); ok {
+			(*context)[fmt.Sprint(i)] = &ContextNode{child: container.context}
+		} else {
+			(*context)[fmt.Sprint(i)] = &ContextNode{result: value}
+		}
+	}
+	return ContainerResult{context}
+}
@@ -1,9 +1,6 @@
 package processor
 
-import (
-	"fmt"
-	"strconv"
-)
+import "strconv"
 
 type StringResult string
 
@@ -19,10 +16,14 @@ func (receiver StringResult) Add(right Result) (Result, error) {
 	switch typedRight := right.(type) {
 	case IntResult:
 		return StringResult(string(receiver) + strconv.Itoa(int(typedRight))), nil
+	case FloatResult:
+		return StringResult(string(receiver) + typedRight.String()), nil
 	case StringResult:
 		return StringResult(receiver + typedRight), nil
+	case SafeResult:
+		return StringResult(string(receiver) + string(typedRight)), nil
 	default:
-		return nil, fmt.Errorf("Cannot add a %T to a %T", receiver, right)
+		return nil, newBinaryError("+", receiver, right)
 	}
 }
 
@@ -30,8 +31,12 @@ func convertToString(result Result) (string, bool) {
 	switch typedResult := result.(type) {
 	case IntResult:
 		return strconv.Itoa(int(typedResult)), true
+	case FloatResult:
+		return typedResult.String(), true
 	case StringResult:
 		return string(typedResult), true
+	case SafeResult:
+		return string(typedResult), true
 	default:
 		return "", false
 	}
@@ -44,7 +49,7 @@ func (receiver StringResult) EqualTo(right Result) (Result, error) {
 		return BoolResult(string(receiver) == rightStr), nil
 	}
 
-	return nil, fmt.Errorf("Cannot determine %T == %T", receiver, right)
+	return nil, newBinaryError("==", receiver, right)
 }
 
 // NotEqualTo checks if the provided result is logically not equal
@@ -54,7 +59,7 @@ func (receiver StringResult) NotEqualTo(right Result) (Result, error) {
 		return BoolResult(string(receiver) != rightStr), nil
 	}
 
-	return nil, fmt.Errorf("Cannot determine %T != %T", receiver, right)
+	return nil, newBinaryError("!=", receiver, right)
 }
 
 // LessThan checks if the provided result is logically less than
@@ -64,7 +69,7 @@ func (receiver StringResult) LessThan(right Result) (Result, error) {
 		return BoolResult(string(receiver) < rightStr), nil
 	}
 
-	return nil, fmt.Errorf("Cannot determine %T < %T", receiver, right)
+	return nil, newBinaryError("<", receiver, right)
 }
 
 // GreaterThan checks if the provided result is logically greater than
@@ -74,7 +79,7 @@ func (receiver StringResult) GreaterThan(right Result) (Result, error) {
 		return BoolResult(string(receiver) > rightStr), nil
 	}
 
-	return nil, fmt.Errorf("Cannot determine %T > %T", receiver, right)
+	return nil, newBinaryError(">", receiver, right)
 }
 
 // LessThanEqual checks if the provided result is logically less than or equal to
@@ -84,7 +89,7 @@ func (receiver StringResult) LessThanEqual(right Result) (Result, error) {
 		return BoolResult(string(receiver) <= rightStr), nil
 	}
 
-	return nil, fmt.Errorf("Cannot determine %T <= %T", receiver, right)
+	return nil, newBinaryError("<=", receiver, right)
 }
 
 // GreaterThanEqual checks if the provided result is logically greater than or equal to
@@ -94,5 +99,5 @@ func (receiver StringResult) GreaterThanEqual(right Result) (Result, error) {
 		return BoolResult(string(receiver) >= rightStr), nil
 	}
 
-	return nil, fmt.Errorf("Cannot determine %T >= %T", receiver, right)
+	return nil, newBinaryError(">=", receiver, right)
 }
@@ -0,0 +1,106 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+
+	"mettlach.codes/frizzy/parser"
+)
+
+// hasExtends returns true if any of nodes is an {{extends}} block
+func hasExtends(nodes []parser.TreeNode) bool {
+	for _, node := range nodes {
+		if _, ok := node.(*parser.ExtendsParseNode); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// processExtendedContent renders the layout named by the {{extends}} block
+// in nodes using the {{block}} regions in nodes as overrides
+// Everything else in nodes is processed so assignments still run but
+// its output is dropped
+func (receiver *NodeProcessor) processExtendedContent(nodes []parser.TreeNode) (Result, error) {
+	var layoutPath string
+
+	// the output of nodes outside of blocks is dropped so it mustn't
+	// move the escaper
+	var escaper *htmlEscaper
+	if receiver.escaper != nil {
+		escaper = receiver.escaper.copy()
+	}
+
+	for _, node := range nodes {
+		switch typedNode := node.(type) {
+		case *parser.ExtendsParseNode:
+			if layoutPath != "" {
+				return nil, fmt.Errorf("extends: multiple layouts %q and %q", layoutPath, typedNode.GetTemplatePath())
+			}
+			layoutPath = typedNode.GetTemplatePath()
+		case *parser.NamedBlockParseNode:
+			receiver.addBlockOverride(typedNode)
+		default:
+			if _, err := receiver.processHeadNode(node); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if escaper != nil {
+		*receiver.escaper = *escaper
+	}
+
+	for _, extended := range receiver.extendsChain {
+		if extended == layoutPath {
+			chain := append(receiver.extendsChain, layoutPath)
+			return nil, fmt.Errorf("extends: cycle detected %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	receiver.recordTemplate(layoutPath)
+	layoutNodes := parser.GetTemplateCache().Get(layoutPath)
+	if len(*layoutNodes) == 0 {
+		return nil, fmt.Errorf("extends: template %q not found", layoutPath)
+	}
+
+	receiver.extendsChain = append(receiver.extendsChain, layoutPath)
+	defer func() {
+		receiver.extendsChain = receiver.extendsChain[:len(receiver.extendsChain)-1]
+	}()
+
+	output := ""
+	firstWarning := len(*receiver.warnings)
+	defer receiver.attributeWarnings(firstWarning, func(err error) error {
+		return inTemplate(layoutPath, err)
+	})
+
+	for _, node := range *layoutNodes {
+		result, err := receiver.processHeadNode(node)
+		if err != nil {
+			return nil, inTemplate(layoutPath, err)
+		}
+		output += result.String()
+	}
+
+	return StringResult(output), nil
+}
+
+// addBlockOverride stores the body of block unless a template further
+// down the extends chain already overrode it
+func (receiver *NodeProcessor) addBlockOverride(block *parser.NamedBlockParseNode) {
+	if receiver.blockOverrides == nil {
+		receiver.blockOverrides = map[string]parser.TreeNode{}
+	}
+
+	name := block.GetName()
+	if _, exists := receiver.blockOverrides[name]; exists {
+		return
+	}
+
+	if body, ok := block.GetBody(); ok {
+		receiver.blockOverrides[name] = body
+	} else {
+		receiver.blockOverrides[name] = &parser.ContentParseNode{}
+	}
+}
@@ -0,0 +1,137 @@
+package processor
+
+import (
+	goContext "context"
+	"strings"
+	"testing"
+
+	"mettlach.codes/frizzy/lexer"
+	"mettlach.codes/frizzy/parser"
+)
+
+// lexes and parses text into its tree nodes
+func parseText(t *testing.T, text string) []parser.TreeNode {
+	lex := lexer.Lexer{}
+	tokChan, _ := lex.Lex(strings.NewReader(text), goContext.Background())
+	nodeChan, errChan := parser.Parse(tokChan, goContext.Background())
+
+	nodes := []parser.TreeNode{}
+	for node := range nodeChan {
+		nodes = append(nodes, node)
+	}
+
+	if err := <-errChan; err != nil {
+		t.Fatalf("failed to parse %q: %s", text, err)
+	}
+
+	return nodes
+}
+
+func cacheTemplate(t *testing.T, key, text string) {
+	templateCache := parser.GetTemplateCache()
+	for _, node := range parseText(t, text) {
+		templateCache.Insert(key, node)
+	}
+}
+
+func processText(t *testing.T, text string) (string, error) {
+	processor := NewNodeProcessor("", &Context{}, nil, &TestExportStore{}, nil, 0, 0)
+
+	output := ""
+	for _, node := range parseText(t, text) {
+		result, err := processor.processHeadNode(node)
+		if err != nil {
+			return "", err
+		}
+		output += result.String()
+	}
+
+	return output, nil
+}
+
+func TestExtendsRendersLayoutWithOverriddenBlocks(t *testing.T) {
+	cacheTemplate(t, "inherit/base.html",
+		`<title>{{block "title"}}Default{{end}}</title><main>{{block "main"}}{{end}}</main>`)
+	cacheTemplate(t, "inherit/section.html",
+		`{{extends "inherit/base.html"}}{{block "main"}}<section>{{block "body"}}section body{{end}}</section>{{end}}`)
+
+	var tests = []struct {
+		page     string
+		expected string
+	}{
+		{
+			`{{extends "inherit/base.html"}}`,
+			`<title>Default</title><main></main>`,
+		},
+		{
+			"{{extends \"inherit/base.html\"}}\n{{block \"main\"}}<p>page</p>{{end}}\n",
+			`<title>Default</title><main><p>page</p></main>`,
+		},
+		{
+			`{{extends "inherit/base.html"}}{{block "title"}}{{: name}}{{end}}{{name = "Page"}}`,
+			`<title>Page</title><main></main>`,
+		},
+		{
+			`{{extends "inherit/section.html"}}{{block "body"}}page body{{end}}`,
+			`<title>Default</title><main><section>page body</section></main>`,
+		},
+		{
+			`{{extends "inherit/section.html"}}{{block "main"}}replaced{{end}}`,
+			`<title>Default</title><main>replaced</main>`,
+		},
+	}
+
+	for i, test := range tests {
+		got, err := processText(t, test.page)
+
+		if err != nil {
+			t.Errorf("%d: expected no errors, got %q", i, err)
+		} else if got != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, got)
+		}
+	}
+}
+
+func TestExtendsReturnsErrors(t *testing.T) {
+	cacheTemplate(t, "inherit/cycle_a.html", `{{extends "inherit/cycle_b.html"}}`)
+	cacheTemplate(t, "inherit/cycle_b.html", `{{extends "inherit/cycle_a.html"}}`)
+
+	var tests = []struct {
+		page     string
+		expected string
+	}{
+		{`{{extends "inherit/missing.html"}}`, `extends: template "inherit/missing.html" not found`},
+		{`{{extends "inherit/cycle_a.html"}}`, "extends: cycle detected"},
+	}
+
+	for i, test := range tests {
+		_, err := processText(t, test.page)
+
+		if err == nil {
+			t.Errorf("%d: expected error %q, got nil", i, test.expected)
+		} else if !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%d: expected error %q, got %q", i, test.expected, err)
+		}
+	}
+}
+
+func TestBlockAndExtendsCanBeVariables(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{`{{block = 1}}{{: block + 1}}`, "2"},
+		{`{{extends = "a"}}{{: extends}}`, "a"},
+		{`{{block = {"main": "b"}}}{{: block.main}}`, "b"},
+	}
+
+	for i, test := range tests {
+		actual, err := processText(t, test.text)
+
+		if err != nil {
+			t.Errorf("%d: expected no error, got %q", i, err)
+		} else if actual != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
@@ -8,8 +8,8 @@ import (
 )
 
 // RenderNullResults reads the results and drops them
-func RenderNullResults(ctx context.Context, inputPath string, nodeChan <-chan parser.TreeNode, curPage, numPages int) (<-chan error, <-chan error) {
-	nodeProcessor := processor.NewNodeProcessor(inputPath, nil, nil, nil, nil, curPage, numPages)
+func RenderNullResults(ctx context.Context, inputPath string, fileContext *processor.Context, nodeChan <-chan parser.TreeNode, curPage, numPages int) (<-chan error, <-chan error) {
+	nodeProcessor := processor.NewNodeProcessor(inputPath, fileContext.Copy(), nil, nil, nil, curPage, numPages)
 
 	outputPath := processor.GetMarkdownOutputPath(inputPath, curPage)
 	nodeProcessor.ExportStore.Insert([]string{"_href"}, processor.StringResult(outputPath))
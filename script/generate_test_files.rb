@@ -30,7 +30,8 @@ def write_content_files(path, paragraphs, num_files)
   (1..num_files).each do |i|
     filepath = File.join(content_path, "test_content_#{i}.md")
     File.open(filepath, 'w') do |f|
-      f.write("{{ title = '#{Faker::Book.title}' }}\n")
+      title = Faker::Book.title.gsub(/['\\]/) { |c| "\\#{c}" }
+      f.write("{{ title = '#{title}' }}\n")
       f.write("\# {{: title}}\n"
       )
       (0...10).each do